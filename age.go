@@ -0,0 +1,36 @@
+package buffer
+
+import "time"
+
+// Arm the MaxAge timer for the file just opened.
+func (b *Buffer) arm() {
+	if b.expiry != nil {
+		b.expiry.Stop()
+	}
+
+	b.expiry = time.AfterFunc(b.MaxAge, b.aged)
+}
+
+// Flush the file when it reaches MaxAge.
+func (b *Buffer) aged() {
+	b.label("age")
+
+	b.Lock()
+	defer b.Unlock()
+
+	if b.stopped {
+		return
+	}
+
+	if wait := b.MaxAge - time.Since(b.opened); wait > 0 {
+		b.expiry.Reset(wait)
+		return
+	}
+
+	if b.writes == 0 {
+		b.expiry.Reset(b.MaxAge)
+		return
+	}
+
+	b.error(b.attempt(func() error { return b.flush(Aged) }))
+}
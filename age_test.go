@@ -0,0 +1,36 @@
+package buffer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test flushing files at MaxAge regardless of other flushes.
+func TestBuffer_Write_FlushOnMaxAge(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 2,
+		MaxAge:      200 * time.Millisecond,
+	})
+
+	assert.Equal(t, nil, err)
+
+	time.Sleep(150 * time.Millisecond)
+	b.Write([]byte("hello\n"))
+	b.Write([]byte("world\n"))
+
+	flush := <-b.Queue
+	assert.Equal(t, Writes, flush.Reason)
+
+	b.Write([]byte("hello\n"))
+
+	flush = <-b.Queue
+	assert.Equal(t, Aged, flush.Reason)
+	assert.Equal(t, int64(1), flush.Writes)
+	assert.Equal(t, true, flush.Age >= 200*time.Millisecond)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
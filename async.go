@@ -0,0 +1,129 @@
+package buffer
+
+// Asynchronous write, funneled write when reply is set, or barrier when
+// done is set.
+type op struct {
+	data  []byte
+	meta  map[string]string
+	done  chan error
+	reply chan reply
+}
+
+// Enqueue a copy of `data` to the ring for the background writer.
+func (b *Buffer) enqueue(data []byte, meta map[string]string) (int, error) {
+	b.RLock()
+	draining := b.draining
+	b.RUnlock()
+
+	if draining {
+		return 0, ErrDraining
+	}
+
+	buf := make([]byte, len(data))
+	copy(buf, data)
+
+	b.ringMu.RLock()
+	defer b.ringMu.RUnlock()
+
+	if b.ringClosed {
+		return 0, ErrClosed
+	}
+
+	b.ring <- op{data: buf, meta: meta}
+	return len(data), nil
+}
+
+// Barrier waits until writes made before it have been written to the
+// buffer when Config.Async is set, returning the first error since the
+// last barrier. Errors are also reported to the Errors channel.
+func (b *Buffer) Barrier() error {
+	if !b.ringed() {
+		return nil
+	}
+
+	done := make(chan error, 1)
+
+	b.ringMu.RLock()
+	if b.ringClosed {
+		b.ringMu.RUnlock()
+		return ErrClosed
+	}
+	b.ring <- op{done: done}
+	b.ringMu.RUnlock()
+
+	return <-done
+}
+
+// Sync waits for a Barrier, then flushes buffered data to the file and
+// fsyncs it, so writes made before it are on disk.
+func (b *Buffer) Sync() error {
+	err := b.Barrier()
+	if err != nil {
+		return err
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	if b.stopped {
+		return ErrClosed
+	}
+
+	if b.buf != nil {
+		err = b.buf.Flush()
+		if err != nil {
+			return err
+		}
+	}
+
+	if b.file == nil {
+		return nil
+	}
+
+	return b.file.Sync()
+}
+
+// Write ring entries to the buffer until the ring is closed.
+func (b *Buffer) writer() {
+	defer b.writers.Done()
+	b.label("async")
+
+	var first error
+	for op := range b.ring {
+		if op.done != nil {
+			op.done <- first
+			first = nil
+			continue
+		}
+
+		if op.reply != nil {
+			n, err := b.put(op.data, op.meta)
+			op.reply <- reply{n, err}
+			continue
+		}
+
+		_, err := b.put(op.data, op.meta)
+		if err != nil {
+			b.report(err)
+			if first == nil {
+				first = err
+			}
+		}
+	}
+}
+
+// Close the ring, waiting for the background writer to drain it.
+func (b *Buffer) closeRing() {
+	if !b.ringed() {
+		return
+	}
+
+	b.ringMu.Lock()
+	if !b.ringClosed {
+		b.ringClosed = true
+		close(b.ring)
+	}
+	b.ringMu.Unlock()
+
+	b.writers.Wait()
+}
@@ -0,0 +1,77 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test asynchronous writes.
+func TestBuffer_Async(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 100,
+		BufferSize:  1024,
+		Async:       16,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	data := []byte("hello\n")
+	for i := 0; i < 50; i++ {
+		n, err := b.Write(data)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 6, n)
+	}
+
+	data[0] = 'j'
+
+	err = b.Sync()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(50), b.Writes())
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	f := <-b.Queue
+	assert.Equal(t, int64(50), f.Writes)
+
+	buf, err := ioutil.ReadFile(f.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "hello\n", string(buf[:6]))
+	assert.Equal(t, 300, len(buf))
+
+	_, err = b.Write([]byte("hello"))
+	assert.Equal(t, ErrClosed, err)
+
+	err = b.Barrier()
+	assert.Equal(t, ErrClosed, err)
+}
+
+// Test asynchronous writes are drained on Close.
+func TestBuffer_Async_close(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 10,
+		Async:       4,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	for i := 0; i < 25; i++ {
+		b.Write([]byte("hello"))
+	}
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	var writes int64
+	for len(b.Queue) > 0 {
+		writes += (<-b.Queue).Writes
+	}
+
+	assert.Equal(t, int64(25), writes)
+}
@@ -0,0 +1,55 @@
+package buffer
+
+import "time"
+
+// Attempt is a delivery attempt of a flushed file.
+type Attempt struct {
+	Time  time.Time `json:"time"`
+	Error string    `json:"error,omitempty"`
+}
+
+// Nack records a failed delivery attempt of `f`, so files which are
+// eventually dead-lettered carry their delivery history.
+func (b *Buffer) Nack(f *Flush, err error) {
+	b.log(1, "delivery of %q failed (attempt %d): %s", f.Path, len(f.Attempts)+1, err)
+	f.attempted(err)
+	b.error(b.journal(nacked, f, err))
+	b.states.set(f.Path, Failed, err)
+}
+
+// LastError returns the error of the last delivery attempt, if any.
+func (f *Flush) LastError() string {
+	if len(f.Attempts) == 0 {
+		return ""
+	}
+
+	return f.Attempts[len(f.Attempts)-1].Error
+}
+
+// Record a delivery attempt.
+func (f *Flush) attempted(err error) {
+	a := Attempt{Time: time.Now()}
+	if err != nil {
+		a.Error = err.Error()
+	}
+
+	f.Attempts = append(f.Attempts, a)
+}
+
+// Requeue records a failed delivery attempt of `f` and publishes it to
+// the queue again after the configured backoff, or halfway to its
+// Flush.Deadline when sooner and not yet passed.
+func (b *Buffer) Requeue(f *Flush, err error) {
+	b.Nack(f, err)
+	d := b.backoff().Backoff(len(f.Attempts))
+	if !f.Deadline.IsZero() {
+		if until := time.Until(f.Deadline) / 2; until > 0 && until < d {
+			d = until
+		}
+	}
+
+	b.log(2, "requeueing %q in %s", f.Path, d)
+	time.AfterFunc(d, func() {
+		b.error(b.publish(f))
+	})
+}
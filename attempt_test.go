@@ -0,0 +1,38 @@
+package buffer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test recording delivery attempts.
+func TestBuffer_Nack(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+
+	f := <-b.Queue
+	assert.Equal(t, "", f.LastError())
+
+	b.Nack(f, errors.New("timeout"))
+	b.Nack(f, errors.New("503 service unavailable"))
+	assert.Equal(t, 2, len(f.Attempts))
+	assert.Equal(t, "timeout", f.Attempts[0].Error)
+	assert.Equal(t, "503 service unavailable", f.LastError())
+
+	b.Ack(f)
+	assert.Equal(t, 3, len(f.Attempts))
+	assert.Equal(t, "", f.LastError())
+	assert.Equal(t, false, f.Attempts[2].Time.Before(f.Attempts[1].Time))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
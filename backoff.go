@@ -0,0 +1,75 @@
+package buffer
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// Backoff computes the delay before a retry.
+type Backoff interface {
+	// Backoff returns the delay before retry `attempt`, starting at 1.
+	Backoff(attempt int) time.Duration
+}
+
+// Exponential backoff with jitter.
+type Exponential struct {
+	Min    time.Duration // Delay before the first retry
+	Max    time.Duration // Maximum delay, zero for no maximum
+	Factor float64       // Multiplier per attempt, defaults to 2
+	Jitter float64       // Fraction of each delay randomized, from 0 to 1
+}
+
+// Backoff implements Backoff.
+func (e Exponential) Backoff(attempt int) time.Duration {
+	factor := e.Factor
+	if factor == 0 {
+		factor = 2
+	}
+
+	d := float64(e.Min)
+	for i := 1; i < attempt; i++ {
+		d *= factor
+		if e.Max != 0 && d >= float64(e.Max) {
+			break
+		}
+	}
+
+	if e.Max != 0 && d > float64(e.Max) {
+		d = float64(e.Max)
+	}
+
+	if e.Jitter != 0 {
+		d -= d * e.Jitter * rand.Float64()
+	}
+
+	return time.Duration(d)
+}
+
+// Retry `fn` up to `retries` times while it fails, waiting between
+// attempts using `backoff`, returning the last error.
+func Retry(ctx context.Context, backoff Backoff, retries int, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt > retries {
+			return err
+		}
+
+		t := time.NewTimer(backoff.Backoff(attempt))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Backoff configured for retries, defaulting to doubling RenameBackoff.
+func (b *Buffer) backoff() Backoff {
+	if b.Backoff != nil {
+		return b.Backoff
+	}
+
+	return Exponential{Min: b.RenameBackoff, Max: time.Minute}
+}
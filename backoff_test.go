@@ -0,0 +1,85 @@
+package buffer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// Constant backoff for tests.
+type constant time.Duration
+
+func (c constant) Backoff(int) time.Duration { return time.Duration(c) }
+
+// Test exponential backoff.
+func TestBuffer_Exponential(t *testing.T) {
+	e := Exponential{Min: time.Second, Max: 5 * time.Second}
+	assert.Equal(t, time.Second, e.Backoff(1))
+	assert.Equal(t, 2*time.Second, e.Backoff(2))
+	assert.Equal(t, 4*time.Second, e.Backoff(3))
+	assert.Equal(t, 5*time.Second, e.Backoff(4))
+	assert.Equal(t, 5*time.Second, e.Backoff(100))
+
+	e.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := e.Backoff(2)
+		assert.Equal(t, true, d > time.Second && d <= 2*time.Second)
+	}
+}
+
+// Test retrying with backoff.
+func TestBuffer_Retry(t *testing.T) {
+	calls := 0
+	err := Retry(context.Background(), constant(0), 3, func() error {
+		calls++
+		return errors.New("boom")
+	})
+
+	assert.Equal(t, "boom", err.Error())
+	assert.Equal(t, 4, calls)
+
+	calls = 0
+	err = Retry(context.Background(), constant(0), 3, func() error {
+		calls++
+		if calls < 2 {
+			return errors.New("boom")
+		}
+		return nil
+	})
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 2, calls)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = Retry(ctx, constant(time.Hour), 3, func() error {
+		return errors.New("boom")
+	})
+
+	assert.Equal(t, context.Canceled, err)
+}
+
+// Test requeueing failed deliveries.
+func TestBuffer_Requeue(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1,
+		Backoff:     constant(10 * time.Millisecond),
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello\n"))
+	f := <-b.Queue
+
+	b.Requeue(f, errors.New("boom"))
+	assert.Equal(t, f, <-b.Queue)
+	assert.Equal(t, "boom", f.LastError())
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
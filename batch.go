@@ -0,0 +1,134 @@
+package buffer
+
+import (
+	"sort"
+	"sync"
+)
+
+// Batch tracking shared by a buffer and its partitions.
+type batches struct {
+	sync.Mutex
+	current string
+	open    map[string]int
+	pending map[string]int
+	lost    map[string]State
+	waiters map[string][]chan State
+}
+
+// New batch tracker.
+func newBatches() *batches {
+	return &batches{
+		open:    make(map[string]int),
+		pending: make(map[string]int),
+		lost:    make(map[string]State),
+		waiters: make(map[string][]chan State),
+	}
+}
+
+// SetBatch sets the upstream batch id recorded for subsequent writes,
+// empty to stop recording. Each Flush lists the batches it contains.
+func (b *Buffer) SetBatch(id string) {
+	t := b.batches
+	t.Lock()
+	defer t.Unlock()
+
+	prev := t.current
+	t.current = id
+	t.notify(prev)
+}
+
+// Delivered returns a channel receiving the outcome of batch `id` once it
+// is no longer current and every file containing it has been flushed and
+// reached a terminal state: Delivered when all were acked, otherwise the
+// state of a file which was not, such as Dropped, Evicted or DeadLettered.
+// The channel is closed after the outcome is sent.
+func (b *Buffer) Delivered(id string) <-chan State {
+	t := b.batches
+	t.Lock()
+	defer t.Unlock()
+
+	ch := make(chan State, 1)
+	t.waiters[id] = append(t.waiters[id], ch)
+	t.notify(id)
+	return ch
+}
+
+// Record the current batch for the open file.
+func (b *Buffer) record() {
+	t := b.batches
+	t.Lock()
+	defer t.Unlock()
+
+	if t.current == "" || b.contains[t.current] {
+		return
+	}
+
+	if b.contains == nil {
+		b.contains = make(map[string]bool)
+	}
+
+	b.contains[t.current] = true
+	t.open[t.current]++
+}
+
+// Mark batches of the open file as pending, returning their ids.
+func (b *Buffer) seal() []string {
+	if len(b.contains) == 0 {
+		return nil
+	}
+
+	t := b.batches
+	t.Lock()
+	defer t.Unlock()
+
+	ids := make([]string, 0, len(b.contains))
+	for id := range b.contains {
+		t.open[id]--
+		t.pending[id]++
+		ids = append(ids, id)
+	}
+
+	b.contains = nil
+	sort.Strings(ids)
+	return ids
+}
+
+// Settle batches of a file which reached terminal state `s`.
+func (t *batches) settle(ids []string, s State) {
+	t.Lock()
+	defer t.Unlock()
+
+	for _, id := range ids {
+		if t.pending[id] > 0 {
+			t.pending[id]--
+		}
+
+		if s != Delivered && t.lost[id] == "" {
+			t.lost[id] = s
+		}
+
+		t.notify(id)
+	}
+}
+
+// Notify waiters when batch `id` is complete.
+func (t *batches) notify(id string) {
+	if id == "" || id == t.current || t.open[id] > 0 || t.pending[id] > 0 {
+		return
+	}
+
+	s := t.lost[id]
+	if s == "" {
+		s = Delivered
+	}
+
+	for _, ch := range t.waiters[id] {
+		ch <- s
+		close(ch)
+	}
+
+	delete(t.waiters, id)
+	delete(t.open, id)
+	delete(t.pending, id)
+	delete(t.lost, id)
+}
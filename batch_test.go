@@ -0,0 +1,88 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test batch delivery tracking.
+func TestBuffer_SetBatch(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 2,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.SetBatch("a")
+	b.Write([]byte("hello"))
+	done := b.Delivered("a")
+
+	b.SetBatch("b")
+	b.Write([]byte("hello"))
+	b.WriteKeyed("tobi", []byte("hello"))
+
+	first := <-b.Queue
+	assert.Equal(t, []string{"a", "b"}, first.Batches)
+
+	b.SetBatch("")
+	b.Flush()
+
+	second := <-b.Queue
+	assert.Equal(t, "tobi", second.Key)
+	assert.Equal(t, []string{"b"}, second.Batches)
+
+	b.Ack(second)
+
+	select {
+	case <-done:
+		t.Fatal("batch delivered before ack")
+	default:
+	}
+
+	b.Ack(first)
+	<-done
+	<-b.Delivered("b")
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test batches settle with the state of files which were not delivered.
+func TestBuffer_SetBatch_lost(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 1),
+		FlushWrites: 1,
+		Policies:    map[Condition]Policy{QueueFull: DropNewest},
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.SetBatch("a")
+	a := b.Delivered("a")
+	b.Write([]byte("hello"))
+	b.SetBatch("b")
+	dropped := b.Delivered("b")
+	b.Write([]byte("world"))
+	b.SetBatch("")
+
+	assert.Equal(t, Dropped, <-dropped)
+	b.Ack(<-b.Queue)
+	assert.Equal(t, Delivered, <-a)
+
+	b.SetBatch("c")
+	c := b.Delivered("c")
+	b.Write([]byte("again"))
+	b.SetBatch("")
+
+	f := <-b.Queue
+	assert.Equal(t, []string{"c"}, f.Batches)
+	b.DeadLetter(f, ErrChecksum)
+	assert.Equal(t, DeadLettered, <-c)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
@@ -0,0 +1,124 @@
+package buffer
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrBreakerOpen is returned by writes while the circuit breaker is open
+// under the Error policy.
+var ErrBreakerOpen = errors.New("circuit breaker open")
+
+// Circuit breaker shared by a buffer and its partitions, tripped by
+// consecutive flush or open failures.
+type breaker struct {
+	sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	until     time.Time
+	trips     int64
+}
+
+// New breaker of the configured threshold, nil when disabled.
+func newBreaker(c *Config) *breaker {
+	if c.TripAfter == 0 {
+		return nil
+	}
+
+	cooldown := c.TripCooldown
+	if cooldown == 0 {
+		cooldown = 30 * time.Second
+	}
+
+	return &breaker{threshold: c.TripAfter, cooldown: cooldown}
+}
+
+// Whether the breaker is open.
+func (c *breaker) open() bool {
+	if c == nil {
+		return false
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	return time.Now().Before(c.until)
+}
+
+// Observe the outcome `err` of disk I/O, returning whether the breaker
+// tripped or closed again.
+func (c *breaker) observe(err error) (changed bool) {
+	if c == nil {
+		return false
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if err == nil {
+		changed = c.failures >= c.threshold
+		c.failures = 0
+		c.until = time.Time{}
+		return changed
+	}
+
+	c.failures++
+	if c.failures < c.threshold {
+		return false
+	}
+
+	c.until = time.Now().Add(c.cooldown)
+	c.trips++
+	return true
+}
+
+// Trips returns the number of times the breaker tripped.
+func (c *breaker) tripped() int64 {
+	if c == nil {
+		return 0
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	return c.trips
+}
+
+// Record the outcome `err` of a flush, notifying the OnTrip hook
+// when the breaker changes state. Full queues are not disk failures
+// and are ignored.
+func (b *Buffer) observe(err error) {
+	if err == ErrQueueFull || !b.breaker.observe(err) {
+		return
+	}
+
+	if err != nil {
+		b.log(1, "circuit breaker tripped for %s: %s", b.breaker.cooldown, err)
+	} else {
+		b.log(1, "circuit breaker closed")
+	}
+
+	if b.Hooks.OnTrip != nil {
+		b.Hooks.OnTrip(err != nil, err)
+	}
+}
+
+// Check the circuit breaker before a write, applying the BreakerOpen
+// policy and returning whether to drop it.
+func (b *Buffer) guard() (bool, error) {
+	if !b.breaker.open() {
+		return false, nil
+	}
+
+	switch b.decide(BreakerOpen) {
+	case DropNewest:
+		if b.Hooks.OnDrop != nil {
+			b.Hooks.OnDrop(BreakerOpen, nil)
+		}
+		return true, nil
+	default:
+		atomic.AddInt64(&b.errors, 1)
+		return false, ErrBreakerOpen
+	}
+}
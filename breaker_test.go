@@ -0,0 +1,57 @@
+package buffer
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test the circuit breaker trips after consecutive failures.
+func TestBuffer_Breaker(t *testing.T) {
+	var trips []bool
+	b, err := New("/tmp/buffer", &Config{
+		Queue:        make(chan *Flush, 10),
+		FlushWrites:  100,
+		TripAfter:    2,
+		TripCooldown: 50 * time.Millisecond,
+		Hooks:        Hooks{OnTrip: func(open bool, err error) { trips = append(trips, open) }},
+	})
+
+	assert.Equal(t, nil, err)
+
+	fail := errors.New("disk failed")
+	b.observe(fail)
+	_, err = b.Write([]byte("a"))
+	assert.Equal(t, nil, err)
+
+	b.observe(fail)
+	assert.Equal(t, []bool{true}, trips)
+	assert.Equal(t, true, b.Stats().Tripped)
+	assert.Equal(t, int64(1), b.Stats().Trips)
+
+	_, err = b.Write([]byte("b"))
+	assert.Equal(t, ErrBreakerOpen, err)
+
+	err = b.SetPolicy(BreakerOpen, DropNewest)
+	assert.Equal(t, nil, err)
+
+	_, err = b.Write([]byte("c"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(1), b.Writes())
+	assert.Equal(t, int64(1), b.Decisions()[Decision{BreakerOpen, DropNewest}])
+
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, false, b.Stats().Tripped)
+
+	_, err = b.Write([]byte("d"))
+	assert.Equal(t, nil, err)
+
+	err = b.Flush()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []bool{true, false}, trips)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
@@ -0,0 +1,29 @@
+package buffer
+
+import "time"
+
+// Arm the timer writing out buffered data after MaxBufferAge.
+func (b *Buffer) age() {
+	if b.aging != nil {
+		return
+	}
+
+	b.aging = time.AfterFunc(b.MaxBufferAge, func() {
+		b.label("buffer-age")
+
+		b.Lock()
+		defer b.Unlock()
+
+		b.aging = nil
+		if b.stopped || b.buf == nil || b.buf.Buffered() == 0 {
+			return
+		}
+
+		b.log(2, "writing out buffered data")
+		b.error(b.buf.Flush())
+
+		if b.live != nil {
+			b.live.commit(b.committed())
+		}
+	})
+}
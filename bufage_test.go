@@ -0,0 +1,41 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test writing out buffered data after MaxBufferAge.
+func TestBuffer_MaxBufferAge(t *testing.T) {
+	var path string
+	b, err := New("/tmp/buffer", &Config{
+		Queue:        make(chan *Flush, 100),
+		FlushWrites:  1000,
+		BufferSize:   1 << 10,
+		MaxBufferAge: 50 * time.Millisecond,
+		Hooks: Hooks{
+			OnOpen: func(p string) { path = p },
+		},
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello\n"))
+
+	data, err := ioutil.ReadFile(path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "", string(data))
+
+	time.Sleep(150 * time.Millisecond)
+
+	data, err = ioutil.ReadFile(path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "hello\n", string(data))
+	assert.Equal(t, 0, len(b.Queue))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
@@ -8,11 +8,20 @@ package buffer
 
 import (
 	"bufio"
+	"context"
 	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
 	"log"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime/pprof"
+	"strconv"
 	"sync"
 	"sync/atomic"
+	"text/template"
 	"time"
 )
 
@@ -27,50 +36,226 @@ type Reason string
 
 // Flush reasons.
 const (
-	Forced   Reason = "forced"
-	Writes   Reason = "writes"
-	Bytes    Reason = "bytes"
-	Interval Reason = "interval"
+	Forced    Reason = "forced"
+	Writes    Reason = "writes"
+	Bytes     Reason = "bytes"
+	Interval  Reason = "interval"
+	Bucket    Reason = "bucket"
+	Recovered Reason = "recovered"
+	Stale     Reason = "stale"
+	Restored  Reason = "restored"
+	Signaled  Reason = "signaled"
+	Predicate Reason = "predicate"
+	Idle      Reason = "idle"
+	Aged      Reason = "aged"
+	Scheduled Reason = "scheduled"
 )
 
 // Flush represents a flushed file.
 type Flush struct {
-	Reason Reason        `json:"reason"`
-	Path   string        `json:"path"`
-	Writes int64         `json:"writes"`
-	Bytes  int64         `json:"bytes"`
-	Opened time.Time     `json:"opened"`
-	Closed time.Time     `json:"closed"`
-	Age    time.Duration `json:"age"`
+	Version  int                 `json:"version"`
+	Reason   Reason              `json:"reason"`
+	Path     string              `json:"path"`
+	Key      string              `json:"key,omitempty"`
+	KeyID    string              `json:"key_id,omitempty"`
+	Batches  []string            `json:"batches,omitempty"`
+	Meta     map[string][]string `json:"meta,omitempty"`
+	Codec    string              `json:"codec,omitempty"`
+	Dict     string              `json:"dict,omitempty"`
+	Hash     string              `json:"hash,omitempty"`
+	Checksum string              `json:"checksum,omitempty"`
+	Bucket   time.Time           `json:"bucket"`
+	Seq      int64               `json:"seq"`
+	Writes   int64               `json:"writes"`
+	Bytes    int64               `json:"bytes"`
+	Opened   time.Time           `json:"opened"`
+	First    time.Time           `json:"first"`
+	Closed   time.Time           `json:"closed"`
+	Age      time.Duration       `json:"age"`
+	Attempts []Attempt           `json:"attempts,omitempty"`
+	Expired  int64               `json:"expired,omitempty"`
+	Members  []string            `json:"members,omitempty"`
+	Spilled  bool                `json:"spilled,omitempty"`
+	Deadline time.Time           `json:"deadline"`
+	Data     []byte              `json:"-"`
 }
 
+// FlushPredicate decides whether to flush after a write, given the
+// writes, bytes and age of the current file and the last record written.
+type FlushPredicate func(writes, bytes int64, age time.Duration, last []byte) bool
+
 // Config for disk buffer.
 type Config struct {
-	FlushWrites   int64         // Flush after N writes, zero to disable
-	FlushBytes    int64         // Flush after N bytes, zero to disable
-	FlushInterval time.Duration // Flush after duration, zero to disable
-	BufferSize    int           // Buffer size for writes
-	Queue         chan *Flush   // Queue of flushed files
-	Verbosity     int           // Verbosity level, 0-3
-	Logger        *log.Logger   // Logger instance
+	FlushWrites    int64                // Flush after N writes, zero to disable
+	FlushBytes     int64                // Flush after N bytes, zero to disable
+	StrictBytes    bool                 // Flush before writes which would take files over FlushBytes, rejecting larger writes
+	FlushInterval  time.Duration        // Flush after duration, zero to disable
+	FlushBucket    time.Duration        // Flush on wall-clock boundaries, zero to disable
+	FlushSchedule  Schedule             // Flush at scheduled times, see ParseCron
+	FlushFunc      FlushPredicate       // Flush when true, evaluated after each write
+	FlushIdle      time.Duration        // Flush after no writes for duration, zero to disable
+	MaxAge         time.Duration        // Flush files open for duration regardless of activity, zero to disable
+	BufferSize     int                  // Buffer size for writes
+	MaxWriteSize   int                  // Reject larger writes with ErrTooLarge, zero to disable
+	RateBytes      int64                // Limit writes to N bytes per second, see RateLimited
+	RateWrites     int64                // Limit writes to N per second, see RateLimited
+	Delimiter      []byte               // Appended to each write, such as "\n" for NDJSON
+	MemoryBytes    int64                // Hold files of up to N bytes in memory, publishing them with Flush.Data and no file
+	MemoryAge      time.Duration        // Write files held in memory out to disk once open for duration, zero for no limit
+	Parquet        *ParquetSchema       // Write files as Parquet with one row group per flush, see WriteRow
+	CSVHeader      []string             // Header row written at the top of each file, see WriteCSV
+	MaxBufferAge   time.Duration        // Write out buffered data after duration without rotating, zero to disable
+	Async          int                  // Capacity of the in-memory ring for asynchronous writes, zero to disable, see Barrier
+	SingleWriter   bool                 // Funnel writes through one goroutine instead of contending for the lock
+	Filename       string               // Filename template, see Name
+	Logrotate      bool                 // Name files like logrotate and flush on SIGUSR1, see LogrotateFilename
+	NewID          func() string        // File id generator used instead of pid and sequence, see ULID
+	Staging        string               // Suffix of files being written, removed on flush
+	Streaming      bool                 // Allow reading the open file with Stream
+	Suffix         string               // Suffix of flushed files, defaults to ".closed" unless Staging is set
+	SpoolDir       string               // Directory of files being written, defaults to the path's directory
+	OutDir         string               // Directory flushed files are renamed into, on the same filesystem
+	SpillDir       string               // Directory files are written to when the primary fails to open them or exceeds Quota, see Flush.Spilled
+	DoneDir        string               // Directory acked files are moved into, on the same filesystem
+	DoneLink       bool                 // Hard link acked files into DoneDir instead of moving them
+	DoneRetention  time.Duration        // Remove files from DoneDir older than this, zero to keep them
+	TrashDir       string               // Directory evicted files are moved into, see Restore
+	TrashRetention time.Duration        // Remove files from TrashDir older than this, zero to keep them
+	FileMode       os.FileMode          // Mode of created files, defaults to 0666 before umask
+	DirMode        os.FileMode          // Mode of created directories, defaults to 0755 before umask
+	RenameRetries  int                  // Retry failed renames N times
+	RenameBackoff  time.Duration        // Backoff between rename retries, doubled per attempt
+	WriteRetries   int                  // Retry writes and creates failing with transient errors such as EIO N times, see DiskError
+	TripAfter      int                  // Trip the circuit breaker after N consecutive flush failures, zero to disable, see BreakerOpen
+	TripCooldown   time.Duration        // Duration the circuit breaker stays open, defaults to 30s
+	Backoff        Backoff              // Backoff between retries, overriding RenameBackoff
+	Durability     Durability           // Fsync flushed files and directories, see PowerSafe
+	Policies       map[Condition]Policy // Failure policies, see SetPolicy
+	Recover        bool                 // Publish flushed files left by previous runs
+	RecoverRatio   int                  // Oldest recovered files published per newest, zero for oldest first
+	ExpireBacklog  bool                 // Drop expired records from recovered and replayed files before publishing them, see Expire
+	RemoveStale    time.Duration        // Remove zero-byte files older than this on startup, zero to disable
+	Quota          *QuotaManager        // Cap on flushed files not yet acked, shared across buffers
+	QuotaPriority  int                  // Files with lower priorities are evicted first under Quota
+	Manifest       string               // Journal of flushed files replayed on startup to publish those not acked
+	SeqFile        string               // State file persisting file sequences across restarts
+	Expvar         string               // Publish state as an expvar under this name
+	Instrument     Instrument           // Observe writes, flushes and acks, see package otelbuffer
+	Hooks          Hooks                // Lifecycle callbacks
+	SizeBuckets    []float64            // File size histogram buckets in bytes
+	AgeBuckets     []float64            // File age histogram buckets in seconds
+	KeyProvider    KeyProvider          // Encrypt files with per-key data keys
+	Codec          string               // Compress files with "gzip" or "zstd", empty to disable
+	Dictionary     bool                 // Train a zstd dictionary on the first file and compress later files with it, see Decompress
+	Passthrough    bool                 // Skip compressing files which start with gzip data
+	Checksum       string               // Hash files as written with "sha256", "sha1", "md5" or "crc32", empty to disable
+	Sidecar        bool                 // Write the checksum next to flushed files, named with the hash appended
+	Trailer        bool                 // Append a CRC32C and record count to flushed files, see Verify
+	MetaFile       bool                 // Write the Flush as JSON next to flushed files, named with ".meta.json" appended
+	Segments       int                  // Files to pre-create for bursts, zero to disable
+	Deferred       bool                 // Compress and checksum flushed files in the background instead of as written, for bursts, see FlushFile
+	Labels         bool                 // Tag work with pprof labels
+	Probe          bool                 // Run SelfTest in New
+	Paranoid       bool                 // Re-read each record after writing it and verify counts on flush, panicking on a mismatch
+	Partitions     int                  // Partitions of WriteHashed, zero to disable
+	Partitioner    Partitioner          // Assignment of WriteHashed keys, defaults to HashPartitioner
+	Meta           bool                 // Aggregate record annotations into Flush.Meta, see WriteWithMeta
+	Envelope       Envelope             // Encoding of annotated records, defaults to QueryEnvelope
+	SLA            time.Duration        // Delivery target from first write to Ack
+	ReportInterval time.Duration        // Report delivery latency after duration, zero to disable
+	Reports        chan *Report         // Queue of delivery reports
+	StatsInterval  time.Duration        // Write a Stats record after duration, zero to disable
+	StatsBuffer    *Buffer              // Buffer receiving Stats records, defaults to this one
+	Shadow         *Buffer              // Buffer every accepted write is mirrored to for comparison, see ShadowReport
+	Queue          chan *Flush          // Queue of flushed files
+	Errors         chan error           // Errors from background flushes, dropped when full
+	Verbosity      int                  // Verbosity level of the default logger, 0-3
+	Logger         *log.Logger          // Logger instance
+	Log            Logger               // Leveled logger such as a *slog.Logger, used instead of Logger
 }
 
 // Validate the configuration.
 func (c *Config) Validate() error {
+	for cond, p := range c.Policies {
+		err := checkPolicy(cond, p)
+		if err == nil {
+			err = c.checkPolicy(cond, p)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
 	switch {
-	case c.FlushBytes == 0 && c.FlushWrites == 0 && c.FlushInterval == 0:
+	case c.FlushBytes == 0 && c.FlushWrites == 0 && c.FlushInterval == 0 && c.FlushBucket == 0 && c.FlushFunc == nil && c.FlushIdle == 0 && c.MaxAge == 0 && c.FlushSchedule == nil:
 		return fmt.Errorf("at least one flush mechanism must be non-zero")
+	case c.FlushBucket != 0 && c.Segments != 0:
+		return fmt.Errorf("segments cannot be pre-created with bucketed flushes")
+	case c.Streaming && c.KeyProvider != nil:
+		return fmt.Errorf("encrypted files cannot be streamed")
+	case c.Codec != "" && c.Codec != "gzip" && c.Codec != "zstd":
+		return fmt.Errorf("unsupported codec %q", c.Codec)
+	case c.Dictionary && c.Codec != "zstd":
+		return fmt.Errorf("dictionaries require the zstd codec")
+	case c.Streaming && c.Codec != "":
+		return fmt.Errorf("compressed files cannot be streamed")
+	case c.Checksum != "" && checksums[c.Checksum] == nil:
+		return fmt.Errorf("unsupported checksum %q", c.Checksum)
+	case c.Sidecar && c.Checksum == "":
+		return fmt.Errorf("sidecar files require a checksum")
+	case c.Durability < NoSync || c.Durability > PowerSafe:
+		return fmt.Errorf("unsupported durability %s", c.Durability)
+	case c.DoneDir == "" && (c.DoneLink || c.DoneRetention != 0):
+		return fmt.Errorf("done directory options require a done directory")
+	case c.TrashDir == "" && c.TrashRetention != 0:
+		return fmt.Errorf("trash retention requires a trash directory")
+	case c.Partitioner != nil && c.Partitions <= 0:
+		return fmt.Errorf("partitioner requires a positive partition count")
+	case c.Recover && c.Manifest != "":
+		return fmt.Errorf("recovery cannot be combined with a manifest")
+	case c.SingleWriter && c.Async != 0:
+		return fmt.Errorf("single writer cannot be combined with async writes")
+	case c.StrictBytes && (c.Codec != "" || c.KeyProvider != nil):
+		return fmt.Errorf("strict byte limits cannot be applied to compressed or encrypted files")
+	case c.Trailer && (c.Codec != "" || c.KeyProvider != nil):
+		return fmt.Errorf("trailers cannot be appended to compressed or encrypted files")
+	case c.Parquet != nil && (c.Codec != "" || c.KeyProvider != nil || c.Trailer || c.Streaming):
+		return fmt.Errorf("parquet files cannot be compressed, encrypted, streamed or given trailers")
+	case c.Deferred && (c.KeyProvider != nil || c.Trailer || c.Dictionary || c.Streaming || c.Parquet != nil || c.MemoryBytes != 0):
+		return fmt.Errorf("deferred encoding cannot be combined with encryption, trailers, dictionaries, streaming, parquet or memory")
+	case c.MemoryBytes != 0 && (c.KeyProvider != nil || c.Streaming || c.Paranoid || c.Segments != 0):
+		return fmt.Errorf("files held in memory cannot be encrypted, streamed, preallocated or checked by paranoid mode")
+	case c.MemoryBytes != 0 && (c.Sidecar || c.MetaFile || c.Manifest != "" || c.DoneDir != ""):
+		return fmt.Errorf("files held in memory cannot be given sidecars, metafiles, manifests or done directories")
+	case c.Parquet != nil && (c.CSVHeader != nil || c.Hooks.Header != nil || c.Hooks.Footer != nil):
+		return fmt.Errorf("parquet files cannot be given headers or footers")
 	default:
 		return nil
 	}
 }
 
+// Hooks are optional callbacks invoked at lifecycle points. They may be
+// called with the buffer locked and must not call back into it.
+type Hooks struct {
+	OnOpen  func(path string)                 // File opened
+	OnFlush func(f *Flush)                    // File flushed
+	OnError func(err error)                   // Error from background flushes or deferred rotations
+	OnDrop  func(c Condition, f *Flush)       // Flush or write dropped by policy, f is nil for writes
+	OnTrip  func(open bool, err error)        // Circuit breaker tripped by err, or closed again
+	Header  func(w io.Writer) error           // Write a preamble to each new file, counted in its bytes
+	Footer  func(w io.Writer, f *Flush) error // Write a trailer to each file before it is flushed, counted in its bytes
+}
+
 // Buffer represents a 1:N on-disk buffer.
 type Buffer struct {
 	*Config
 
 	verbosity int
+	logger    Logger
+	fields    []interface{}
 	path      string
+	key       string
 	ids       int64
 	id        int64
 
@@ -78,19 +263,95 @@ type Buffer struct {
 	buf    *bufio.Writer
 	opened time.Time
 	writes int64
+	framed int64
 	bytes  int64
 	file   *os.File
+	staged string
+	moved  bool
+	w      io.Writer
+	hash   hash.Hash
+	sink   io.Writer
+	crc    hash.Hash32
+	codec  *codecWriter
+	rows   *rowGroup
+	keyID  string
 	tick   *time.Ticker
+	bucket time.Time
+	roll   *time.Timer
+	idle   *time.Timer
+	cron   *time.Timer
+	expiry *time.Timer
+	aging  *time.Timer
+	last   time.Time
+	first  time.Time
+
+	segments   chan *segment
+	refills    sync.WaitGroup
+	encodings  sync.WaitGroup
+	encoded    *Flush
+	tap        *tap
+	drain      sync.Once
+	keys       map[string]*Buffer
+	batches    *batches
+	policies   *policies
+	manifest   *manifest
+	sequences  *sequences
+	staleness  *staleness
+	tees       *tees
+	states     *states
+	dictionary *dictionary
+	shadow     *shadowing
+	limiter    *limiter
+	breaker    *breaker
+	spilling   bool
+	memory     *memory
+	seq        int64
+	draining   bool
+	paused     bool
+	stopped    bool
+	quit       chan struct{}
+	halt       sync.Once
+	loops      sync.WaitGroup
+	ring       chan op
+	ringMu     sync.RWMutex
+	ringClosed bool
+	writers    sync.WaitGroup
+	histograms *histograms
+	contains   map[string]bool
+	meta       map[string]map[string]bool
+	live       *live
+	backlog    backlog
+	flushes    int64
+	flushed    time.Time
+	total      totals
+	errors     int64
+	snapshots  *time.Ticker
+	signals    chan os.Signal
+	prunes     *time.Ticker
+	name       *template.Template
+
+	window  time.Time
+	latency latencies
+	reports *time.Ticker
 }
 
 // New buffer at `path`. The path given is used for the base
-// of the filenames created, which append ".{pid}.{id}.{fid}".
+// of the filenames created, which append ".{pid}.{id}.{fid}"
+// unless Config.Filename is set.
 func New(path string, config *Config) (*Buffer, error) {
+	return newBuffer(path, config, nil, "")
+}
+
+// New buffer for partition `key` of `root`, which are empty for the root
+// buffer. Partitions share the tracking state of the root.
+func newBuffer(path string, config *Config, root *Buffer, key string) (*Buffer, error) {
 	id := atomic.AddInt64(&ids, 1)
 
 	b := &Buffer{
+		quit:      make(chan struct{}),
 		Config:    config,
 		path:      path,
+		key:       key,
 		id:        id,
 		verbosity: 1,
 	}
@@ -100,69 +361,481 @@ func New(path string, config *Config) (*Buffer, error) {
 		b.Logger = log.New(os.Stderr, prefix, log.LstdFlags)
 	}
 
+	b.logger = b.Log
+	if b.logger == nil {
+		b.logger = StdLogger(b.Logger, b.Verbosity)
+	}
+
+	b.total.reasons = make(map[Reason]int64)
+
+	b.fields = []interface{}{"buffer", b.id, "path", path}
+	if key != "" {
+		b.fields = append(b.fields, "key", key)
+	}
+
 	if b.Queue == nil {
 		b.Queue = make(chan *Flush)
 	}
 
-	if b.FlushInterval != 0 {
-		b.tick = time.NewTicker(config.FlushInterval)
-		go b.loop()
+	err := config.Validate()
+	if err != nil {
+		return nil, err
 	}
 
-	err := config.Validate()
+	if root == nil {
+		b.batches = newBatches()
+		b.policies = newPolicies(b.Policies)
+		b.histograms = newHistograms(config)
+		b.staleness = newStaleness()
+		b.tees = newTees()
+		b.states = newStates()
+		b.dictionary = &dictionary{}
+		b.shadow = &shadowing{}
+		b.limiter = newLimiter(config)
+		b.breaker = newBreaker(config)
+	} else {
+		b.batches = root.batches
+		b.policies = root.policies
+		b.histograms = root.histograms
+		b.manifest = root.manifest
+		b.sequences = root.sequences
+		b.staleness = root.staleness
+		b.tees = root.tees
+		b.states = root.states
+		b.dictionary = root.dictionary
+		b.shadow = root.shadow
+		b.limiter = root.limiter
+		b.breaker = root.breaker
+	}
+
+	if b.SeqFile != "" && root == nil {
+		b.sequences, err = openSequences(b.SeqFile, b.Durability >= PowerSafe)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if b.Expvar != "" && key == "" {
+		err := b.publishExpvar()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if filename := b.filename(); filename != "" {
+		b.name, err = template.New("filename").Parse(filename)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if b.Dictionary && key == "" {
+		err := b.loadDictionary()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if b.Probe && key == "" {
+		err := b.SelfTest()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if b.RemoveStale != 0 && key == "" {
+		err := b.clean()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var backlog []*Flush
+	if b.Recover && key == "" {
+		backlog, err = b.recoverable()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if b.Manifest != "" && key == "" {
+		b.log(1, "replaying manifest %q", b.Manifest)
+		b.manifest, backlog, err = openManifest(b.Manifest, b.FileMode, b.Durability >= PowerSafe)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if b.Segments != 0 {
+		err := b.preallocate()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = b.open()
 	if err != nil {
 		return nil, err
 	}
 
-	return b, b.open()
+	b.start()
+
+	if len(backlog) != 0 {
+		b.backlog.total = int64(len(backlog))
+		go b.deliver(interleave(backlog, b.RecoverRatio))
+	}
+
+	return b, nil
+}
+
+// Start background work.
+func (b *Buffer) start() {
+	if b.ringed() {
+		size := b.Async
+		if size == 0 {
+			size = singleWriterRing
+		}
+
+		b.ringMu.Lock()
+		b.ring = make(chan op, size)
+		b.ringClosed = false
+		b.ringMu.Unlock()
+
+		b.writers.Add(1)
+		go b.writer()
+	}
+
+	if b.FlushInterval != 0 {
+		b.tick = time.NewTicker(b.FlushInterval)
+		b.loops.Add(1)
+		go b.loop()
+	}
+
+	if b.FlushBucket != 0 {
+		b.schedule()
+	}
+
+	if b.FlushSchedule != nil {
+		b.plan()
+	}
+
+	if b.key != "" {
+		return
+	}
+
+	if b.ReportInterval != 0 {
+		b.reports = time.NewTicker(b.ReportInterval)
+		go b.reporter()
+	}
+
+	if d := b.pruneInterval(); d != 0 {
+		b.prunes = time.NewTicker(d)
+		go b.pruner()
+	}
+
+	if b.StatsInterval != 0 {
+		b.snapshots = time.NewTicker(b.StatsInterval)
+		go b.snapshot()
+	}
+
+	if b.Logrotate && len(rotateSignals) != 0 {
+		b.signals = make(chan os.Signal, 1)
+		signal.Notify(b.signals, rotateSignals...)
+		go b.rotator()
+	}
 }
 
 // Write implements io.Writer.
 func (b *Buffer) Write(data []byte) (int, error) {
+	return b.submit(data, nil)
+}
+
+// Write `data` annotated with `meta`.
+func (b *Buffer) submit(data []byte, meta map[string]string) (n int, err error) {
 	b.log(3, "write %s", data)
 
+	if b.Shadow != nil {
+		defer func() {
+			if err == nil {
+				b.mirror(data, meta)
+			}
+		}()
+	}
+
+	if b.Instrument != nil {
+		start := time.Now()
+		defer func() {
+			b.Instrument.Wrote(n, time.Since(start), err)
+		}()
+	}
+
+	err = b.checkSize(len(data))
+	if err != nil {
+		return 0, err
+	}
+
+	drop, err := b.throttle(1, len(data), false)
+	if err == nil && !drop {
+		drop, err = b.guard()
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	if drop {
+		return len(data), nil
+	}
+
+	if b.Async != 0 {
+		return b.enqueue(data, meta)
+	}
+
+	if b.SingleWriter {
+		return b.funnel(data, meta)
+	}
+
+	if b.Labels {
+		b.do("write", func() {
+			n, err = b.put(data, meta)
+		})
+		return
+	}
+
+	return b.put(data, meta)
+}
+
+// Write and flush when thresholds are met.
+func (b *Buffer) put(data []byte, meta map[string]string) (int, error) {
 	b.Lock()
 	defer b.Unlock()
+	return b.store(data, meta)
+}
+
+// Write and flush when thresholds are met, with the lock held.
+func (b *Buffer) store(data []byte, meta map[string]string) (n int, err error) {
+	defer func() {
+		if err != nil {
+			atomic.AddInt64(&b.errors, 1)
+		}
+	}()
+
+	err = b.admit()
+	if err != nil {
+		return 0, err
+	}
+
+	err = b.fit(len(data) + len(b.Delimiter))
+	if err != nil {
+		return 0, err
+	}
 
-	n, err := b.write(data)
+	n, err = b.write(data)
 	if err != nil {
 		return n, err
 	}
 
+	b.annotate(meta)
+
+	return n, b.thresholds(data)
+}
+
+// Check a write may be made, rolling over to the current bucket.
+func (b *Buffer) admit() error {
+	if b.draining && b.Async == 0 {
+		return ErrDraining
+	}
+
+	if b.stopped {
+		return ErrClosed
+	}
+
+	err := b.overQuota()
+	if err != nil {
+		return err
+	}
+
+	if b.FlushBucket != 0 && !time.Now().Before(b.bucket.Add(b.FlushBucket)) {
+		return b.attempt(b.rollover)
+	}
+
+	return nil
+}
+
+// Flush when thresholds are met after writing the record `last`.
+func (b *Buffer) thresholds(last []byte) error {
 	if b.FlushWrites != 0 && b.writes >= b.FlushWrites {
-		err := b.flush(Writes)
+		err := b.attempt(func() error { return b.flush(Writes) })
 		if err != nil {
-			return n, err
+			return err
 		}
 	}
 
 	if b.FlushBytes != 0 && b.bytes >= b.FlushBytes {
-		err := b.flush(Bytes)
+		err := b.attempt(func() error { return b.flush(Bytes) })
 		if err != nil {
-			return n, err
+			return err
 		}
 	}
 
-	return n, err
+	if b.FlushFunc != nil && b.writes != 0 && b.FlushFunc(b.writes, b.bytes, time.Since(b.opened), last) {
+		return b.attempt(func() error { return b.flush(Predicate) })
+	}
+
+	return nil
 }
 
-// Close the underlying file after flushing.
+// Close the underlying file after flushing, removing it when empty.
 func (b *Buffer) Close() error {
+	_, err := b.CloseFile()
+	return err
+}
+
+// CloseFile closes like Close, returning the final flushed file, or nil
+// when it was empty. Files of keyed partitions are only published.
+func (b *Buffer) CloseFile() (*Flush, error) {
+	b.closeRing()
+
+	b.halt.Do(func() {
+		close(b.quit)
+	})
+
+	b.loops.Wait()
+
 	b.Lock()
 	defer b.Unlock()
 
+	if b.stopped {
+		return nil, nil
+	}
+
+	b.stopped = true
+
 	if b.tick != nil {
 		b.tick.Stop()
 	}
 
-	return b.flush(Forced)
+	if b.roll != nil {
+		b.roll.Stop()
+		b.roll = nil
+	}
+
+	if b.idle != nil {
+		b.idle.Stop()
+		b.idle = nil
+	}
+
+	if b.cron != nil {
+		b.cron.Stop()
+		b.cron = nil
+	}
+
+	if b.aging != nil {
+		b.aging.Stop()
+		b.aging = nil
+	}
+
+	if b.reports != nil {
+		b.reports.Stop()
+	}
+
+	if b.snapshots != nil {
+		b.snapshots.Stop()
+	}
+
+	if b.prunes != nil {
+		b.prunes.Stop()
+	}
+
+	if b.signals != nil {
+		signal.Stop(b.signals)
+	}
+
+	if b.key == "" {
+		b.staleness.stop()
+	}
+
+	for _, k := range b.keys {
+		err := k.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if b.key == "" {
+		b.tees.close()
+	}
+
+	f, err := b.flushFile(Forced)
+	if err != nil {
+		return f, err
+	}
+
+	if b.expiry != nil {
+		b.expiry.Stop()
+	}
+
+	err = b.remove()
+	if err != nil {
+		return f, err
+	}
+
+	err = b.release()
+	if err != nil {
+		return f, err
+	}
+
+	b.encodings.Wait()
+
+	if f != nil && b.Deferred {
+		f = b.encoded
+	}
+
+	if b.manifest != nil && b.key == "" {
+		return f, b.manifest.close()
+	}
+
+	return f, nil
 }
 
 // Flush forces a flush.
 func (b *Buffer) Flush() error {
+	_, err := b.FlushFile()
+	return err
+}
+
+// FlushFile forces a flush like Flush, returning the flushed file, or nil
+// when it was empty. Files of keyed partitions are only published. With
+// Config.Deferred the file is returned before it is encoded, so its Codec
+// and Checksum are unset; the flush published to the Queue is complete.
+func (b *Buffer) FlushFile() (*Flush, error) {
+	return b.flushWith(Forced)
+}
+
+// FlushWithReason forces a flush like Flush, with a caller-supplied
+// reason such as "deploy" or "sigterm" set on the Flush and in metrics.
+func (b *Buffer) FlushWithReason(reason string) error {
+	_, err := b.flushWith(Reason(reason))
+	return err
+}
+
+// Force a flush for the given reason, including keyed partitions.
+func (b *Buffer) flushWith(reason Reason) (*Flush, error) {
 	b.Lock()
 	defer b.Unlock()
-	return b.flush(Forced)
+
+	if b.stopped {
+		return nil, ErrClosed
+	}
+
+	for _, k := range b.keys {
+		_, err := k.flushWith(reason)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return b.flushFile(reason)
 }
 
 // Writes returns the number of writes made to the current file.
@@ -181,111 +854,777 @@ func (b *Buffer) Bytes() int64 {
 
 // Loop for flush interval.
 func (b *Buffer) loop() {
-	for range b.tick.C {
+	b.label("interval")
+
+	defer b.loops.Done()
+
+	for {
+		select {
+		case <-b.quit:
+			return
+		case <-b.tick.C:
+			if b.breaker.open() {
+				continue
+			}
+
+			b.Lock()
+			b.error(b.attempt(func() error { return b.flush(Interval) }))
+			b.Unlock()
+		}
+	}
+}
+
+// Schedule a flush at the next bucket boundary.
+func (b *Buffer) schedule() {
+	now := time.Now()
+	next := now.Truncate(b.FlushBucket).Add(b.FlushBucket)
+	b.roll = time.AfterFunc(next.Sub(now), func() {
+		b.label("bucket")
+
 		b.Lock()
-		b.flush(Interval)
-		b.Unlock()
+		defer b.Unlock()
+
+		if b.roll == nil {
+			return
+		}
+
+		b.error(b.attempt(b.rollover))
+		b.schedule()
+	})
+}
+
+// Roll over to the current bucket, removing the file when empty.
+func (b *Buffer) rollover() error {
+	if b.writes != 0 {
+		return b.flush(Bucket)
+	}
+
+	err := b.remove()
+	if err != nil {
+		return err
 	}
+
+	return b.open()
+}
+
+// Remove the current file, which has no writes.
+func (b *Buffer) remove() error {
+	path := b.staged
+	b.log(2, "removing empty %q", path)
+	b.states.forget(path)
+
+	if b.memory != nil {
+		b.memory.stop()
+	}
+
+	if b.live != nil {
+		b.live.seal(0, "")
+	}
+
+	if b.file == nil {
+		return nil
+	}
+
+	err := b.file.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(path)
 }
 
 // Open a new buffer.
 func (b *Buffer) open() error {
-	path := b.pathname()
+	if b.FlushBucket != 0 {
+		b.bucket = time.Now().Truncate(b.FlushBucket)
+	}
 
-	b.log(1, "opening %s", path)
-	f, err := os.Create(path)
+	b.spilling = b.overflowing()
+	f, path, seq, err := b.begin()
 	if err != nil {
 		return err
 	}
 
-	b.log(2, "buffer size %d", b.BufferSize)
+	var w io.Writer
+	b.memory = nil
+	if f == nil {
+		b.memory = newMemory(b, path)
+		w = b.memory
+	} else {
+		w = b.retried(f)
+	}
+
+	b.hash = nil
+	if b.Checksum != "" && !b.Deferred {
+		b.hash = checksums[b.Checksum]()
+		w = io.MultiWriter(w, b.hash)
+	}
+
+	b.sink = w
+	b.crc = nil
+	if b.Trailer {
+		b.crc = crc32.New(castagnoli)
+		w = io.MultiWriter(w, b.crc)
+	}
+
+	b.keyID = ""
+	if b.KeyProvider != nil {
+		w, b.keyID, err = b.encrypt(w)
+		if err != nil {
+			return err
+		}
+	}
+
+	b.codec = nil
+	if b.Codec != "" && !b.Deferred {
+		b.codec = &codecWriter{w: w, codec: b.Codec, passthrough: b.Passthrough}
+		if b.Dictionary {
+			b.codec.dict, _ = b.dictionary.current()
+		}
+		w = b.codec
+	}
+
+	b.rows = nil
+	if b.Parquet != nil {
+		b.rows = newRowGroup(b.Parquet)
+	}
+
+	b.log(2, "buffer size %d", b.bufferSize())
 	if b.BufferSize != 0 {
-		b.buf = bufio.NewWriterSize(f, b.BufferSize)
+		b.tap = &tap{w: w}
+		b.buf = bufio.NewWriterSize(b.tap, b.bufferSize())
+		w = b.buf
 	}
 
 	b.log(2, "reset state")
 	b.opened = time.Now()
+	b.first = time.Time{}
 	b.writes = 0
+	b.framed = 0
 	b.bytes = 0
 	b.file = f
+	b.staged = path
+	b.moved = false
+	b.seq = seq
+	b.w = w
+
+	if b.CSVHeader != nil {
+		err = b.header()
+		if err != nil {
+			return err
+		}
+	}
+
+	if b.Hooks.Header != nil {
+		err = b.preamble()
+		if err != nil {
+			return err
+		}
+	}
+
+	if b.MaxAge != 0 {
+		b.arm()
+	}
+
+	if b.Streaming {
+		b.live = b.live.succeed(path)
+	}
+
+	b.states.set(path, Writing, nil)
+
+	if b.Hooks.OnOpen != nil {
+		b.Hooks.OnOpen(path)
+	}
 
 	return nil
 }
 
+// Create the next file, its path and sequence, or only the path and
+// sequence of files held in memory until written out.
+func (b *Buffer) begin() (*os.File, string, int64, error) {
+	if b.MemoryBytes != 0 {
+		path, seq, err := b.pathname()
+		return nil, path, seq, err
+	}
+
+	f, seq, err := b.createRetried()
+	if err != nil && b.spill(err) {
+		f, seq, err = b.createRetried()
+	}
+
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	return f, f.Name(), seq, nil
+}
+
+// Writer to `f`, retrying failed writes when configured.
+func (b *Buffer) retried(f *os.File) io.Writer {
+	if b.WriteRetries != 0 || b.makesRoom() {
+		return retrying{b: b, w: f, path: f.Name()}
+	}
+
+	return f
+}
+
 // Write with metrics.
 func (b *Buffer) write(data []byte) (int, error) {
+	if b.writes == 0 {
+		b.first = time.Now()
+	}
+
+	b.record()
+
+	if b.FlushIdle != 0 {
+		b.touch()
+	}
+
+	size := int64(len(data) + len(b.Delimiter))
 	b.writes++
-	b.bytes += int64(len(data))
+	b.bytes += size
+	b.total.writes++
+	b.total.bytes += size
 
-	if b.BufferSize != 0 {
-		return b.buf.Write(data)
+	var n, d int
+	err := b.preflush(int(size))
+	pending := err != nil
+	if err == nil {
+		n, err = b.w.Write(data)
+		if err == nil && len(b.Delimiter) != 0 {
+			d, err = b.w.Write(b.Delimiter)
+		}
+	}
+
+	dropped := false
+	if err != nil {
+		dropped, err = b.writeFailed(data, n+d, pending, err)
+		switch {
+		case dropped:
+			n = len(data)
+		case err != nil:
+			b.unwind(size - int64(n+d))
+		}
+	} else if b.Paranoid {
+		b.reread(data, b.bytes-size)
+		b.reread(b.Delimiter, b.bytes-int64(len(b.Delimiter)))
+	}
+
+	if err == nil && !dropped {
+		b.tees.push(data)
+		b.tees.push(b.Delimiter)
+	}
+
+	if b.Dictionary {
+		b.dictionary.sample(data)
+	}
+
+	if b.MaxBufferAge != 0 && b.buf != nil {
+		b.age()
+	}
+
+	if b.live != nil {
+		b.live.commit(b.committed())
 	}
 
-	return b.file.Write(data)
+	return n, err
 }
 
 // Flush for the given reason and re-open.
 func (b *Buffer) flush(reason Reason) error {
+	if b.paused && reason != Forced {
+		b.log(2, "paused, not flushing (%s)", reason)
+		return nil
+	}
+
+	_, err := b.flushFile(reason)
+	return err
+}
+
+// Flush for the given reason and re-open, returning the flushed file.
+func (b *Buffer) flushFile(reason Reason) (f *Flush, err error) {
 	b.log(1, "flushing (%s)", reason)
 
 	if b.writes == 0 {
 		b.log(2, "nothing to flush")
-		return nil
+		return nil, nil
+	}
+
+	if b.Instrument != nil {
+		done := b.Instrument.Flushing(reason)
+		defer func() {
+			done(f, err)
+		}()
+	}
+
+	if b.Labels {
+		b.do("flush", func() {
+			f, err = b.rotate(reason)
+		})
+		b.observe(err)
+		return
 	}
 
-	err := b.close()
+	f, err = b.rotate(reason)
+	b.observe(err)
+	return
+}
+
+// Rotate the file for the given reason, returning the published flush.
+func (b *Buffer) rotate(reason Reason) (*Flush, error) {
+	err := b.close(reason)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	b.Queue <- &Flush{
-		Reason: reason,
-		Writes: b.writes,
-		Bytes:  b.bytes,
-		Opened: b.opened,
-		Closed: time.Now(),
-		Path:   b.file.Name() + ".closed",
-		Age:    time.Since(b.opened),
+	f := &Flush{
+		Version:  FlushVersion,
+		Reason:   reason,
+		Writes:   b.writes,
+		Bytes:    b.bytes,
+		Opened:   b.opened,
+		First:    b.first,
+		Closed:   time.Now(),
+		Path:     b.closed(),
+		Key:      b.key,
+		KeyID:    b.keyID,
+		Codec:    b.codec.Codec(),
+		Dict:     b.dictionaryID(),
+		Hash:     b.Checksum,
+		Checksum: b.checksum(),
+		Batches:  b.seal(),
+		Meta:     b.aggregated(),
+		Bucket:   b.bucket,
+		Seq:      b.seq,
+		Age:      time.Since(b.opened),
+		Spilled:  b.spilling,
+		Data:     b.held(),
 	}
 
-	return b.open()
+	b.states.move(b.staged, f.Path, Sealed, nil)
+
+	b.flushes++
+	b.flushed = f.Closed
+	b.total.reasons[reason]++
+	b.histograms.observe(f)
+
+	var perr error
+	if b.Deferred {
+		b.postpone(f)
+	} else {
+		perr = b.ship(f)
+	}
+
+	if b.Dictionary {
+		b.error(b.train())
+	}
+
+	err = b.open()
+	if err != nil {
+		return f, err
+	}
+
+	return f, perr
 }
 
-// Close existing file after a rename.
-func (b *Buffer) close() error {
-	if b.file == nil {
+// Write the sidecars of flushed file `f`, journal and publish it.
+func (b *Buffer) ship(f *Flush) error {
+	var serr error
+	if b.Sidecar {
+		serr = b.sidecar(f)
+	}
+
+	if b.MetaFile && serr == nil {
+		serr = b.metafile(f)
+	}
+
+	if serr == nil {
+		serr = b.journal(flushed, f, nil)
+	}
+
+	b.staleness.track(f)
+
+	b.charge(f)
+
+	perr := b.publish(f)
+	if perr == nil {
+		perr = serr
+	}
+
+	b.reclaim()
+
+	if b.Hooks.OnFlush != nil {
+		b.Hooks.OnFlush(f)
+	}
+
+	return perr
+}
+
+// Close existing file flushed for `reason`. The file is renamed before
+// its writers are finalized, so it remains writable when renaming fails.
+func (b *Buffer) close(reason Reason) error {
+	if b.staged == "" {
 		return nil
 	}
 
-	path := b.file.Name()
+	path := b.staged
+	closed := b.closed()
 
-	b.log(2, "renaming %q", path)
-	err := os.Rename(path, path+".closed")
+	err := b.settle(path, closed)
 	if err != nil {
 		return err
 	}
 
+	if b.rows != nil {
+		err = b.endRows()
+		if err != nil {
+			return err
+		}
+	}
+
+	if b.Hooks.Footer != nil {
+		err = b.footer(reason)
+		if err != nil {
+			return err
+		}
+	}
+
 	if b.BufferSize != 0 {
-		b.log(2, "flushing %q", path)
+		b.log(2, "flushing %q", b.staged)
 		err = b.buf.Flush()
 		if err != nil {
 			return err
 		}
 	}
 
+	if b.codec != nil {
+		err = b.codec.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	if b.Paranoid {
+		b.verify()
+	}
+
+	if b.crc != nil {
+		err = b.trailer()
+		if err != nil {
+			return err
+		}
+	}
+
+	if b.memory != nil {
+		b.memory.stop()
+	}
+
+	if b.file == nil {
+		b.log(2, "sealing %q, held in memory", path)
+		if b.live != nil {
+			b.live.seal(b.bytes, closed)
+		}
+		return nil
+	}
+
+	if b.Durability >= FileSync {
+		b.log(2, "syncing %q", path)
+		err = b.file.Sync()
+		if err != nil {
+			return err
+		}
+	}
+
+	// files held in memory may have spilled while finalizing
+	err = b.settle(path, closed)
+	if err != nil {
+		return err
+	}
+
 	b.log(2, "closing %q", path)
-	return b.file.Close()
+	err = b.file.Close()
+
+	if b.live != nil {
+		b.live.seal(b.bytes, closed)
+	}
+
+	if err == nil && b.Durability >= PowerSafe {
+		b.log(2, "syncing directory of %q", closed)
+		err = syncDir(closed)
+	}
+
+	if err == nil && b.Durability >= PowerSafe && filepath.Dir(path) != filepath.Dir(closed) {
+		b.log(2, "syncing directory of %q", path)
+		err = syncDir(path)
+	}
+
+	return err
+}
+
+// Rename `path` to `target`, retrying with backoff.
+func (b *Buffer) rename(path, target string) error {
+	backoff := b.backoff()
+	attempts := 0
+
+	for {
+		attempts++
+		err := os.Rename(path, target)
+		if err == nil {
+			return nil
+		}
+
+		if attempts > b.RenameRetries {
+			return &RenameError{Path: path, Target: target, Attempts: attempts, Err: err}
+		}
+
+		b.log(1, "error renaming %q (attempt %d): %s", path, attempts, err)
+		time.Sleep(backoff.Backoff(attempts))
+	}
+}
+
+// Rename the current file at `path` to `closed`, unless it was renamed
+// already or is held in memory.
+func (b *Buffer) settle(path, closed string) error {
+	if b.file == nil || b.moved {
+		return nil
+	}
+
+	b.log(2, "renaming %q", path)
+	err := b.mkdir(closed)
+	if err != nil {
+		return err
+	}
+
+	err = b.rename(path, closed)
+	if err != nil {
+		return err
+	}
+
+	b.moved = true
+	return nil
+}
+
+// Create the next file and its sequence, taking a pre-created segment
+// when available.
+func (b *Buffer) create() (*os.File, int64, error) {
+	select {
+	case s := <-b.segments:
+		f, err := b.claim(s)
+		if err == nil {
+			return f, s.seq, nil
+		}
+		b.error(err)
+	default:
+	}
+
+	path, seq, err := b.pathname()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	b.log(1, "opening %s", path)
+	f, err := b.createNew(path)
+	return f, seq, err
+}
+
+// Create file `path` with the configured mode.
+func (b *Buffer) createFile(path string) (*os.File, error) {
+	return b.openFile(path, os.O_TRUNC)
+}
+
+// Open `path` for writing with the configured mode, creating it and its
+// directory, with the additional `flag`.
+func (b *Buffer) openFile(path string, flag int) (*os.File, error) {
+	err := b.mkdir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	mode := b.FileMode
+	if mode == 0 {
+		mode = 0666
+	}
+
+	return os.OpenFile(path, os.O_RDWR|os.O_CREATE|flag, mode)
 }
 
-// Pathname for a new buffer.
-func (b *Buffer) pathname() string {
-	fid := atomic.AddInt64(&b.ids, 1)
-	return fmt.Sprintf("%s.%d.%d.%d", b.path, pid, b.id, fid)
+// Create missing parent directories of `path` with the configured mode.
+func (b *Buffer) mkdir(path string) error {
+	mode := b.DirMode
+	if mode == 0 {
+		mode = 0755
+	}
+
+	return os.MkdirAll(filepath.Dir(path), mode)
 }
 
-// Log helper.
+// Pre-create the segment pool.
+func (b *Buffer) preallocate() error {
+	b.log(2, "pre-creating %d segments", b.Segments)
+	b.segments = make(chan *segment, b.Segments)
+
+	for i := 0; i < b.Segments; i++ {
+		path, seq, err := b.pathname()
+		if err != nil {
+			return err
+		}
+
+		f, err := b.createNew(path)
+		if err != nil {
+			return err
+		}
+		b.segments <- &segment{f, seq}
+	}
+
+	return nil
+}
+
+// Buffer size for writes, rounded up to whole pages with segments.
+func (b *Buffer) bufferSize() int {
+	if b.Segments == 0 {
+		return b.BufferSize
+	}
+
+	page := os.Getpagesize()
+	return (b.BufferSize + page - 1) / page * page
+}
+
+// Claim segment `s`, renaming it for the current time and directory,
+// and pre-create its replacement.
+func (b *Buffer) claim(s *segment) (*os.File, error) {
+	path, seq, err := b.pathname()
+	if err != nil {
+		b.error(err)
+	} else {
+		b.refills.Add(1)
+		go b.refill(path, seq)
+	}
+
+	name, err := b.nameOf(s.seq)
+	if err != nil {
+		b.discard(s)
+		return nil, err
+	}
+
+	b.log(1, "opening %s (segment)", name)
+	if name == s.Name() {
+		return s.File, nil
+	}
+
+	err = b.mkdir(name)
+	if err == nil {
+		err = os.Rename(s.Name(), name)
+	}
+
+	if err != nil {
+		b.discard(s)
+		return nil, err
+	}
+
+	s.Close()
+	return b.openFile(name, 0)
+}
+
+// Close and remove the unused segment `s`.
+func (b *Buffer) discard(s *segment) error {
+	b.log(2, "removing segment %q", s.Name())
+	s.Close()
+	return os.Remove(s.Name())
+}
+
+// Pre-create a segment at `path` with sequence `seq` to replace one
+// taken from the pool.
+func (b *Buffer) refill(path string, seq int64) {
+	defer b.refills.Done()
+	b.label("refill")
+
+	b.log(2, "pre-creating %s", path)
+	f, err := b.createNew(path)
+	if err != nil {
+		b.error(err)
+		return
+	}
+
+	b.segments <- &segment{f, seq}
+}
+
+// Remove unused segments.
+func (b *Buffer) release() error {
+	if b.segments == nil {
+		return nil
+	}
+
+	b.refills.Wait()
+
+	for {
+		select {
+		case s := <-b.segments:
+			err := b.discard(s)
+			if err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// Labels for pprof.
+func (b *Buffer) labels(stage string) pprof.LabelSet {
+	return pprof.Labels("buffer", strconv.FormatInt(b.id, 10), "stage", stage)
+}
+
+// Run fn with pprof labels for the given stage.
+func (b *Buffer) do(stage string, fn func()) {
+	pprof.Do(context.Background(), b.labels(stage), func(context.Context) {
+		fn()
+	})
+}
+
+// Label the current goroutine for the given stage when enabled.
+func (b *Buffer) label(stage string) {
+	if b.Labels {
+		pprof.SetGoroutineLabels(pprof.WithLabels(context.Background(), b.labels(stage)))
+	}
+}
+
+// Error helper for background errors, logging and publishing them
+// to the OnError hook and Errors channel.
+func (b *Buffer) error(err error) {
+	if err == nil {
+		return
+	}
+
+	atomic.AddInt64(&b.errors, 1)
+	b.report(err)
+}
+
+// Report an error which has already been counted.
+func (b *Buffer) report(err error) {
+	b.logger.Error(err.Error(), b.fields...)
+
+	if b.Hooks.OnError != nil {
+		b.Hooks.OnError(err)
+	}
+
+	if b.Errors != nil {
+		select {
+		case b.Errors <- err:
+		default:
+			b.log(2, "errors channel full")
+		}
+	}
+}
+
+// Log helper, logging level 1 as info and levels 2-3 as debug. Level 3
+// traces individual writes and is only logged when Verbosity is 3.
 func (b *Buffer) log(n int, msg string, args ...interface{}) {
-	if b.Verbosity >= n {
-		b.Logger.Printf(msg, args...)
+	switch {
+	case n >= 3 && b.Verbosity < 3:
+		return
+	case n == 1:
+		b.logger.Info(fmt.Sprintf(msg, args...), b.fields...)
+	default:
+		b.logger.Debug(fmt.Sprintf(msg, args...), b.fields...)
 	}
 }
@@ -1,6 +1,8 @@
 package buffer
 
 import (
+	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -140,6 +142,214 @@ func TestBuffer_Write_FlushOnInterval(t *testing.T) {
 	assert.Equal(t, nil, err)
 }
 
+// Test flushing on bucket boundaries.
+func TestBuffer_Write_FlushOnBucket(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushBucket: time.Second,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello world"))
+
+	flush := <-b.Queue
+	assert.Equal(t, int64(1), flush.Writes)
+	assert.Equal(t, Bucket, flush.Reason)
+	assert.Equal(t, flush.Bucket, flush.Opened.Truncate(time.Second))
+
+	bucket := flush.Bucket.UTC().Format("20060102T150405Z")
+	assert.Equal(t, true, strings.HasPrefix(flush.Path, "/tmp/buffer."+bucket+"."))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test flushing on a predicate.
+func TestBuffer_Write_FlushOnFunc(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue: make(chan *Flush, 100),
+		FlushFunc: func(writes, bytes int64, age time.Duration, last []byte) bool {
+			return string(last) == "end\n"
+		},
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello\n"))
+	b.Write([]byte("world\n"))
+	b.Write([]byte("end\n"))
+	b.Write([]byte("next\n"))
+
+	flush := <-b.Queue
+	assert.Equal(t, int64(3), flush.Writes)
+	assert.Equal(t, int64(16), flush.Bytes)
+	assert.Equal(t, Predicate, flush.Reason)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	flush = <-b.Queue
+	assert.Equal(t, int64(1), flush.Writes)
+}
+
+// Test file and directory modes.
+func TestBuffer_Modes(t *testing.T) {
+	os.RemoveAll("/tmp/buffer-modes")
+
+	b, err := New("/tmp/buffer-modes/nested/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1,
+		FileMode:    0600,
+		DirMode:     0700,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+	flush := <-b.Queue
+
+	info, err := os.Stat(flush.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	info, err = os.Stat("/tmp/buffer-modes/nested")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test rename failures are retried without failing writes.
+func TestBuffer_Write_RenameError(t *testing.T) {
+	os.RemoveAll("/tmp/buffer-rename")
+	os.MkdirAll("/tmp/buffer-rename/buffer.1.closed/taken", 0755)
+
+	b, err := New("/tmp/buffer-rename/buffer", &Config{
+		Queue:         make(chan *Flush, 100),
+		FlushWrites:   1,
+		Filename:      "{{.Path}}.{{.Seq}}",
+		RenameRetries: 2,
+		RenameBackoff: time.Millisecond,
+		Verbosity:     0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	_, err = b.Write([]byte("hello"))
+	assert.Equal(t, nil, err)
+
+	err = b.Flush()
+	e, ok := err.(*RenameError)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, 3, e.Attempts)
+
+	os.RemoveAll("/tmp/buffer-rename/buffer.1.closed")
+
+	_, err = b.Write([]byte("world"))
+	assert.Equal(t, nil, err)
+
+	flush := <-b.Queue
+	assert.Equal(t, int64(2), flush.Writes)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test background errors are published.
+func TestBuffer_Errors(t *testing.T) {
+	os.RemoveAll("/tmp/buffer-errors")
+	os.MkdirAll("/tmp/buffer-errors/buffer.1.closed/taken", 0755)
+
+	b, err := New("/tmp/buffer-errors/buffer", &Config{
+		Queue:         make(chan *Flush, 100),
+		Errors:        make(chan error, 10),
+		FlushInterval: 10 * time.Millisecond,
+		Filename:      "{{.Path}}.{{.Seq}}",
+		Verbosity:     0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+
+	_, ok := (<-b.Errors).(*RenameError)
+	assert.Equal(t, true, ok)
+
+	os.RemoveAll("/tmp/buffer-errors/buffer.1.closed")
+
+	flush := <-b.Queue
+	assert.Equal(t, Interval, flush.Reason)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test flushing with pre-created segments.
+func TestBuffer_Write_Segments(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 10,
+		Segments:    2,
+		BufferSize:  100,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 100, b.BufferSize)
+	assert.Equal(t, 0, b.bufferSize()%os.Getpagesize())
+
+	write(b, 30, []byte("hello"))
+
+	for i := 0; i < 3; i++ {
+		flush := <-b.Queue
+		assert.Equal(t, int64(10), flush.Writes)
+		assert.Equal(t, int64(50), flush.Bytes)
+	}
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, len(b.segments))
+}
+
+// Test lifecycle hooks.
+func TestBuffer_Hooks(t *testing.T) {
+	var opened, flushed, dropped int
+
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush),
+		FlushWrites: 1,
+		Policies:    map[Condition]Policy{QueueFull: DropNewest},
+		Hooks: Hooks{
+			OnOpen: func(path string) {
+				opened++
+			},
+			OnFlush: func(f *Flush) {
+				flushed++
+			},
+			OnDrop: func(c Condition, f *Flush) {
+				assert.Equal(t, QueueFull, c)
+				dropped++
+			},
+		},
+		Verbosity: 0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+	b.Write([]byte("world"))
+
+	assert.Equal(t, 3, opened)
+	assert.Equal(t, 2, flushed)
+	assert.Equal(t, 2, dropped)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
 // Test config validation.
 func TestConfig_Validate(t *testing.T) {
 	_, err := New("/tmp/buffer", &Config{})
@@ -194,3 +404,140 @@ func BenchmarkBuffer_Write_Bufio(t *testing.B) {
 		}
 	})
 }
+
+// Benchmark buffer writes with pre-created segments.
+func BenchmarkBuffer_Write_Segments(t *testing.B) {
+	b, err := New("/tmp/buffer", &Config{
+		FlushWrites:   30000,
+		FlushBytes:    1 << 30,
+		FlushInterval: time.Minute,
+		BufferSize:    1 << 20,
+		Segments:      8,
+		Verbosity:     0,
+	})
+
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+
+	discard(b)
+
+	t.ResetTimer()
+
+	t.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			b.Write([]byte("hello world"))
+		}
+	})
+}
+
+// Benchmark buffer writes with pprof labels.
+func BenchmarkBuffer_Write_Labels(t *testing.B) {
+	b, err := New("/tmp/buffer", &Config{
+		FlushWrites:   30000,
+		FlushBytes:    1 << 30,
+		FlushInterval: time.Minute,
+		BufferSize:    1 << 10,
+		Labels:        true,
+		Verbosity:     0,
+	})
+
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+
+	discard(b)
+
+	t.ResetTimer()
+
+	t.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			b.Write([]byte("hello world"))
+		}
+	})
+}
+
+// Test returning the flushed file.
+func TestBuffer_FlushFile(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 100,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	f, err := b.FlushFile()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, (*Flush)(nil), f)
+
+	b.Write([]byte("hello"))
+
+	f, err = b.FlushFile()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(1), f.Writes)
+	assert.Equal(t, f, <-b.Queue)
+
+	b.Write([]byte("world"))
+
+	f, err = b.CloseFile()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(1), f.Writes)
+	assert.Equal(t, f, <-b.Queue)
+}
+
+// Test closing twice and use after close.
+func TestBuffer_Close_twice(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:         make(chan *Flush, 100),
+		FlushInterval: time.Millisecond,
+		Verbosity:     0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+	b.WriteKeyed("tobi", []byte("hello"))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	_, err = b.Write([]byte("hello"))
+	assert.Equal(t, ErrClosed, err)
+
+	_, err = b.WriteKeyed("tobi", []byte("hello"))
+	assert.Equal(t, ErrClosed, err)
+
+	_, err = b.WriteKeyed("loki", []byte("hello"))
+	assert.Equal(t, ErrClosed, err)
+
+	err = b.Flush()
+	assert.Equal(t, ErrClosed, err)
+}
+
+// Test flushing with a custom reason.
+func TestBuffer_FlushWithReason(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 100,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+	b.WriteKeyed("tobi", []byte("hello"))
+
+	err = b.FlushWithReason("deploy")
+	assert.Equal(t, nil, err)
+
+	assert.Equal(t, Reason("deploy"), (<-b.Queue).Reason)
+	assert.Equal(t, Reason("deploy"), (<-b.Queue).Reason)
+	assert.Equal(t, int64(2), b.Stats().Reasons["deploy"])
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
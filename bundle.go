@@ -0,0 +1,159 @@
+package buffer
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Bundled is the reason of archives made by Bundle.
+const Bundled Reason = "bundled"
+
+// BundleManifest is the name of the first member of bundles, a JSON array
+// of the Flush of each member file, named by their base names.
+const BundleManifest = "MANIFEST.json"
+
+// Bundle delivers flushes received from Config.Queue bundled into tar.gz
+// archives of `n` files, so shipping costs one request per bundle. The
+// archive lists the bundled files in its BundleManifest member, and its
+// Flush lists them in Members. A partial bundle is delivered `window`
+// after its first file arrives when non-zero, and when the queue is
+// closed, such as by Drain. Bundles are journaled and charged in place of
+// the files they replace, which are removed. The returned channel is
+// closed when the queue is closed or `ctx` is done. Files held in memory
+// cannot be bundled.
+func (b *Buffer) Bundle(ctx context.Context, n int, window time.Duration) (<-chan *Flush, error) {
+	switch {
+	case n <= 0:
+		return nil, fmt.Errorf("bundle size must be positive")
+	case b.MemoryBytes != 0:
+		return nil, fmt.Errorf("files held in memory cannot be bundled")
+	}
+
+	g := &grouping{
+		name:  "bundle",
+		merge: b.bundle,
+		full: func(pending []*Flush) bool {
+			return len(pending) >= n
+		},
+	}
+
+	return b.group(ctx, window, g), nil
+}
+
+// Bundle `files` into a tar.gz archive.
+func (b *Buffer) bundle(files []*Flush) (*Flush, error) {
+	first := files[0]
+	f := &Flush{
+		Version: FlushVersion,
+		Reason:  Bundled,
+		Path:    strings.TrimSuffix(first.Path, filepath.Ext(first.Path)) + ".tar.gz",
+		Key:     first.Key,
+		Bucket:  first.Bucket,
+		Seq:     first.Seq,
+		Opened:  first.Opened,
+		First:   first.First,
+	}
+
+	members := make([]*Flush, len(files))
+	for i, in := range files {
+		m := *in
+		m.Path = filepath.Base(in.Path)
+		members[i] = &m
+
+		f.Members = append(f.Members, m.Path)
+		f.Writes += in.Writes
+		f.Batches = append(f.Batches, in.Batches...)
+
+		if in.Key != f.Key {
+			f.Key = ""
+		}
+
+		if f.First.IsZero() || (!in.First.IsZero() && in.First.Before(f.First)) {
+			f.First = in.First
+		}
+	}
+
+	b.log(1, "bundling %d files into %q", len(files), f.Path)
+
+	err := b.archive(f, files, members)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(f.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	f.Bytes = info.Size()
+
+	f.Closed = time.Now()
+	f.Age = f.Closed.Sub(f.Opened)
+
+	return f, b.supersede(f, files)
+}
+
+// Write `files` to the archive of `f`, listing them as `members`.
+func (b *Buffer) archive(f *Flush, files, members []*Flush) error {
+	manifest, err := json.MarshalIndent(members, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return b.writeAtomic(f, func(w io.Writer) error {
+		z := gzip.NewWriter(w)
+		t := tar.NewWriter(z)
+
+		err := t.WriteHeader(&tar.Header{
+			Name:    BundleManifest,
+			Mode:    0644,
+			Size:    int64(len(manifest)),
+			ModTime: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = t.Write(manifest)
+		if err != nil {
+			return err
+		}
+
+		for i, in := range files {
+			info, err := os.Stat(in.Path)
+			if err != nil {
+				return err
+			}
+
+			err = t.WriteHeader(&tar.Header{
+				Name:    members[i].Path,
+				Mode:    0644,
+				Size:    info.Size(),
+				ModTime: in.Closed,
+			})
+			if err != nil {
+				return err
+			}
+
+			err = appendFile(t, in.Path)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = t.Close()
+		if err != nil {
+			return err
+		}
+
+		return z.Close()
+	})
+}
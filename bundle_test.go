@@ -0,0 +1,74 @@
+package buffer
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test bundling flushed files into archives.
+func TestBuffer_Bundle(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1,
+	})
+
+	assert.Equal(t, nil, err)
+
+	out, err := b.Bundle(context.Background(), 2, 50*time.Millisecond)
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("tobi\n"))
+	b.Write([]byte("loki\n"))
+	b.Write([]byte("jane\n"))
+
+	f := <-out
+	assert.Equal(t, Bundled, f.Reason)
+	assert.Equal(t, int64(2), f.Writes)
+	assert.Equal(t, 2, len(f.Members))
+
+	file, err := os.Open(f.Path)
+	assert.Equal(t, nil, err)
+	defer file.Close()
+
+	z, err := gzip.NewReader(file)
+	assert.Equal(t, nil, err)
+	r := tar.NewReader(z)
+
+	h, err := r.Next()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, BundleManifest, h.Name)
+
+	var members []*Flush
+	err = json.NewDecoder(r).Decode(&members)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, f.Members[0], members[0].Path)
+	assert.Equal(t, Writes, members[0].Reason)
+
+	for i, want := range []string{"tobi\n", "loki\n"} {
+		h, err = r.Next()
+		assert.Equal(t, nil, err)
+		assert.Equal(t, f.Members[i], h.Name)
+
+		data, err := ioutil.ReadAll(r)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, want, string(data))
+
+		_, err = os.Stat(filepath.Join(filepath.Dir(f.Path), h.Name))
+		assert.Equal(t, true, os.IsNotExist(err))
+	}
+
+	f = <-out
+	assert.Equal(t, 1, len(f.Members))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
@@ -0,0 +1,101 @@
+package buffer
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Supported checksum algorithms.
+var checksums = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha1":   sha1.New,
+	"md5":    md5.New,
+	"crc32":  func() hash.Hash { return crc32.NewIEEE() },
+}
+
+// Hex encoded checksum of the file written, empty when disabled.
+func (b *Buffer) checksum() string {
+	if b.hash == nil {
+		return ""
+	}
+
+	return hex.EncodeToString(b.hash.Sum(nil))
+}
+
+// Write the checksum of `f` to a sidecar file in the format of sha256sum
+// and friends, so it can be verified with `sha256sum -c`.
+func (b *Buffer) sidecar(f *Flush) error {
+	path := f.Path + "." + f.Hash
+	line := fmt.Sprintf("%s  %s\n", f.Checksum, filepath.Base(f.Path))
+
+	b.log(2, "writing checksum %q", path)
+	return b.writeFile(path, []byte(line))
+}
+
+// Write `data` to a new file at `path`.
+func (b *Buffer) writeFile(path string, data []byte) error {
+	file, err := b.createFile(path)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(data)
+	if err != nil {
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+
+	return file.Close()
+}
+
+// VerifySidecar checks the flushed file at `path` against its sidecar
+// checksum, returning false when it has none, or ErrChecksum when the
+// data does not match.
+func VerifySidecar(path string) (bool, error) {
+	for name, fn := range checksums {
+		line, err := ioutil.ReadFile(path + "." + name)
+		if os.IsNotExist(err) {
+			continue
+		}
+
+		if err != nil {
+			return true, err
+		}
+
+		var want string
+		_, err = fmt.Sscan(string(line), &want)
+		if err != nil {
+			return true, err
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return true, err
+		}
+		defer f.Close()
+
+		h := fn()
+		_, err = io.Copy(h, f)
+		if err != nil {
+			return true, err
+		}
+
+		if hex.EncodeToString(h.Sum(nil)) != want {
+			return true, ErrChecksum
+		}
+
+		return true, nil
+	}
+
+	return false, nil
+}
@@ -0,0 +1,127 @@
+package buffer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test checksums of flushed files.
+func TestBuffer_Checksum(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 2,
+		Checksum:    "sha256",
+		Sidecar:     true,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+	b.Write([]byte("world"))
+
+	f := <-b.Queue
+	sum := sha256.Sum256([]byte("helloworld"))
+	assert.Equal(t, "sha256", f.Hash)
+	assert.Equal(t, hex.EncodeToString(sum[:]), f.Checksum)
+
+	sidecar, err := ioutil.ReadFile(f.Path + ".sha256")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, f.Checksum+"  "+filepath.Base(f.Path)+"\n", string(sidecar))
+
+	b.Write([]byte("hello"))
+	b.Write([]byte("world"))
+
+	f = <-b.Queue
+	assert.Equal(t, hex.EncodeToString(sum[:]), f.Checksum)
+
+	ok, err := VerifySidecar(f.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, ok)
+
+	err = ioutil.WriteFile(f.Path, []byte("jelloworld"), 0666)
+	assert.Equal(t, nil, err)
+
+	ok, err = VerifySidecar(f.Path)
+	assert.Equal(t, ErrChecksum, err)
+	assert.Equal(t, true, ok)
+
+	ok, err = VerifySidecar(f.Path + ".sha256")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, false, ok)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test checksums of compressed files.
+func TestBuffer_Checksum_codec(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1,
+		Codec:       "gzip",
+		Checksum:    "md5",
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+
+	f := <-b.Queue
+	data, err := ioutil.ReadFile(f.Path)
+	assert.Equal(t, nil, err)
+
+	h := checksums["md5"]()
+	h.Write(data)
+	assert.Equal(t, hex.EncodeToString(h.Sum(nil)), f.Checksum)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test checksums of encrypted files.
+func TestBuffer_Checksum_encrypted(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1,
+		KeyProvider: keys{"tenant-": bytes.Repeat([]byte("k"), 32)},
+		Checksum:    "sha256",
+		Sidecar:     true,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+
+	f := <-b.Queue
+	data, err := ioutil.ReadFile(f.Path)
+	assert.Equal(t, nil, err)
+
+	sum := sha256.Sum256(data)
+	assert.Equal(t, hex.EncodeToString(sum[:]), f.Checksum)
+
+	ok, err := VerifySidecar(f.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, ok)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test unsupported checksums.
+func TestBuffer_Checksum_unsupported(t *testing.T) {
+	_, err := New("/tmp/buffer", &Config{
+		FlushWrites: 1,
+		Checksum:    "crc64",
+	})
+
+	assert.Equal(t, `unsupported checksum "crc64"`, err.Error())
+}
@@ -0,0 +1,363 @@
+// Command diskbuffer lists, verifies, decodes, replays and restores the
+// files flushed by a disk buffer.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tj/go-disk-buffer"
+)
+
+const usage = `Usage: diskbuffer <command> [flags] [args]
+
+Commands:
+  ls      [dir...]         list flushed files with their metadata
+  verify  [flags] file...  verify checksums, trailers and framing
+  cat     [flags] file...  decode and print records, one per line
+  replay  [flags] file...  re-ship files to a directory or command
+  restore [flags] file...  move evicted files out of a trash directory
+
+Run "diskbuffer <command> -h" for the flags of a command.
+`
+
+// Suffixes of files written alongside flushed files.
+var auxiliary = []string{
+	buffer.MetaFileSuffix,
+	buffer.DictionarySuffix,
+	".sha256",
+	".sha1",
+	".md5",
+	".crc32",
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("diskbuffer: ")
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cmd, args := os.Args[1], os.Args[2:]
+
+	var err error
+	switch cmd {
+	case "ls":
+		err = ls(args)
+	case "verify":
+		err = verify(args)
+	case "cat":
+		err = cat(args)
+	case "replay":
+		err = replay(args)
+	case "restore":
+		err = restore(args)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Record framing flags.
+type framing struct {
+	delimiter string
+	delimited bool
+	max       int
+}
+
+// Register the flags on `fs`.
+func (f *framing) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.delimiter, "delimiter", `\n`, "record delimiter, with Go escapes")
+	fs.BoolVar(&f.delimited, "delimited", false, "records are length-delimited instead")
+	fs.IntVar(&f.max, "max", 0, "largest record in bytes, defaults to 1 MiB")
+}
+
+// Buffer configuration of the framing.
+func (f *framing) config() (*buffer.Config, error) {
+	c := &buffer.Config{MaxWriteSize: f.max}
+	if f.delimited {
+		return c, nil
+	}
+
+	d, err := strconv.Unquote(`"` + f.delimiter + `"`)
+	if err != nil || d == "" {
+		return nil, fmt.Errorf("invalid delimiter %q", f.delimiter)
+	}
+
+	c.Delimiter = []byte(d)
+	return c, nil
+}
+
+// List the flushed files in each directory.
+func ls(args []string) error {
+	fs := flag.NewFlagSet("ls", flag.ExitOnError)
+	fs.Parse(args)
+
+	dirs := fs.Args()
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "PATH\tSIZE\tMODIFIED\tREASON\tWRITES")
+
+	for _, dir := range dirs {
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+
+		for _, info := range infos {
+			if info.IsDir() || isAuxiliary(info.Name()) {
+				continue
+			}
+
+			path := filepath.Join(dir, info.Name())
+			reason, writes := "-", "-"
+			if f, err := meta(path); err == nil {
+				reason, writes = string(f.Reason), strconv.FormatInt(f.Writes, 10)
+			}
+
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", path, info.Size(), info.ModTime().Format(time.RFC3339), reason, writes)
+		}
+	}
+
+	return w.Flush()
+}
+
+// Whether `name` is written alongside flushed files.
+func isAuxiliary(name string) bool {
+	for _, suffix := range auxiliary {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Flush metadata written next to the file at `path`.
+func meta(path string) (*buffer.Flush, error) {
+	data, err := ioutil.ReadFile(path + buffer.MetaFileSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	var f buffer.Flush
+	err = json.Unmarshal(data, &f)
+	return &f, err
+}
+
+// Verify the sidecar checksum, trailer and framing of each file.
+func verify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	var fr framing
+	fr.register(fs)
+	fs.Parse(args)
+
+	c, err := fr.config()
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, path := range fs.Args() {
+		n, err := check(path, c)
+		if err != nil {
+			failed++
+			fmt.Printf("%s: %s\n", path, err)
+			continue
+		}
+
+		fmt.Printf("%s: ok, %d records\n", path, n)
+	}
+
+	if failed != 0 {
+		return fmt.Errorf("%d of %d files failed verification", failed, fs.NArg())
+	}
+
+	return nil
+}
+
+// Check the file at `path`, returning its record count.
+func check(path string, c *buffer.Config) (int64, error) {
+	_, err := buffer.VerifySidecar(path)
+	if err != nil {
+		return 0, fmt.Errorf("sidecar: %s", err)
+	}
+
+	_, err = buffer.Verify(path)
+	if err != nil && err != buffer.ErrNoTrailer {
+		return 0, fmt.Errorf("trailer: %s", err)
+	}
+
+	s, err := buffer.Records(path, c)
+	if err != nil {
+		return 0, err
+	}
+	defer s.Close()
+
+	var n int64
+	for s.Scan() {
+		n++
+	}
+
+	if err := s.Err(); err != nil {
+		return n, fmt.Errorf("record %d: %s", n+1, err)
+	}
+
+	return n, nil
+}
+
+// Print the records of each file, one per line.
+func cat(args []string) error {
+	fs := flag.NewFlagSet("cat", flag.ExitOnError)
+	var fr framing
+	fr.register(fs)
+	fs.Parse(args)
+
+	c, err := fr.config()
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	for _, path := range fs.Args() {
+		s, err := buffer.Records(path, c)
+		if err != nil {
+			return err
+		}
+
+		for s.Scan() {
+			w.Write(s.Record())
+			w.WriteByte('\n')
+		}
+
+		err = s.Err()
+		s.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+	}
+
+	return nil
+}
+
+// Re-ship each file to a directory or command.
+func replay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	to := fs.String("to", "", "directory to copy files into, such as the OutDir of a shipper")
+	command := fs.String("exec", "", "command run with each file on stdin and its path as the last argument")
+	fs.Parse(args)
+
+	if (*to == "") == (strings.TrimSpace(*command) == "") {
+		return fmt.Errorf("replay requires one of -to or -exec")
+	}
+
+	for _, path := range fs.Args() {
+		var err error
+		if *to != "" {
+			err = copyInto(*to, path)
+		} else {
+			err = run(*command, path)
+		}
+
+		if err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+
+		fmt.Printf("replayed %s\n", path)
+	}
+
+	return nil
+}
+
+// Move each evicted file and its sidecars from the trash into a directory.
+func restore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	to := fs.String("to", "", "directory to restore files into, such as the OutDir of the buffer")
+	fs.Parse(args)
+
+	if *to == "" {
+		return fmt.Errorf("restore requires -to")
+	}
+
+	for _, path := range fs.Args() {
+		f, err := buffer.RestoreFile(path, *to)
+		if err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+
+		fmt.Printf("restored %s\n", f.Path)
+	}
+
+	return nil
+}
+
+// Copy the file at `path` into `dir`, renaming it into place once
+// complete so watchers never see partial files.
+func copyInto(dir, path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	target := filepath.Join(dir, filepath.Base(path))
+	tmp, err := ioutil.TempFile(dir, ".replay-")
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tmp, src)
+	if err == nil {
+		err = tmp.Sync()
+	}
+
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), target)
+}
+
+// Run `command` with the file at `path` on stdin.
+func run(command, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fields := strings.Fields(command)
+	cmd := exec.Command(fields[0], append(fields[1:], path)...)
+	cmd.Stdin = f
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+
+	"github.com/tj/go-disk-buffer"
+)
+
+// Run `fn` returning what it printed to stdout.
+func capture(t *testing.T, fn func() error) (string, error) {
+	f, err := ioutil.TempFile("", "diskbuffer-stdout-")
+	assert.Equal(t, nil, err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	stdout := os.Stdout
+	os.Stdout = f
+	err = fn()
+	os.Stdout = stdout
+
+	out, rerr := ioutil.ReadFile(f.Name())
+	assert.Equal(t, nil, rerr)
+	return string(out), err
+}
+
+// Test parsing framing flags.
+func TestFraming(t *testing.T) {
+	cases := []struct {
+		args      []string
+		delimiter string
+		max       int
+		err       string
+	}{
+		{args: nil, delimiter: "\n"},
+		{args: []string{"-delimiter", `\t`}, delimiter: "\t"},
+		{args: []string{"-delimiter", `\r\n`, "-max", "10"}, delimiter: "\r\n", max: 10},
+		{args: []string{"-delimited"}, delimiter: ""},
+		{args: []string{"-delimiter", ""}, err: `invalid delimiter ""`},
+		{args: []string{"-delimiter", `\x`}, err: `invalid delimiter "\\x"`},
+	}
+
+	for _, c := range cases {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		var fr framing
+		fr.register(fs)
+		assert.Equal(t, nil, fs.Parse(c.args))
+
+		config, err := fr.config()
+		if c.err != "" {
+			assert.Equal(t, c.err, err.Error())
+			continue
+		}
+
+		assert.Equal(t, nil, err)
+		assert.Equal(t, c.delimiter, string(config.Delimiter))
+		assert.Equal(t, c.max, config.MaxWriteSize)
+	}
+}
+
+// Test rejecting invalid arguments.
+func TestArguments(t *testing.T) {
+	cases := []struct {
+		command func([]string) error
+		args    []string
+		err     string
+	}{
+		{replay, nil, "replay requires one of -to or -exec"},
+		{replay, []string{"-to", "/tmp", "-exec", "cat"}, "replay requires one of -to or -exec"},
+		{replay, []string{"-exec", " "}, "replay requires one of -to or -exec"},
+		{restore, nil, "restore requires -to"},
+		{verify, []string{"-delimiter", ""}, `invalid delimiter ""`},
+		{cat, []string{"-delimiter", ""}, `invalid delimiter ""`},
+		{ls, []string{"/tmp/diskbuffer-missing"}, "open /tmp/diskbuffer-missing: no such file or directory"},
+	}
+
+	for _, c := range cases {
+		_, err := capture(t, func() error { return c.command(c.args) })
+		assert.Equal(t, c.err, err.Error())
+	}
+}
+
+// Test the commands against files flushed by a buffer.
+func TestCommands(t *testing.T) {
+	dir := "/tmp/diskbuffer-cli"
+	os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "replayed"), 0755)
+	os.MkdirAll(filepath.Join(dir, "trash"), 0755)
+	defer os.RemoveAll(dir)
+
+	b, err := buffer.New(filepath.Join(dir, "buffer"), &buffer.Config{
+		Queue:       make(chan *buffer.Flush, 10),
+		FlushWrites: 100,
+		Delimiter:   []byte("\n"),
+		Checksum:    "sha256",
+		Sidecar:     true,
+		MetaFile:    true,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+	b.Write([]byte("world"))
+
+	f, err := b.CloseFile()
+	assert.Equal(t, nil, err)
+
+	out, err := capture(t, func() error { return ls([]string{dir}) })
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 2, strings.Count(out, "\n"))
+	assert.Equal(t, true, strings.Contains(out, f.Path))
+	assert.Equal(t, true, strings.Contains(out, "forced"))
+
+	out, err = capture(t, func() error { return verify([]string{f.Path}) })
+	assert.Equal(t, nil, err)
+	assert.Equal(t, f.Path+": ok, 2 records\n", out)
+
+	out, err = capture(t, func() error { return cat([]string{f.Path}) })
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "hello\nworld\n", out)
+
+	replayed := filepath.Join(dir, "replayed", filepath.Base(f.Path))
+	out, err = capture(t, func() error { return replay([]string{"-to", filepath.Join(dir, "replayed"), f.Path}) })
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "replayed "+f.Path+"\n", out)
+
+	data, err := ioutil.ReadFile(replayed)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "hello\nworld\n", string(data))
+
+	ioutil.WriteFile(f.Path, []byte("hello\nworlds\n"), 0644)
+	out, err = capture(t, func() error { return verify([]string{f.Path}) })
+	assert.Equal(t, "1 of 1 files failed verification", err.Error())
+	assert.Equal(t, true, strings.HasPrefix(out, f.Path+": sidecar: "))
+
+	trashed := filepath.Join(dir, "trash", "buffer.evicted")
+	ioutil.WriteFile(trashed, []byte("hello\n"), 0644)
+	ioutil.WriteFile(trashed+buffer.MetaFileSuffix, []byte("{}\n"), 0644)
+
+	out, err = capture(t, func() error { return restore([]string{"-to", dir, trashed}) })
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "restored "+filepath.Join(dir, "buffer.evicted")+"\n", out)
+
+	_, err = os.Stat(filepath.Join(dir, "buffer.evicted"+buffer.MetaFileSuffix))
+	assert.Equal(t, nil, err)
+}
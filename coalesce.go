@@ -0,0 +1,67 @@
+package buffer
+
+import (
+	"context"
+	"time"
+)
+
+// Coalesce delivers flushes received from `queue` in batches, collecting
+// those which arrive within `window` of the first, up to `max` per batch
+// when non-zero, so consumers can process rotation storms together. The
+// returned channel is closed when `queue` is closed, such as by Drain,
+// or `ctx` is done.
+func Coalesce(ctx context.Context, queue <-chan *Flush, window time.Duration, max int) <-chan []*Flush {
+	out := make(chan []*Flush)
+
+	go func() {
+		defer close(out)
+
+		for {
+			var batch []*Flush
+
+			select {
+			case <-ctx.Done():
+				return
+			case f, ok := <-queue:
+				if !ok {
+					return
+				}
+				batch = append(batch, f)
+			}
+
+			open := true
+			t := time.NewTimer(window)
+
+		collect:
+			for max == 0 || len(batch) < max {
+				select {
+				case <-ctx.Done():
+					t.Stop()
+					return
+				case <-t.C:
+					break collect
+				case f, ok := <-queue:
+					if !ok {
+						open = false
+						break collect
+					}
+					batch = append(batch, f)
+				}
+			}
+
+			t.Stop()
+
+			select {
+			case <-ctx.Done():
+				return
+			case out <- batch:
+			}
+
+			if !open {
+				return
+			}
+		}
+	}()
+
+	return out
+}
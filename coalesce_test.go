@@ -0,0 +1,36 @@
+package buffer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test coalescing flushes into batches.
+func TestBuffer_Coalesce(t *testing.T) {
+	queue := make(chan *Flush, 100)
+	for i := 0; i < 5; i++ {
+		queue <- &Flush{Seq: int64(i)}
+	}
+
+	batches := Coalesce(context.Background(), queue, 50*time.Millisecond, 3)
+
+	batch := <-batches
+	assert.Equal(t, 3, len(batch))
+	assert.Equal(t, int64(0), batch[0].Seq)
+
+	batch = <-batches
+	assert.Equal(t, 2, len(batch))
+	assert.Equal(t, int64(4), batch[1].Seq)
+
+	queue <- &Flush{Seq: 5}
+	close(queue)
+
+	batch = <-batches
+	assert.Equal(t, 1, len(batch))
+
+	_, ok := <-batches
+	assert.Equal(t, false, ok)
+}
@@ -0,0 +1,70 @@
+package buffer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+)
+
+// Gzip and zstd magic bytes.
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdHead  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// Compressing writer, which decides on the first write whether to
+// compress or pass through data which is already gzipped.
+type codecWriter struct {
+	w           io.Writer
+	codec       string
+	passthrough bool
+	started     bool
+	dict        []byte
+	z           io.WriteCloser
+}
+
+// Write implements io.Writer.
+func (c *codecWriter) Write(p []byte) (int, error) {
+	if !c.started {
+		c.started = true
+
+		if c.passthrough && bytes.HasPrefix(p, gzipMagic) {
+			c.codec = ""
+		}
+
+		switch c.codec {
+		case "gzip":
+			c.z = gzip.NewWriter(c.w)
+		case "zstd":
+			z, err := newZstdWriter(c.w, c.dict)
+			if err != nil {
+				return 0, err
+			}
+			c.z = z
+		}
+	}
+
+	if c.z != nil {
+		return c.z.Write(p)
+	}
+
+	return c.w.Write(p)
+}
+
+// Close the compressor without closing the underlying writer.
+func (c *codecWriter) Close() error {
+	if c.z != nil {
+		return c.z.Close()
+	}
+
+	return nil
+}
+
+// Codec applied to the data written, empty when passed through.
+func (c *codecWriter) Codec() string {
+	if c == nil || !c.started {
+		return ""
+	}
+
+	return c.codec
+}
@@ -0,0 +1,62 @@
+package buffer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Read a gzipped file.
+func gunzip(t *testing.T, path string) string {
+	f, err := os.Open(path)
+	assert.Equal(t, nil, err)
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	assert.Equal(t, nil, err)
+
+	b, err := ioutil.ReadAll(gz)
+	assert.Equal(t, nil, err)
+	return string(b)
+}
+
+// Test gzip compression and passthrough.
+func TestBuffer_Codec(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 2,
+		BufferSize:  1 << 10,
+		Codec:       "gzip",
+		Passthrough: true,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello "))
+	b.Write([]byte("world"))
+
+	flush := <-b.Queue
+	assert.Equal(t, "gzip", flush.Codec)
+	assert.Equal(t, "hello world", gunzip(t, flush.Path))
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte("compressed"))
+	gz.Close()
+
+	b.Write(buf.Bytes())
+	b.Write(buf.Bytes())
+
+	flush = <-b.Queue
+	assert.Equal(t, "", flush.Codec)
+	assert.Equal(t, int64(2*buf.Len()), flush.Bytes)
+	assert.Equal(t, "compressedcompressed", gunzip(t, flush.Path))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
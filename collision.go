@@ -0,0 +1,39 @@
+package buffer
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// CollisionError is reported when a file to be created already exists,
+// such as after a pid and id collision. The existing file is renamed
+// aside rather than truncated.
+type CollisionError struct {
+	Path  string
+	Aside string
+}
+
+// Error implements error.
+func (e *CollisionError) Error() string {
+	return fmt.Sprintf("%q already exists, preserved as %q", e.Path, e.Aside)
+}
+
+// Create a new file at `path`, renaming an existing file aside.
+func (b *Buffer) createNew(path string) (*os.File, error) {
+	f, err := b.openFile(path, os.O_EXCL)
+	if !os.IsExist(err) {
+		return f, err
+	}
+
+	aside := fmt.Sprintf("%s.%d.preserved", path, time.Now().UnixNano())
+	b.log(1, "%q exists, renaming to %q", path, aside)
+
+	err = os.Rename(path, aside)
+	if err != nil {
+		return nil, err
+	}
+
+	b.error(&CollisionError{Path: path, Aside: aside})
+	return b.openFile(path, os.O_EXCL)
+}
@@ -0,0 +1,48 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test preserving existing files at the path of a new file.
+func TestBuffer_Collision(t *testing.T) {
+	dir := "/tmp/buffer-collision"
+	os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "buffer.log.tmp")
+	err := os.MkdirAll(dir, 0755)
+	assert.Equal(t, nil, err)
+
+	err = ioutil.WriteFile(path, []byte("hello\n"), 0644)
+	assert.Equal(t, nil, err)
+
+	b, err := New(filepath.Join(dir, "buffer"), &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1000,
+		Filename:    "{{.Path}}.log",
+		Staging:     ".tmp",
+		Errors:      make(chan error, 10),
+	})
+
+	assert.Equal(t, nil, err)
+
+	e := (<-b.Errors).(*CollisionError)
+	assert.Equal(t, path, e.Path)
+
+	data, err := ioutil.ReadFile(e.Aside)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "hello\n", string(data))
+
+	b.Write([]byte("world\n"))
+	flush, err := b.FlushFile()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(6), flush.Bytes)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
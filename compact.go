@@ -0,0 +1,134 @@
+package buffer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Compacted is the reason of files merged by Compact.
+const Compacted Reason = "compacted"
+
+// Compact delivers flushes received from Config.Queue, merging files
+// smaller than `target` bytes into files of at least `target` bytes, so
+// quiet periods do not produce thousands of tiny files. Files are merged
+// whole, so records are never split, and only with files of the same key
+// and codec; encrypted files and files over the target pass through. A
+// partial merge is delivered `window` after its first file arrives when
+// non-zero, and when the queue is closed, such as by Drain. Merged files
+// are journaled and charged in place of the files they replace, which
+// are removed. The returned channel is closed when the queue is
+// closed or `ctx` is done, leaving files not yet merged on disk.
+//
+// Files must be concatenable, so buffers with trailers, headers, footers
+// or Parquet files cannot be compacted, nor files held in memory.
+func (b *Buffer) Compact(ctx context.Context, target int64, window time.Duration) (<-chan *Flush, error) {
+	switch {
+	case b.Trailer || b.Parquet != nil || b.CSVHeader != nil || b.Hooks.Header != nil || b.Hooks.Footer != nil:
+		return nil, fmt.Errorf("files with trailers, headers or footers cannot be compacted")
+	case b.MemoryBytes != 0:
+		return nil, fmt.Errorf("files held in memory cannot be compacted")
+	case target <= 0:
+		return nil, fmt.Errorf("compaction target must be positive")
+	}
+
+	g := &grouping{
+		name:  "compact",
+		merge: b.merge,
+		skip: func(f *Flush) bool {
+			return f.KeyID != "" || f.Bytes >= target
+		},
+		split: func(pending []*Flush, f *Flush) bool {
+			return f.Key != pending[0].Key || f.Codec != pending[0].Codec || f.Dict != pending[0].Dict
+		},
+		full: func(pending []*Flush) bool {
+			var size int64
+			for _, f := range pending {
+				size += f.Bytes
+			}
+			return size >= target
+		},
+	}
+
+	return b.group(ctx, window, g), nil
+}
+
+// Merge `files` into one, or return a single file unchanged.
+func (b *Buffer) merge(files []*Flush) (*Flush, error) {
+	if len(files) == 1 {
+		return files[0], nil
+	}
+
+	first := files[0]
+	ext := filepath.Ext(first.Path)
+	f := &Flush{
+		Version: FlushVersion,
+		Reason:  Compacted,
+		Path:    strings.TrimSuffix(first.Path, ext) + ".compacted" + ext,
+		Key:     first.Key,
+		Codec:   first.Codec,
+		Dict:    first.Dict,
+		Bucket:  first.Bucket,
+		Seq:     first.Seq,
+		Opened:  first.Opened,
+		First:   first.First,
+	}
+
+	b.log(1, "compacting %d files into %q", len(files), f.Path)
+
+	err := b.concat(f, files)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, in := range files {
+		f.Writes += in.Writes
+		f.Bytes += in.Bytes
+		f.Batches = append(f.Batches, in.Batches...)
+
+		for k, v := range in.Meta {
+			if f.Meta == nil {
+				f.Meta = make(map[string][]string)
+			}
+			f.Meta[k] = append(f.Meta[k], v...)
+		}
+
+		if f.First.IsZero() || (!in.First.IsZero() && in.First.Before(f.First)) {
+			f.First = in.First
+		}
+	}
+
+	f.Closed = time.Now()
+	f.Age = f.Closed.Sub(f.Opened)
+
+	return f, b.supersede(f, files)
+}
+
+// Concatenate `files` into the file of `f`, setting its checksum.
+func (b *Buffer) concat(f *Flush, files []*Flush) error {
+	return b.writeAtomic(f, func(w io.Writer) error {
+		for _, in := range files {
+			err := appendFile(w, in.Path)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Copy the file at `path` to `w`.
+func appendFile(w io.Writer, path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	_, err = io.Copy(w, src)
+	return err
+}
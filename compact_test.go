@@ -0,0 +1,135 @@
+package buffer
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test merging small files up to the target size.
+func TestBuffer_Compact(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1,
+	})
+
+	assert.Equal(t, nil, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out, err := b.Compact(ctx, 10, 50*time.Millisecond)
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("tobi\n"))
+	b.Write([]byte("loki\n"))
+	b.Write([]byte("a much longer record\n"))
+	b.Write([]byte("jane\n"))
+
+	f := <-out
+	assert.Equal(t, Compacted, f.Reason)
+	assert.Equal(t, int64(2), f.Writes)
+	assert.Equal(t, int64(10), f.Bytes)
+
+	data, err := ioutil.ReadFile(f.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "tobi\nloki\n", string(data))
+
+	f = <-out
+	assert.Equal(t, Writes, f.Reason)
+	assert.Equal(t, int64(21), f.Bytes)
+
+	start := time.Now()
+	f = <-out
+	assert.Equal(t, true, time.Since(start) < time.Second)
+	assert.Equal(t, Writes, f.Reason)
+	assert.Equal(t, int64(5), f.Bytes)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test merged files replace their inputs in the manifest.
+func TestBuffer_Compact_manifest(t *testing.T) {
+	os.Remove("/tmp/buffer.manifest")
+	defer os.Remove("/tmp/buffer.manifest")
+
+	c := &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1,
+		Manifest:    "/tmp/buffer.manifest",
+	}
+
+	b, err := New("/tmp/buffer", c)
+	assert.Equal(t, nil, err)
+
+	out, err := b.Compact(context.Background(), 10, 0)
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("tobi\n"))
+	b.Write([]byte("loki\n"))
+
+	f := <-out
+	assert.Equal(t, Compacted, f.Reason)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	pending, err := replay(c.Manifest)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, len(pending))
+	assert.Equal(t, f.Path, pending[0].Path)
+
+	b, err = New("/tmp/buffer", &Config{FlushWrites: 1, Trailer: true})
+	assert.Equal(t, nil, err)
+
+	_, err = b.Compact(context.Background(), 10, 0)
+	assert.NotEqual(t, nil, err)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test files are delivered unmerged when merging fails.
+func TestBuffer_Compact_error(t *testing.T) {
+	var errs []error
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1,
+		Hooks: Hooks{
+			OnFlush: func(f *Flush) {
+				if f.Seq == 1 {
+					os.Remove(f.Path)
+				}
+			},
+			OnError: func(err error) { errs = append(errs, err) },
+		},
+	})
+
+	assert.Equal(t, nil, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out, err := b.Compact(ctx, 100, 50*time.Millisecond)
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("tobi\n"))
+	b.Write([]byte("loki\n"))
+
+	f := <-out
+	assert.Equal(t, Writes, f.Reason)
+	assert.Equal(t, int64(1), f.Seq)
+
+	f = <-out
+	assert.Equal(t, Writes, f.Reason)
+	assert.Equal(t, int64(2), f.Seq)
+	assert.Equal(t, 1, len(errs))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
@@ -0,0 +1,359 @@
+package buffer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Converter converts flushed files to a downstream format.
+type Converter struct {
+	Ext     string                               // Extension appended to converted files
+	Codec   string                               // Codec of converted files, if compressed
+	Convert func(w io.Writer, r io.Reader) error // Convert the contents of r to w
+}
+
+// Registered converters.
+var converters = struct {
+	sync.RWMutex
+	m map[string]Converter
+}{
+	m: map[string]Converter{
+		"gzip":           {Ext: ".gz", Codec: "gzip", Convert: gzipConvert},
+		"csv-ndjson":     {Ext: ".ndjson", Convert: csvConvert},
+		"csv-parquet":    {Ext: ".parquet", Convert: csvParquetConvert},
+		"ndjson-parquet": {Ext: ".parquet", Convert: ndjsonParquetConvert},
+	},
+}
+
+// Register converter `c` as `name`, replacing any existing converter.
+func Register(name string, c Converter) {
+	converters.Lock()
+	defer converters.Unlock()
+	converters.m[name] = c
+}
+
+// Convert the file of `f` using the converter registered as `name`,
+// returning a copy of `f` with the path and size of the converted file.
+// Files are decompressed before conversion, as with Open, and encrypted
+// files require Buffer.Convert. The output is written to a temporary file and
+// renamed into place, and the original file is left for other
+// destinations. Files held in memory are converted in memory, into the
+// Data of the copy.
+func Convert(name string, f *Flush) (*Flush, error) {
+	return convert(name, f, nil, 0666)
+}
+
+// Convert converts like the package Convert, decrypting files with
+// Config.KeyProvider and creating converted files with Config.FileMode.
+func (b *Buffer) Convert(name string, f *Flush) (*Flush, error) {
+	mode := b.FileMode
+	if mode == 0 {
+		mode = 0666
+	}
+
+	return convert(name, f, b.KeyProvider, mode)
+}
+
+// Convert `f` with converter `name`, looking up keys with `p` and
+// creating the converted file with `mode`.
+func convert(name string, f *Flush, p KeyProvider, mode os.FileMode) (*Flush, error) {
+	converters.RLock()
+	c, ok := converters.m[name]
+	converters.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown converter %q", name)
+	}
+
+	path := f.Path + c.Ext
+	converted := *f
+	converted.Path = path
+	converted.Codec = c.Codec
+	converted.KeyID = ""
+	converted.Dict = ""
+	converted.Hash = ""
+	converted.Checksum = ""
+
+	src, err := OpenFlush(f, p)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	if f.Data != nil {
+		var buf bytes.Buffer
+		err := c.Convert(&buf, src)
+		if err != nil {
+			return nil, err
+		}
+
+		converted.Data = buf.Bytes()
+		converted.Bytes = int64(buf.Len())
+		return &converted, nil
+	}
+
+	dst, err := os.OpenFile(path+".tmp", os.O_RDWR|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.Convert(dst, src)
+	if err != nil {
+		dst.Close()
+		os.Remove(dst.Name())
+		return nil, err
+	}
+
+	err = dst.Close()
+	if err != nil {
+		os.Remove(dst.Name())
+		return nil, err
+	}
+
+	err = os.Rename(dst.Name(), path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	converted.Bytes = info.Size()
+	return &converted, nil
+}
+
+// Gzip converter.
+func gzipConvert(w io.Writer, r io.Reader) error {
+	gz := gzip.NewWriter(w)
+
+	_, err := io.Copy(gz, r)
+	if err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
+
+// CSV to NDJSON converter, using the first row as field names.
+func csvConvert(w io.Writer, r io.Reader) error {
+	cr := csv.NewReader(r)
+	enc := json.NewEncoder(w)
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		record := make(map[string]string, len(header))
+		for i, field := range header {
+			if i < len(row) {
+				record[field] = row[i]
+			}
+		}
+
+		err = enc.Encode(record)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// CSV to Parquet converter, using the first row as the names of string
+// columns.
+func csvParquetConvert(w io.Writer, r io.Reader) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return fmt.Errorf("csv has no header row")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	s := &ParquetSchema{}
+	for _, name := range header {
+		s.columns = append(s.columns, parquetColumn{name: name, kind: parquetByteArray, converted: parquetUTF8})
+	}
+
+	g := newRowGroup(s)
+	values := make([]reflect.Value, len(header))
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		for i := range values {
+			var field string
+			if i < len(row) {
+				field = row[i]
+			}
+			values[i] = reflect.ValueOf(field)
+		}
+
+		g.put(values)
+	}
+
+	_, err = g.encode(w)
+	return err
+}
+
+// NDJSON to Parquet converter, with a column per key of the records,
+// typed as booleans, integers or doubles when all their values are, or
+// otherwise strings, holding other values as JSON. Missing keys and
+// nulls are written as zero values.
+func ndjsonParquetConvert(w io.Writer, r io.Reader) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	var records []map[string]interface{}
+	kinds := make(map[string]int32)
+	for {
+		var record map[string]interface{}
+		err := dec.Decode(&record)
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		for k, v := range record {
+			kind, seen := kinds[k]
+			switch {
+			case v != nil:
+				kinds[k] = inferKind(v, kind, seen)
+			case !seen:
+				kinds[k] = -1
+			}
+		}
+
+		records = append(records, record)
+	}
+
+	if len(kinds) == 0 {
+		return fmt.Errorf("ndjson has no fields")
+	}
+
+	names := make([]string, 0, len(kinds))
+	for k := range kinds {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	s := &ParquetSchema{}
+	for _, name := range names {
+		c := parquetColumn{name: name, kind: kinds[name], converted: -1}
+		if c.kind < 0 || c.kind == parquetByteArray {
+			c.kind = parquetByteArray
+			c.converted = parquetUTF8
+		}
+		s.columns = append(s.columns, c)
+	}
+
+	g := newRowGroup(s)
+	values := make([]reflect.Value, len(names))
+	for _, record := range records {
+		for i, c := range s.columns {
+			v, err := columnValue(record[c.name], c.kind)
+			if err != nil {
+				return err
+			}
+			values[i] = v
+		}
+
+		g.put(values)
+	}
+
+	_, err := g.encode(w)
+	return err
+}
+
+// Parquet kind of the JSON values of a column given the non-null value
+// `v` and the kind of those seen before, negative for only nulls.
+func inferKind(v interface{}, kind int32, seen bool) int32 {
+	var k int32
+	switch n := v.(type) {
+	case bool:
+		k = parquetBoolean
+	case json.Number:
+		k = parquetInt64
+		if _, err := n.Int64(); err != nil {
+			k = parquetDouble
+		}
+	default:
+		k = parquetByteArray
+	}
+
+	switch {
+	case !seen || kind < 0 || k == kind:
+		return k
+	case kind == parquetByteArray || k == parquetByteArray:
+		return parquetByteArray
+	case kind == parquetBoolean || k == parquetBoolean:
+		return parquetByteArray
+	default:
+		return parquetDouble
+	}
+}
+
+// Value of JSON value `v` in a column of `kind`.
+func columnValue(v interface{}, kind int32) (reflect.Value, error) {
+	switch kind {
+	case parquetBoolean:
+		b, _ := v.(bool)
+		return reflect.ValueOf(b), nil
+	case parquetInt64:
+		n, _ := v.(json.Number)
+		i, _ := n.Int64()
+		return reflect.ValueOf(i), nil
+	case parquetDouble:
+		n, _ := v.(json.Number)
+		f, _ := n.Float64()
+		return reflect.ValueOf(f), nil
+	}
+
+	switch s := v.(type) {
+	case nil:
+		return reflect.ValueOf(""), nil
+	case string:
+		return reflect.ValueOf(s), nil
+	case json.Number:
+		return reflect.ValueOf(s.String()), nil
+	default:
+		data, err := json.Marshal(s)
+		return reflect.ValueOf(string(data)), err
+	}
+}
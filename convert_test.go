@@ -0,0 +1,150 @@
+package buffer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test gzip conversion.
+func TestConvert_Gzip(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello world"))
+	flush, err := Convert("gzip", <-b.Queue)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, ".gz", flush.Path[len(flush.Path)-3:])
+
+	info, err := os.Stat(flush.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, info.Size(), flush.Bytes)
+
+	f, err := os.Open(flush.Path)
+	assert.Equal(t, nil, err)
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	assert.Equal(t, nil, err)
+
+	data, err := ioutil.ReadAll(gz)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "hello world", string(data))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test CSV to NDJSON conversion.
+func TestConvert_CSV(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 3,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("name,species\n"))
+	b.Write([]byte("tobi,ferret\n"))
+	b.Write([]byte("loki,ferret\n"))
+
+	flush, err := Convert("csv-ndjson", <-b.Queue)
+	assert.Equal(t, nil, err)
+
+	data, err := ioutil.ReadFile(flush.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "{\"name\":\"tobi\",\"species\":\"ferret\"}\n{\"name\":\"loki\",\"species\":\"ferret\"}\n", string(data))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test unknown converters.
+func TestConvert_Unknown(t *testing.T) {
+	_, err := Convert("parquet", &Flush{})
+	assert.Equal(t, `unknown converter "parquet"`, err.Error())
+}
+
+// Test NDJSON to Parquet conversion.
+func TestConvert_NDJSONParquet(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 2,
+		Delimiter:   []byte("\n"),
+		Codec:       "gzip",
+		FileMode:    0600,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte(`{"name":"tobi","count":1,"ok":true,"tags":["a"]}`))
+	b.Write([]byte(`{"name":"loki","count":2,"ok":null}`))
+
+	flush, err := b.Convert("ndjson-parquet", <-b.Queue)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "", flush.Codec)
+
+	info, err := os.Stat(flush.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	data, err := ioutil.ReadFile(flush.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "PAR1", string(data[:4]))
+
+	size := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	meta := thrift{bytes.NewReader(data[len(data)-8-size : len(data)-8])}.structure()
+	assert.Equal(t, int64(2), meta[3])
+
+	var names []string
+	var kinds []int64
+	for _, s := range meta[2].([]interface{})[1:] {
+		names = append(names, s.(map[int16]interface{})[4].(string))
+		kinds = append(kinds, s.(map[int16]interface{})[1].(int64))
+	}
+	assert.Equal(t, []string{"count", "name", "ok", "tags"}, names)
+	assert.Equal(t, []int64{parquetInt64, parquetByteArray, parquetBoolean, parquetByteArray}, kinds)
+	assert.Equal(t, true, bytes.Contains(data, []byte("\x05\x00\x00\x00[\"a\"]\x00\x00\x00\x00")))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test CSV to Parquet conversion.
+func TestConvert_CSVParquet(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 3,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("name,species\n"))
+	b.Write([]byte("tobi,ferret\n"))
+	b.Write([]byte("loki,ferret\n"))
+
+	flush, err := Convert("csv-parquet", <-b.Queue)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, ".parquet", filepath.Ext(flush.Path))
+
+	data, err := ioutil.ReadFile(flush.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "PAR1", string(data[len(data)-4:]))
+	assert.Equal(t, true, bytes.Contains(data, []byte("\x04\x00\x00\x00tobi\x04\x00\x00\x00loki")))
+	assert.Equal(t, true, bytes.Contains(data, []byte("\x06\x00\x00\x00ferret\x06\x00\x00\x00ferret")))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
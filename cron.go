@@ -0,0 +1,183 @@
+package buffer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule of flush times, see Config.FlushSchedule.
+type Schedule interface {
+	// Next returns the first flush time after `t`.
+	Next(t time.Time) time.Time
+}
+
+// Cron schedule of minutes, hours, days of the month, months and days
+// of the week, as bit sets.
+type cron struct {
+	minute, hour, dom, month, dow uint64
+	anyDom, anyDow                bool
+}
+
+// Cron field bounds.
+var cronFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+// ParseCron parses a standard five field cron expression, such as
+// "0 * * * *" for the top of every hour. Fields support "*", lists,
+// ranges and steps, matched in the local time zone.
+func ParseCron(expr string) (Schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression %q must have %d fields", expr, len(cronFields))
+	}
+
+	var sets [5]uint64
+	for i, f := range fields {
+		set, err := parseCronField(f, cronFields[i].min, cronFields[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s in cron expression %q: %s", cronFields[i].name, expr, err)
+		}
+		sets[i] = set
+	}
+
+	return &cron{
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		anyDom: fields[2] == "*",
+		anyDow: fields[4] == "*",
+	}, nil
+}
+
+// Parse a cron field into a bit set of values.
+func parseCronField(field string, min, max int) (uint64, error) {
+	var set uint64
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i != -1 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q", part[i+1:])
+			}
+			step = n
+			part = part[:i]
+		}
+
+		lo, hi := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			lo, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", bounds[0])
+			}
+			hi, err = strconv.Atoi(bounds[1])
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", bounds[1])
+			}
+		default:
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			lo = n
+			if step == 1 {
+				hi = n
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%d-%d out of range %d-%d", lo, hi, min, max)
+		}
+
+		for n := lo; n <= hi; n += step {
+			set |= 1 << uint(n)
+		}
+	}
+
+	return set, nil
+}
+
+// Whether `n` is in `set`.
+func has(set uint64, n int) bool {
+	return set&(1<<uint(n)) != 0
+}
+
+// Whether the day of `t` matches, either field matching when both are
+// restricted as in standard cron.
+func (c *cron) day(t time.Time) bool {
+	dom, dow := has(c.dom, t.Day()), has(c.dow, int(t.Weekday()))
+	switch {
+	case c.anyDom && c.anyDow:
+		return true
+	case c.anyDom:
+		return dow
+	case c.anyDow:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+// Next implements Schedule.
+func (c *cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	end := t.AddDate(5, 0, 0)
+
+	for t.Before(end) {
+		switch {
+		case !has(c.month, int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.day(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !has(c.hour, t.Hour()):
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case !has(c.minute, t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// Schedule a flush at the next time of FlushSchedule.
+func (b *Buffer) plan() {
+	now := time.Now()
+	next := b.FlushSchedule.Next(now)
+	if next.IsZero() {
+		b.log(1, "flush schedule has no next time")
+		return
+	}
+
+	b.log(2, "next scheduled flush at %s", next)
+	b.cron = time.AfterFunc(next.Sub(now), func() {
+		b.label("schedule")
+
+		b.Lock()
+		defer b.Unlock()
+
+		if b.cron == nil {
+			return
+		}
+
+		b.error(b.attempt(func() error { return b.flush(Scheduled) }))
+		b.plan()
+	})
+}
@@ -0,0 +1,75 @@
+package buffer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test cron schedules.
+func TestBuffer_ParseCron(t *testing.T) {
+	start := time.Date(2024, 1, 31, 10, 30, 15, 0, time.UTC)
+
+	cases := []struct {
+		expr string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2024, 1, 31, 10, 31, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2024, 1, 31, 11, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 1, 31, 10, 45, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2024, 2, 1, 2, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 9-17 * * 1-5", time.Date(2024, 1, 31, 11, 0, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2024, 2, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"5,35 10 * * *", time.Date(2024, 1, 31, 10, 35, 0, 0, time.UTC)},
+	}
+
+	for _, c := range cases {
+		s, err := ParseCron(c.expr)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, c.next, s.Next(start), c.expr)
+	}
+
+	_, err := ParseCron("* * *")
+	assert.Equal(t, `cron expression "* * *" must have 5 fields`, err.Error())
+
+	_, err = ParseCron("60 * * * *")
+	assert.Equal(t, `invalid minute in cron expression "60 * * * *": 60-60 out of range 0-59`, err.Error())
+
+	s, err := ParseCron("0 0 30 2 *")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, s.Next(start).IsZero())
+}
+
+// Schedule every interval, aligned to it.
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Truncate(time.Duration(e)).Add(time.Duration(e))
+}
+
+// Test flushing on a schedule.
+func TestBuffer_Write_FlushOnSchedule(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:         make(chan *Flush, 100),
+		FlushSchedule: every(100 * time.Millisecond),
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello\n"))
+
+	flush := <-b.Queue
+	assert.Equal(t, Scheduled, flush.Reason)
+
+	b.Write([]byte("world\n"))
+
+	flush = <-b.Queue
+	assert.Equal(t, Scheduled, flush.Reason)
+	assert.Equal(t, int64(1), flush.Writes)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
@@ -0,0 +1,57 @@
+package buffer
+
+import (
+	"bytes"
+	"encoding/csv"
+)
+
+// Encode `record` as a CSV row, terminated by Config.Delimiter when set
+// rather than a newline.
+func (b *Buffer) encodeCSV(buf *bytes.Buffer, record []string) ([]byte, error) {
+	w := csv.NewWriter(buf)
+	err := w.Write(record)
+	if err != nil {
+		return nil, err
+	}
+
+	w.Flush()
+	err = w.Error()
+	if err != nil {
+		return nil, err
+	}
+
+	data := buf.Bytes()
+	if len(b.Delimiter) != 0 {
+		data = data[:len(data)-1]
+	}
+
+	return data, nil
+}
+
+// WriteCSV writes `record` as a CSV row, quoted as with csv.Writer and
+// followed by a newline unless Config.Delimiter is set.
+func (b *Buffer) WriteCSV(record []string) error {
+	buf := encodings.Get().(*bytes.Buffer)
+	defer encodings.Put(buf)
+	buf.Reset()
+
+	data, err := b.encodeCSV(buf, record)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.Write(data)
+	return err
+}
+
+// Write Config.CSVHeader at the top of the current file. The header
+// counts towards the bytes of the file but not its writes.
+func (b *Buffer) header() error {
+	var buf bytes.Buffer
+	data, err := b.encodeCSV(&buf, b.CSVHeader)
+	if err != nil {
+		return err
+	}
+
+	return b.frame(append(data, b.Delimiter...))
+}
@@ -0,0 +1,44 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test writing a CSV header at the top of each file.
+func TestBuffer_WriteCSV(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 2,
+		CSVHeader:   []string{"name", "note"},
+	})
+
+	assert.Equal(t, nil, err)
+
+	err = b.WriteCSV([]string{"tobi", "ferret"})
+	assert.Equal(t, nil, err)
+
+	err = b.WriteCSV([]string{"loki", "says \"hi\", twice"})
+	assert.Equal(t, nil, err)
+
+	err = b.WriteCSV([]string{"jane", ""})
+	assert.Equal(t, nil, err)
+
+	flush := <-b.Queue
+	assert.Equal(t, int64(2), flush.Writes)
+	assert.Equal(t, int64(48), flush.Bytes)
+
+	data, err := ioutil.ReadFile(flush.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "name,note\ntobi,ferret\nloki,\"says \"\"hi\"\", twice\"\n", string(data))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	flush = <-b.Queue
+	data, err = ioutil.ReadFile(flush.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "name,note\njane,\n", string(data))
+}
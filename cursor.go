@@ -0,0 +1,125 @@
+package buffer
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Cursor records a consumer's progress through flushed files, persisted
+// atomically so shippers can resume after a crash.
+type Cursor struct {
+	mu   sync.Mutex
+	path string
+
+	File     string    `json:"file"`     // File being or last processed
+	Offset   int64     `json:"offset"`   // Offset processed within File
+	Complete bool      `json:"complete"` // File was fully processed
+	Updated  time.Time `json:"updated"`  // Time of the last save
+}
+
+// OpenCursor loads the cursor persisted at `path`, or
+// returns an empty cursor when it does not exist.
+func OpenCursor(path string) (*Cursor, error) {
+	c := &Cursor{path: path}
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(b, c)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Cursor opens the cursor `name` in the spool directory of the buffer.
+func (b *Buffer) Cursor(name string) (*Cursor, error) {
+	dir := b.SpoolDir
+	if dir == "" {
+		dir = filepath.Dir(b.path)
+	}
+
+	return OpenCursor(filepath.Join(dir, name+".cursor"))
+}
+
+// Save progress of `offset` bytes processed within `file`.
+func (c *Cursor) Save(file string, offset int64) error {
+	return c.save(file, offset, false)
+}
+
+// Done marks `file` as fully processed.
+func (c *Cursor) Done(file string) error {
+	return c.save(file, 0, true)
+}
+
+// Resume returns the files remaining from `files`, given in processing
+// order, and the offset to resume from within the first. When the
+// cursor's file is not present all files are returned.
+func (c *Cursor) Resume(files []string) ([]string, int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, file := range files {
+		if file != c.File {
+			continue
+		}
+
+		if c.Complete {
+			return files[i+1:], 0
+		}
+
+		return files[i:], c.Offset
+	}
+
+	return files, 0
+}
+
+// Save the cursor atomically by writing a temporary file and renaming it.
+func (c *Cursor) save(file string, offset int64, complete bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.File = file
+	c.Offset = offset
+	c.Complete = complete
+	c.Updated = time.Now()
+
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	tmp := c.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(b)
+	if err == nil {
+		err = f.Sync()
+	}
+
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, c.path)
+}
@@ -0,0 +1,45 @@
+package buffer
+
+import (
+	"os"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test cursor persistence and resumption.
+func TestCursor(t *testing.T) {
+	os.Remove("/tmp/buffer-test.cursor")
+
+	b, err := New("/tmp/buffer", config)
+	assert.Equal(t, nil, err)
+
+	c, err := b.Cursor("buffer-test")
+	assert.Equal(t, nil, err)
+
+	files := []string{"a", "b", "c"}
+
+	rest, offset := c.Resume(files)
+	assert.Equal(t, files, rest)
+	assert.Equal(t, int64(0), offset)
+
+	err = c.Save("b", 10)
+	assert.Equal(t, nil, err)
+
+	c, err = OpenCursor("/tmp/buffer-test.cursor")
+	assert.Equal(t, nil, err)
+
+	rest, offset = c.Resume(files)
+	assert.Equal(t, []string{"b", "c"}, rest)
+	assert.Equal(t, int64(10), offset)
+
+	err = c.Done("b")
+	assert.Equal(t, nil, err)
+
+	rest, offset = c.Resume(files)
+	assert.Equal(t, []string{"c"}, rest)
+	assert.Equal(t, int64(0), offset)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
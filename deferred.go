@@ -0,0 +1,61 @@
+package buffer
+
+import (
+	"io"
+	"os"
+)
+
+// Encode and ship a copy of the file of `f` in the background, so the
+// writes of a burst are not slowed by compression or hashing. The copy
+// is kept until the next, for CloseFile to return once encoded.
+func (b *Buffer) postpone(f *Flush) {
+	g := *f
+	b.encoded = &g
+	b.encodings.Add(1)
+	go func() {
+		defer b.encodings.Done()
+		b.label("encode")
+
+		err := b.encode(&g)
+		if err != nil {
+			b.error(err)
+		}
+
+		b.error(b.ship(&g))
+	}()
+}
+
+// Compress and checksum the file of `f`, written raw under Deferred. The
+// raw file is left in place when this fails.
+func (b *Buffer) encode(f *Flush) error {
+	if b.Codec == "" && b.Checksum == "" {
+		return nil
+	}
+
+	src, err := os.Open(f.Path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	b.log(2, "encoding %q", f.Path)
+	var codec string
+	err = b.writeAtomic(f, func(w io.Writer) error {
+		c := &codecWriter{w: w, codec: b.Codec, passthrough: b.Passthrough}
+		_, err := io.Copy(c, src)
+		if err == nil {
+			err = c.Close()
+		}
+
+		codec = c.Codec()
+		return err
+	})
+
+	if err != nil {
+		f.Hash, f.Checksum = "", ""
+		return err
+	}
+
+	f.Codec = codec
+	return nil
+}
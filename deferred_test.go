@@ -0,0 +1,107 @@
+package buffer
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test compressing and hashing files after they are flushed.
+func TestBuffer_Deferred(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 10),
+		FlushWrites: 2,
+		Segments:    2,
+		Deferred:    true,
+		Codec:       "gzip",
+		Checksum:    "sha256",
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+	b.Write([]byte("world"))
+
+	f := <-b.Queue
+	assert.Equal(t, "gzip", f.Codec)
+	assert.Equal(t, int64(10), f.Bytes)
+
+	data, err := ioutil.ReadFile(f.Path)
+	assert.Equal(t, nil, err)
+
+	sum := sha256.Sum256(data)
+	assert.Equal(t, hex.EncodeToString(sum[:]), f.Checksum)
+
+	file, err := os.Open(f.Path)
+	assert.Equal(t, nil, err)
+	defer file.Close()
+
+	r, err := gzip.NewReader(file)
+	assert.Equal(t, nil, err)
+
+	plain, err := ioutil.ReadAll(r)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "helloworld", string(plain))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test closing returns the encoded file.
+func TestBuffer_Deferred_CloseFile(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 10),
+		FlushWrites: 100,
+		Deferred:    true,
+		Codec:       "gzip",
+		Checksum:    "sha256",
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+
+	f, err := b.CloseFile()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "gzip", f.Codec)
+	assert.Equal(t, "sha256", f.Hash)
+	assert.Equal(t, true, f == <-b.Queue)
+
+	data, err := ioutil.ReadFile(f.Path)
+	assert.Equal(t, nil, err)
+
+	sum := sha256.Sum256(data)
+	assert.Equal(t, hex.EncodeToString(sum[:]), f.Checksum)
+}
+
+// Test segments are named when they are taken from the pool.
+func TestBuffer_Segments_spill(t *testing.T) {
+	os.RemoveAll("/tmp/buffer-segments")
+
+	b, err := New("/tmp/buffer-segments/primary/buffer", &Config{
+		Queue:       make(chan *Flush, 10),
+		FlushWrites: 1,
+		Segments:    2,
+		Quota:       NewQuotaManager(1),
+		SpillDir:    "/tmp/buffer-segments/spill",
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+	b.Write([]byte("world"))
+
+	<-b.Queue
+	f := <-b.Queue
+	assert.Equal(t, true, f.Spilled)
+	assert.Equal(t, "/tmp/buffer-segments/spill", filepath.Dir(f.Path))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
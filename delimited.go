@@ -0,0 +1,100 @@
+package buffer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Message is implemented by protobuf messages, such as those generated
+// with gogo/protobuf or wrapped with proto.Marshal.
+type Message interface {
+	Marshal() ([]byte, error)
+}
+
+// WriteDelimited writes `data` prefixed with its varint length, the
+// standard protobuf length-delimited framing, as one record.
+func (b *Buffer) WriteDelimited(data []byte) (int, error) {
+	if len(b.Delimiter) != 0 {
+		return 0, fmt.Errorf("length-delimited records cannot be combined with a delimiter")
+	}
+
+	record := make([]byte, binary.MaxVarintLen64+len(data))
+	n := binary.PutUvarint(record, uint64(len(data)))
+	n += copy(record[n:], data)
+
+	_, err := b.Write(record[:n])
+	if err != nil {
+		return 0, err
+	}
+
+	return len(data), nil
+}
+
+// WriteMessage marshals and writes `m` with WriteDelimited.
+func (b *Buffer) WriteMessage(m Message) error {
+	data, err := m.Marshal()
+	if err != nil {
+		return err
+	}
+
+	_, err = b.WriteDelimited(data)
+	return err
+}
+
+// DelimitedReader reads length-delimited records from flushed files.
+type DelimitedReader struct {
+	r   *bufio.Reader
+	max int
+	buf []byte
+}
+
+// NewDelimitedReader returns a reader of length-delimited records from
+// `r`, rejecting records over `max` bytes when non-zero.
+func NewDelimitedReader(r io.Reader, max int) *DelimitedReader {
+	return &DelimitedReader{r: bufio.NewReader(r), max: max}
+}
+
+// Next returns the next record, valid until the following call, or
+// io.EOF after the last. A truncated record returns io.ErrUnexpectedEOF.
+func (d *DelimitedReader) Next() ([]byte, error) {
+	size, err := binary.ReadUvarint(d.r)
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if d.max != 0 && size > uint64(d.max) {
+		return nil, fmt.Errorf("record of %d bytes exceeds %d", size, d.max)
+	}
+
+	if uint64(cap(d.buf)) < size {
+		d.buf = make([]byte, size)
+	}
+
+	d.buf = d.buf[:size]
+	_, err = io.ReadFull(d.r, d.buf)
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return d.buf, nil
+}
+
+// ReadMessage reads the next record into `m`.
+func (d *DelimitedReader) ReadMessage(m interface{ Unmarshal([]byte) error }) error {
+	data, err := d.Next()
+	if err != nil {
+		return err
+	}
+
+	return m.Unmarshal(data)
+}
@@ -0,0 +1,78 @@
+package buffer
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Message stub marshaling its text.
+type text struct {
+	s string
+}
+
+func (t *text) Marshal() ([]byte, error) { return []byte(t.s), nil }
+
+func (t *text) Unmarshal(data []byte) error {
+	t.s = string(data)
+	return nil
+}
+
+// Test length-delimited records.
+func TestBuffer_WriteDelimited(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 3,
+	})
+
+	assert.Equal(t, nil, err)
+
+	long := strings.Repeat("x", 300)
+
+	n, err := b.WriteDelimited([]byte("hello"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 5, n)
+
+	err = b.WriteMessage(&text{long})
+	assert.Equal(t, nil, err)
+
+	_, err = b.WriteDelimited(nil)
+	assert.Equal(t, nil, err)
+
+	flush := <-b.Queue
+	assert.Equal(t, int64(1+5+2+300+1), flush.Bytes)
+
+	f, err := os.Open(flush.Path)
+	assert.Equal(t, nil, err)
+	defer f.Close()
+
+	r := NewDelimitedReader(f, 0)
+
+	record, err := r.Next()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "hello", string(record))
+
+	var m text
+	err = r.ReadMessage(&m)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, long, m.s)
+
+	record, err = r.Next()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, len(record))
+
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+
+	_, err = NewDelimitedReader(strings.NewReader("\x05hel"), 0).Next()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	_, err = NewDelimitedReader(strings.NewReader("\x05hello"), 4).Next()
+	assert.Equal(t, "record of 5 bytes exceeds 4", err.Error())
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
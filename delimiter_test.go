@@ -0,0 +1,75 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test appending a delimiter to each write.
+func TestBuffer_Delimiter(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 5,
+		Delimiter:   []byte("\n"),
+		Paranoid:    true,
+	})
+
+	assert.Equal(t, nil, err)
+
+	n, err := b.Write([]byte(`{"a":1}`))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 7, n)
+
+	b.WriteString(`{"b":2}`)
+
+	n, err = b.WriteBatch([][]byte{[]byte(`{"c":3}`), []byte(`{"d":4}`), []byte(`{"e":5}`)})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 21, n)
+
+	flush := <-b.Queue
+	assert.Equal(t, int64(5), flush.Writes)
+	assert.Equal(t, int64(40), flush.Bytes)
+
+	data, err := ioutil.ReadFile(flush.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "{\"a\":1}\n{\"b\":2}\n{\"c\":3}\n{\"d\":4}\n{\"e\":5}\n", string(data))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	b, err = New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 2,
+		Delimiter:   []byte("\n"),
+	})
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, b.vectored())
+
+	n, err = b.WriteBatch([][]byte{[]byte("hello"), []byte("world")})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 10, n)
+
+	flush = <-b.Queue
+	data, err = ioutil.ReadFile(flush.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "hello\nworld\n", string(data))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test counting record bytes of partial vectored writes.
+func TestBuffer_written(t *testing.T) {
+	records := [][]byte{[]byte("abc"), []byte("de")}
+	delim := []byte("\n")
+
+	assert.Equal(t, 0, written(records, delim, 0))
+	assert.Equal(t, 2, written(records, delim, 2))
+	assert.Equal(t, 3, written(records, delim, 3))
+	assert.Equal(t, 3, written(records, delim, 4))
+	assert.Equal(t, 4, written(records, delim, 5))
+	assert.Equal(t, 5, written(records, delim, 7))
+}
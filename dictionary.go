@@ -0,0 +1,175 @@
+package buffer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/adler32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Maximum size of the content of a trained dictionary.
+const dictionarySize = 32 << 10
+
+// DictionarySuffix is appended to the name of dictionaries persisted
+// next to flushed files, named "{base}.{id}.dict".
+const DictionarySuffix = ".dict"
+
+// Zstd dictionary shared by a buffer and its partitions, trained on the
+// records of the first file.
+type dictionary struct {
+	sync.Mutex
+	data    []byte
+	id      uint32
+	samples []byte
+}
+
+// Current dictionary and its id, nil until trained.
+func (d *dictionary) current() ([]byte, uint32) {
+	d.Lock()
+	defer d.Unlock()
+	return d.data, d.id
+}
+
+// Sample record `data` for training.
+func (d *dictionary) sample(data []byte) {
+	d.Lock()
+	defer d.Unlock()
+
+	if d.data != nil || len(d.samples) >= dictionarySize {
+		return
+	}
+
+	n := dictionarySize - len(d.samples)
+	if n > len(data) {
+		n = len(data)
+	}
+
+	d.samples = append(d.samples, data[:n]...)
+}
+
+// Directory dictionaries are persisted in, that of flushed files so
+// readers find them with Decompress.
+func (b *Buffer) dictionaryDir() string {
+	if b.OutDir != "" {
+		return b.OutDir
+	}
+
+	if b.SpoolDir != "" {
+		return b.SpoolDir
+	}
+
+	return filepath.Dir(b.path)
+}
+
+// Load the newest persisted dictionary of the buffer, if any.
+func (b *Buffer) loadDictionary() error {
+	pattern := filepath.Join(b.dictionaryDir(), filepath.Base(b.path)+".*"+DictionarySuffix)
+	paths, err := filepath.Glob(pattern)
+	if err != nil || len(paths) == 0 {
+		return err
+	}
+
+	var newest string
+	var mod int64
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err == nil && info.ModTime().UnixNano() >= mod {
+			newest, mod = p, info.ModTime().UnixNano()
+		}
+	}
+
+	data, err := ioutil.ReadFile(newest)
+	if err != nil {
+		return err
+	}
+
+	d, err := parseZstdDict(data)
+	if err != nil {
+		return err
+	}
+
+	if d.id == 0 {
+		return fmt.Errorf("dictionary %q is not a zstd dictionary", newest)
+	}
+
+	b.log(1, "loaded dictionary %q", newest)
+	b.dictionary.data = data
+	b.dictionary.id = d.id
+	return nil
+}
+
+// Train the dictionary from sampled records once, persisting it.
+func (b *Buffer) train() error {
+	d := b.dictionary
+	d.Lock()
+	defer d.Unlock()
+
+	if d.data != nil || len(d.samples) < 8 {
+		return nil
+	}
+
+	// ids below 1<<15 and from 1<<31 are reserved
+	id := adler32.Checksum(d.samples)%(1<<31-1<<15) + 1<<15
+	data := zstdDictionary(id, d.samples)
+	path := filepath.Join(b.dictionaryDir(), fmt.Sprintf("%s.%08x%s", filepath.Base(b.path), id, DictionarySuffix))
+
+	b.log(1, "writing dictionary %q", path)
+	err := b.writeFile(path, data)
+	if err != nil {
+		return err
+	}
+
+	d.data = data
+	d.id = id
+	d.samples = nil
+	return nil
+}
+
+// Dictionary id of the current file, empty without one.
+func (b *Buffer) dictionaryID() string {
+	if b.codec == nil || b.codec.dict == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%08x", binary.LittleEndian.Uint32(b.codec.dict[4:]))
+}
+
+// Decompress returns a reader of the data in the zstd file `r`, loading
+// the dictionaries its frames reference from `dir`.
+func Decompress(r io.Reader, dir string) (io.ReadCloser, error) {
+	dicts := make(map[uint32]*zstdDict)
+
+	return newZstdReader(r, func(id uint32) (*zstdDict, error) {
+		if d, ok := dicts[id]; ok {
+			return d, nil
+		}
+
+		paths, err := filepath.Glob(filepath.Join(dir, fmt.Sprintf("*.%08x%s", id, DictionarySuffix)))
+		if err != nil {
+			return nil, err
+		}
+
+		sort.Strings(paths)
+		if len(paths) == 0 {
+			return nil, fmt.Errorf("dictionary %08x not found in %q", id, dir)
+		}
+
+		data, err := ioutil.ReadFile(paths[0])
+		if err != nil {
+			return nil, err
+		}
+
+		d, err := parseZstdDict(data)
+		if err != nil {
+			return nil, err
+		}
+
+		dicts[id] = d
+		return d, nil
+	}), nil
+}
@@ -0,0 +1,122 @@
+package buffer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Decompress a zstd file.
+func inflate(t *testing.T, path, dir string) string {
+	f, err := os.Open(path)
+	assert.Equal(t, nil, err)
+	defer f.Close()
+
+	r, err := Decompress(f, dir)
+	assert.Equal(t, nil, err)
+
+	b, err := ioutil.ReadAll(r)
+	assert.Equal(t, nil, err)
+	return string(b)
+}
+
+// Test compressing with a trained dictionary.
+func TestBuffer_Dictionary(t *testing.T) {
+	dir := "/tmp/buffer-dictionary"
+	os.RemoveAll(dir)
+
+	config := &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 10,
+		Codec:       "zstd",
+		Dictionary:  true,
+	}
+
+	b, err := New(filepath.Join(dir, "buffer"), config)
+	assert.Equal(t, nil, err)
+
+	record := func(i int) []byte {
+		return []byte(fmt.Sprintf(`{"level":"info","service":"checkout","message":"order placed","order":%d}`+"\n", i))
+	}
+
+	var first string
+	for i := 0; i < 10; i++ {
+		first += string(record(i))
+		b.Write(record(i))
+	}
+
+	plain := <-b.Queue
+	assert.Equal(t, "zstd", plain.Codec)
+	assert.Equal(t, "", plain.Dict)
+	assert.Equal(t, first, inflate(t, plain.Path, dir))
+
+	var second string
+	for i := 10; i < 20; i++ {
+		second += string(record(i))
+		b.Write(record(i))
+	}
+
+	trained := <-b.Queue
+	assert.NotEqual(t, "", trained.Dict)
+	assert.Equal(t, second, inflate(t, trained.Path, dir))
+	before, err := os.Stat(plain.Path)
+	assert.Equal(t, nil, err)
+	after, err := os.Stat(trained.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, after.Size() < before.Size())
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	b, err = New(filepath.Join(dir, "buffer"), config)
+	assert.Equal(t, nil, err)
+
+	b.Write(record(20))
+	f, err := b.FlushFile()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, trained.Dict, f.Dict)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	_, err = New("/tmp/buffer", &Config{FlushWrites: 1, Dictionary: true})
+	assert.Equal(t, "dictionaries require the zstd codec", err.Error())
+}
+
+// Test dictionaries are persisted next to files flushed to OutDir.
+func TestBuffer_Dictionary_OutDir(t *testing.T) {
+	os.RemoveAll("/tmp/buffer-dictionary")
+	os.RemoveAll("/tmp/buffer-dictionary-out")
+
+	b, err := New("/tmp/buffer-dictionary/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 10,
+		Codec:       "zstd",
+		Dictionary:  true,
+		OutDir:      "/tmp/buffer-dictionary-out",
+	})
+
+	assert.Equal(t, nil, err)
+
+	var records string
+	for i := 0; i < 20; i++ {
+		record := fmt.Sprintf(`{"level":"info","service":"checkout","order":%d}`+"\n", i)
+		b.Write([]byte(record))
+		if i >= 10 {
+			records += record
+		}
+	}
+
+	<-b.Queue
+	f := <-b.Queue
+	assert.NotEqual(t, "", f.Dict)
+	assert.Equal(t, "/tmp/buffer-dictionary-out", filepath.Dir(f.Path))
+	assert.Equal(t, records, inflate(t, f.Path, filepath.Dir(f.Path)))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
@@ -0,0 +1,29 @@
+package buffer
+
+// Discard throws away the current file without publishing it, for
+// example when the producer detects it wrote corrupt data, and opens
+// a fresh one. Batches recorded in the file are settled as Dropped.
+func (b *Buffer) Discard() error {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.stopped {
+		return ErrClosed
+	}
+
+	b.log(1, "discarding %d writes", b.writes)
+
+	if b.buf != nil {
+		b.buf.Reset(b.tap)
+	}
+
+	b.batches.settle(b.seal(), Dropped)
+	b.aggregated()
+
+	err := b.remove()
+	if err != nil {
+		return err
+	}
+
+	return b.open()
+}
@@ -0,0 +1,54 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test discarding the current file.
+func TestBuffer_Discard(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 100,
+		BufferSize:  1024,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.SetBatch("corrupt")
+	b.Write([]byte("garbage"))
+
+	path := b.file.Name()
+
+	err = b.Discard()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(0), b.Writes())
+
+	_, err = os.Stat(path)
+	assert.Equal(t, true, os.IsNotExist(err))
+
+	b.SetBatch("")
+	b.Write([]byte("hello"))
+
+	f, err := b.FlushFile()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(1), f.Writes)
+	assert.Equal(t, 0, len(f.Batches))
+
+	data, err := ioutil.ReadFile(f.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "hello", string(data))
+
+	select {
+	case <-b.Delivered("corrupt"):
+	default:
+		t.Fatal("expected batch to be settled")
+	}
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
@@ -0,0 +1,79 @@
+package buffer
+
+import (
+	"os"
+	"time"
+)
+
+// Make room after a write failed with ENOSPC on attempt `attempt`, under
+// the Block and DropOldest policies for DiskFull, returning whether to
+// retry the write.
+func (b *Buffer) makeRoom(attempt int) bool {
+	switch b.Policy(DiskFull) {
+	case Block:
+		b.decide(DiskFull)
+		backoff := b.backoff().Backoff(attempt)
+		if backoff == 0 {
+			backoff = time.Second
+		}
+		b.log(1, "disk full, retrying write in %s", backoff)
+		time.Sleep(backoff)
+		return true
+	case DropOldest:
+		path, ok := b.oldest()
+		if !ok {
+			return false
+		}
+
+		b.decide(DiskFull)
+		b.shed(path)
+		return true
+	default:
+		return false
+	}
+}
+
+// Whether the DiskFull policy makes room for failed writes, taking
+// effect from the next file opened.
+func (b *Buffer) makesRoom() bool {
+	p := b.Policy(DiskFull)
+	return p == Block || p == DropOldest
+}
+
+// Oldest flushed file which has not been taken for delivery.
+func (b *Buffer) oldest() (string, bool) {
+	var path string
+	var mod time.Time
+	for _, s := range b.FileStates() {
+		if !s.State.evictable() {
+			continue
+		}
+
+		info, err := os.Stat(s.Path)
+		if err != nil {
+			continue
+		}
+
+		if path == "" || info.ModTime().Before(mod) {
+			path, mod = s.Path, info.ModTime()
+		}
+	}
+
+	return path, path != ""
+}
+
+// Evict the flushed file at `path` to free disk space.
+func (b *Buffer) shed(path string) {
+	b.log(1, "disk full, evicting %q", path)
+	f := &Flush{Version: FlushVersion, Path: path}
+	b.error(b.evict(path))
+	b.staleness.untrack(f)
+
+	if b.Quota != nil {
+		b.Quota.remove(f)
+	}
+
+	if b.Hooks.OnDrop != nil {
+		b.Hooks.OnDrop(DiskFull, f)
+	}
+}
@@ -0,0 +1,189 @@
+package buffer
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test evicting the oldest flushed file when the disk is full.
+func TestBuffer_DiskFull_DropOldest(t *testing.T) {
+	os.RemoveAll("/tmp/buffer-diskfull")
+
+	var dropped []*Flush
+	b, err := New("/tmp/buffer-diskfull/buffer", &Config{
+		Queue:       make(chan *Flush, 10),
+		FlushWrites: 1,
+		Policies:    map[Condition]Policy{DiskFull: DropOldest},
+		Hooks:       Hooks{OnDrop: func(c Condition, f *Flush) { dropped = append(dropped, f) }},
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("a"))
+	time.Sleep(10 * time.Millisecond)
+	b.Write([]byte("b"))
+	first, second := <-b.Queue, <-b.Queue
+
+	w := &flaky{fails: 1, err: syscall.ENOSPC}
+	n, err := retrying{b: b, w: w, path: "file"}.Write([]byte("hello"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, 1, len(dropped))
+	assert.Equal(t, first.Path, dropped[0].Path)
+
+	_, err = os.Stat(first.Path)
+	assert.Equal(t, true, os.IsNotExist(err))
+
+	_, err = os.Stat(second.Path)
+	assert.Equal(t, nil, err)
+
+	s, _ := b.FileState(first.Path)
+	assert.Equal(t, State(""), s.State)
+	assert.Equal(t, int64(1), b.Decisions()[Decision{DiskFull, DropOldest}])
+
+	w = &flaky{fails: 3, err: syscall.ENOSPC}
+	_, err = retrying{b: b, w: w, path: "file"}.Write([]byte("hello"))
+	assert.Equal(t, true, errors.Is(err, syscall.ENOSPC))
+	assert.Equal(t, 2, len(dropped))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test blocking until the disk has room.
+func TestBuffer_DiskFull_Block(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		FlushWrites:   100,
+		RenameBackoff: time.Millisecond,
+		Policies:      map[Condition]Policy{DiskFull: Block},
+	})
+
+	assert.Equal(t, nil, err)
+
+	w := &flaky{fails: 3, err: syscall.ENOSPC}
+	n, err := retrying{b: b, w: w, path: "file"}.Write([]byte("hello"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, "hello", string(w.data))
+	assert.Equal(t, int64(3), b.Decisions()[Decision{DiskFull, Block}])
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Writer with room for a limited number of bytes.
+type full struct {
+	w    io.Writer
+	room int
+}
+
+func (f *full) Write(p []byte) (int, error) {
+	if len(p) <= f.room {
+		f.room -= len(p)
+		return f.w.Write(p)
+	}
+
+	n, _ := f.w.Write(p[:f.room])
+	f.room -= n
+	return n, syscall.ENOSPC
+}
+
+// Test dropping only the failed write when the disk is full.
+func TestBuffer_DiskFull_DropNewest(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 10),
+		FlushWrites: 100,
+		Delimiter:   []byte("\n"),
+		Policies:    map[Condition]Policy{DiskFull: DropNewest},
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+
+	b.Lock()
+	w := b.w
+	b.w = &full{w: w, room: 2}
+	b.Unlock()
+
+	n, err := b.Write([]byte("world"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 5, n)
+
+	b.Lock()
+	b.w = w
+	b.Unlock()
+
+	b.Write([]byte("again"))
+
+	f, err := b.FlushFile()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(2), f.Writes)
+	assert.Equal(t, int64(12), f.Bytes)
+
+	data, err := ioutil.ReadFile(f.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "hello\nagain\n", string(data))
+
+	<-b.Queue
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test restoring buffered records when writing them out fails.
+func TestBuffer_DiskFull_DropNewest_buffered(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 10),
+		FlushWrites: 100,
+		BufferSize:  8,
+		Delimiter:   []byte("\n"),
+		Policies:    map[Condition]Policy{DiskFull: DropNewest},
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("abc"))
+
+	b.Lock()
+	w := b.tap.w
+	b.tap.w = &full{w: w, room: 2}
+	b.Unlock()
+
+	_, err = b.Write([]byte("defghijk"))
+	assert.Equal(t, nil, err)
+
+	b.Lock()
+	b.tap.w = w
+	b.Unlock()
+
+	b.Write([]byte("xy"))
+
+	f, err := b.FlushFile()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(2), f.Writes)
+
+	data, err := ioutil.ReadFile(f.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "abc\nxy\n", string(data))
+
+	<-b.Queue
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test DropOldest requires a buffered queue.
+func TestBuffer_QueueFull_DropOldest_unbuffered(t *testing.T) {
+	_, err := New("/tmp/buffer", &Config{
+		FlushWrites: 1,
+		Policies:    map[Condition]Policy{QueueFull: DropOldest},
+	})
+
+	assert.Equal(t, "policy drop_oldest for queue_full requires a buffered queue", err.Error())
+}
@@ -0,0 +1,118 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Move or link acked file `f` and its sidecars into the done directory,
+// leaving an audit trail and a manual replay path. Their modification
+// times are reset so retention counts from the ack.
+func (b *Buffer) done(f *Flush) error {
+	paths := []string{f.Path}
+	if b.Sidecar {
+		paths = append(paths, f.Path+"."+f.Hash)
+	}
+	if b.MetaFile {
+		paths = append(paths, f.Path+MetaFileSuffix)
+	}
+
+	for _, path := range paths {
+		target := filepath.Join(b.DoneDir, filepath.Base(path))
+
+		err := b.mkdir(target)
+		if err != nil {
+			return err
+		}
+
+		if b.DoneLink {
+			b.log(2, "linking %q to %q", path, target)
+			err = os.Link(path, target)
+		} else {
+			b.log(2, "moving %q to %q", path, target)
+			err = os.Rename(path, target)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		err = os.Chtimes(target, now, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	if b.Durability >= PowerSafe {
+		return syncDir(filepath.Join(b.DoneDir, filepath.Base(f.Path)))
+	}
+
+	return nil
+}
+
+// Remove files older than `retention` from `dir`.
+func (b *Buffer) prune(dir string, retention time.Duration) error {
+	if dir == "" || retention == 0 {
+		return nil
+	}
+
+	infos, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-retention)
+
+	for _, info := range infos {
+		if !info.Mode().IsRegular() || info.ModTime().After(cutoff) {
+			continue
+		}
+
+		path := filepath.Join(dir, info.Name())
+		b.log(2, "removing expired %q", path)
+
+		err := os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Prune the done and trash directories on an interval.
+func (b *Buffer) pruner() {
+	b.label("prune")
+
+	for {
+		select {
+		case <-b.quit:
+			return
+		case <-b.prunes.C:
+			b.error(b.prune(b.DoneDir, b.DoneRetention))
+			b.error(b.prune(b.TrashDir, b.TrashRetention))
+		}
+	}
+}
+
+// Interval between prunes, a tenth of the shortest retention.
+func (c *Config) pruneInterval() time.Duration {
+	var d time.Duration
+
+	if c.DoneDir != "" && c.DoneRetention != 0 {
+		d = c.DoneRetention
+	}
+
+	if c.TrashDir != "" && c.TrashRetention != 0 && (d == 0 || c.TrashRetention < d) {
+		d = c.TrashRetention
+	}
+
+	return d / 10
+}
@@ -0,0 +1,106 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test moving acked files into the done directory.
+func TestBuffer_DoneDir(t *testing.T) {
+	os.RemoveAll("/tmp/buffer-done")
+
+	b, err := New("/tmp/buffer", &Config{
+		Queue:         make(chan *Flush, 100),
+		FlushWrites:   1,
+		MetaFile:      true,
+		DoneDir:       "/tmp/buffer-done",
+		DoneRetention: 100 * time.Millisecond,
+		Verbosity:     0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+
+	f := <-b.Queue
+	b.Ack(f)
+
+	_, err = os.Stat(f.Path)
+	assert.Equal(t, true, os.IsNotExist(err))
+
+	data, err := ioutil.ReadFile(filepath.Join("/tmp/buffer-done", filepath.Base(f.Path)))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "hello", string(data))
+
+	files, _ := filepath.Glob("/tmp/buffer-done/*")
+	assert.Equal(t, 2, len(files))
+
+	time.Sleep(200 * time.Millisecond)
+
+	files, _ = filepath.Glob("/tmp/buffer-done/*")
+	assert.Equal(t, 0, len(files))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test linking acked files into the done directory.
+func TestBuffer_DoneLink(t *testing.T) {
+	os.RemoveAll("/tmp/buffer-done")
+
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1,
+		DoneDir:     "/tmp/buffer-done",
+		DoneLink:    true,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+
+	f := <-b.Queue
+	b.Ack(f)
+
+	_, err = os.Stat(f.Path)
+	assert.Equal(t, nil, err)
+
+	_, err = os.Stat(filepath.Join("/tmp/buffer-done", filepath.Base(f.Path)))
+	assert.Equal(t, nil, err)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test retention of acked files counts from the ack.
+func TestBuffer_DoneRetention(t *testing.T) {
+	os.RemoveAll("/tmp/buffer-done")
+
+	b, err := New("/tmp/buffer", &Config{
+		Queue:         make(chan *Flush, 100),
+		FlushWrites:   1,
+		DoneDir:       "/tmp/buffer-done",
+		DoneRetention: 100 * time.Millisecond,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+
+	f := <-b.Queue
+	time.Sleep(200 * time.Millisecond)
+	b.Ack(f)
+	time.Sleep(30 * time.Millisecond)
+
+	files, _ := filepath.Glob("/tmp/buffer-done/*")
+	assert.Equal(t, 1, len(files))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
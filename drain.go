@@ -0,0 +1,79 @@
+package buffer
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrDraining is returned by writes once Drain has been called.
+var ErrDraining = errors.New("buffer draining")
+
+// Drain stops accepting writes and flushes the buffer, waits for consumers
+// to empty the Queue, for files marked Delivering to be settled and for
+// recovered files to be published, then closes the buffer and the Queue so
+// consumers ranging over it can tell the stream is done. Files sent on an
+// unbuffered Queue have been received once they are flushed. The buffer
+// and Queue are left open when `ctx` is done first, and Drain may be
+// called again.
+func (b *Buffer) Drain(ctx context.Context) error {
+	b.Lock()
+	b.draining = true
+	keys := make([]*Buffer, 0, len(b.keys))
+	for _, k := range b.keys {
+		keys = append(keys, k)
+	}
+	b.Unlock()
+
+	for _, k := range keys {
+		k.Lock()
+		k.draining = true
+		k.Unlock()
+	}
+
+	b.log(1, "draining")
+	b.closeRing()
+
+	err := b.Flush()
+	if err != nil && err != ErrClosed {
+		return err
+	}
+
+	b.encodings.Wait()
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		if b.drained() {
+			b.log(1, "drained")
+			err := b.Close()
+			b.drain.Do(func() {
+				close(b.Queue)
+			})
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Whether the Queue is empty and no file is being delivered.
+func (b *Buffer) drained() bool {
+	delivered, total := b.Backlog()
+	if len(b.Queue) != 0 || delivered != total {
+		return false
+	}
+
+	for _, s := range b.FileStates() {
+		if s.State == Delivering {
+			return false
+		}
+	}
+
+	return true
+}
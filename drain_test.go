@@ -0,0 +1,106 @@
+package buffer
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test draining the buffer.
+func TestBuffer_Drain(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 100,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+	b.WriteKeyed("tobi", []byte("hello"))
+
+	var flushes []*Flush
+	done := make(chan struct{})
+	go func() {
+		for f := range b.Queue {
+			flushes = append(flushes, f)
+		}
+		close(done)
+	}()
+
+	err = b.Drain(context.Background())
+	assert.Equal(t, nil, err)
+
+	<-done
+	assert.Equal(t, 2, len(flushes))
+
+	_, err = b.Write([]byte("hello"))
+	assert.Equal(t, ErrDraining, err)
+
+	_, err = b.WriteKeyed("loki", []byte("hello"))
+	assert.Equal(t, ErrDraining, err)
+}
+
+// Test draining with a stalled consumer.
+func TestBuffer_Drain_timeout(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 100,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err = b.Drain(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, 1, len(b.Queue))
+}
+
+// Test consumers can ack while draining, and draining again.
+func TestBuffer_Drain_ack(t *testing.T) {
+	os.Remove("/tmp/buffer-drain.manifest")
+
+	config := &Config{
+		Queue:       make(chan *Flush),
+		FlushWrites: 100,
+		Manifest:    "/tmp/buffer-drain.manifest",
+	}
+
+	b, err := New("/tmp/buffer", config)
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+
+	done := make(chan struct{})
+	go func() {
+		for f := range b.Queue {
+			b.Delivering(f)
+			time.Sleep(50 * time.Millisecond)
+			b.Ack(f)
+		}
+		close(done)
+	}()
+
+	err = b.Drain(context.Background())
+	assert.Equal(t, nil, err)
+	<-done
+
+	err = b.Drain(context.Background())
+	assert.Equal(t, nil, err)
+
+	config.Queue = make(chan *Flush, 10)
+	b, err = New("/tmp/buffer", config)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, len(b.Queue))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
@@ -0,0 +1,48 @@
+package buffer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Durability level of flushed files.
+type Durability int
+
+// Durability levels.
+const (
+	NoSync    Durability = iota // Leave writeback to the OS
+	FileSync                    // Fsync files before they are published
+	PowerSafe                   // FileSync, and fsync directories after renames and manifest appends
+)
+
+// String returns the level name.
+func (d Durability) String() string {
+	switch d {
+	case NoSync:
+		return "none"
+	case FileSync:
+		return "file"
+	case PowerSafe:
+		return "power_safe"
+	default:
+		return fmt.Sprintf("durability(%d)", int(d))
+	}
+}
+
+// Fsync the directory containing `path`, so a rename into it survives
+// power loss.
+func syncDir(path string) error {
+	d, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+
+	err = d.Sync()
+	if err != nil {
+		d.Close()
+		return err
+	}
+
+	return d.Close()
+}
@@ -0,0 +1,85 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test power-safe durability.
+func TestBuffer_Durability(t *testing.T) {
+	os.Remove("/tmp/buffer.manifest")
+
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1,
+		Durability:  PowerSafe,
+		Manifest:    "/tmp/buffer.manifest",
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+
+	f := <-b.Queue
+	data, err := ioutil.ReadFile(f.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "hello", string(data))
+
+	b.Ack(f)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test unsupported durability levels.
+func TestBuffer_Durability_unsupported(t *testing.T) {
+	_, err := New("/tmp/buffer", &Config{
+		FlushWrites: 1,
+		Durability:  Durability(5),
+	})
+
+	assert.Equal(t, "unsupported durability durability(5)", err.Error())
+}
+
+// Test power-safe durability across spool and out directories.
+func TestBuffer_Durability_OutDir(t *testing.T) {
+	os.RemoveAll("/tmp/buffer-spool")
+	os.RemoveAll("/tmp/buffer-out")
+
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 2,
+		Delimiter:   []byte("\n"),
+		Durability:  PowerSafe,
+		SpoolDir:    "/tmp/buffer-spool",
+		OutDir:      "/tmp/buffer-out",
+		Codec:       "gzip",
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+	b.Write([]byte("world"))
+
+	f := <-b.Queue
+	assert.Equal(t, "/tmp/buffer-out", filepath.Dir(f.Path))
+
+	r, err := Open(f.Path)
+	assert.Equal(t, nil, err)
+	data, err := ioutil.ReadAll(r)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "hello\nworld\n", string(data))
+	r.Close()
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	files, err := ioutil.ReadDir("/tmp/buffer-spool")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, len(files))
+}
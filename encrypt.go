@@ -0,0 +1,109 @@
+package buffer
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Magic bytes of an encrypted file header.
+var magic = []byte("DBUF")
+
+// Encrypted file header version.
+const fileVersion = 1
+
+// KeyProvider supplies data keys used to encrypt files. Keyed writes
+// request the key for their partition, allowing each tenant's files
+// to be isolated and revoked by removing its key from the provider.
+type KeyProvider interface {
+	// Key returns the id and AES key for `partition`, which is
+	// empty for writes made without a key.
+	Key(partition string) (id string, key []byte, err error)
+
+	// Lookup returns the AES key for `id`.
+	Lookup(id string) ([]byte, error)
+}
+
+// Encrypt writes to `w`, returning the encrypting writer and key id. Files
+// begin with a header of magic bytes, version, key id and IV followed by
+// the AES-CTR encrypted data.
+func (b *Buffer) encrypt(w io.Writer) (io.Writer, string, error) {
+	id, key, err := b.KeyProvider.Key(b.key)
+	if err != nil {
+		return nil, "", err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, "", err
+	}
+
+	iv := make([]byte, block.BlockSize())
+	_, err = rand.Read(iv)
+	if err != nil {
+		return nil, "", err
+	}
+
+	header := make([]byte, 0, len(magic)+3+len(id)+len(iv))
+	header = append(header, magic...)
+	header = append(header, fileVersion)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(id)))
+	header = append(header, id...)
+	header = append(header, iv...)
+
+	_, err = w.Write(header)
+	if err != nil {
+		return nil, "", err
+	}
+
+	b.log(2, "encrypting with key %q", id)
+	return cipher.StreamWriter{S: cipher.NewCTR(block, iv), W: w}, id, nil
+}
+
+// Decrypt returns a reader of the data in file `r`, written by a
+// buffer using a KeyProvider, looking up the key with `p`.
+func Decrypt(r io.Reader, p KeyProvider) (io.Reader, error) {
+	br := bufio.NewReader(r)
+
+	header := make([]byte, len(magic)+3)
+	_, err := io.ReadFull(br, header)
+	if err != nil {
+		return nil, err
+	}
+
+	if string(header[:len(magic)]) != string(magic) {
+		return nil, fmt.Errorf("invalid file header")
+	}
+
+	if v := header[len(magic)]; v != fileVersion {
+		return nil, fmt.Errorf("unsupported file version %d", v)
+	}
+
+	id := make([]byte, binary.BigEndian.Uint16(header[len(magic)+1:]))
+	_, err = io.ReadFull(br, id)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := p.Lookup(string(id))
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	iv := make([]byte, block.BlockSize())
+	_, err = io.ReadFull(br, iv)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.StreamReader{S: cipher.NewCTR(block, iv), R: br}, nil
+}
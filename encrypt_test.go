@@ -0,0 +1,76 @@
+package buffer
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Keys by tenant.
+type keys map[string][]byte
+
+func (k keys) Key(partition string) (string, []byte, error) {
+	id := "tenant-" + partition
+	key, err := k.Lookup(id)
+	return id, key, err
+}
+
+func (k keys) Lookup(id string) ([]byte, error) {
+	key, ok := k[id]
+	if !ok {
+		return nil, fmt.Errorf("key %q revoked", id)
+	}
+	return key, nil
+}
+
+// Test per-key encryption.
+func TestBuffer_Encrypt(t *testing.T) {
+	provider := keys{
+		"tenant-tobi": bytes.Repeat([]byte("t"), 32),
+		"tenant-loki": bytes.Repeat([]byte("l"), 32),
+		"tenant-":     bytes.Repeat([]byte("r"), 32),
+	}
+
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 2,
+		BufferSize:  1 << 10,
+		KeyProvider: provider,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.WriteKeyed("tobi", []byte("hello "))
+	b.WriteKeyed("tobi", []byte("world"))
+
+	flush := <-b.Queue
+	assert.Equal(t, "tenant-tobi", flush.KeyID)
+
+	raw, err := ioutil.ReadFile(flush.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, false, bytes.Contains(raw, []byte("hello")))
+
+	f, err := os.Open(flush.Path)
+	assert.Equal(t, nil, err)
+	defer f.Close()
+
+	r, err := Decrypt(f, provider)
+	assert.Equal(t, nil, err)
+
+	data, err := ioutil.ReadAll(r)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "hello world", string(data))
+
+	delete(provider, "tenant-tobi")
+	f.Seek(0, 0)
+	_, err = Decrypt(f, provider)
+	assert.Equal(t, `key "tenant-tobi" revoked`, err.Error())
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
@@ -0,0 +1,91 @@
+package buffer
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+)
+
+// Envelope encodes annotated records, see WriteWithMeta. Implement it to
+// match an existing wire format, such as protobuf.
+type Envelope interface {
+	Encode(data []byte, meta map[string]string) ([]byte, error)
+	Decode(record []byte) (map[string]string, []byte, error)
+}
+
+// MetaPrefix marks a record encoded by QueryEnvelope.
+const MetaPrefix = '\x1e'
+
+// QueryEnvelope prefixes the data with MetaPrefix and the annotations as a
+// URL-encoded query terminated by a newline. This is the default.
+type QueryEnvelope struct{}
+
+// Encode implementation.
+func (QueryEnvelope) Encode(data []byte, meta map[string]string) ([]byte, error) {
+	values := make(url.Values, len(meta))
+	for k, v := range meta {
+		values.Set(k, v)
+	}
+
+	enc := values.Encode()
+	buf := make([]byte, 0, len(enc)+len(data)+2)
+	buf = append(buf, MetaPrefix)
+	buf = append(buf, enc...)
+	buf = append(buf, '\n')
+	return append(buf, data...), nil
+}
+
+// Decode implementation. Records without the prefix are returned as is.
+func (QueryEnvelope) Decode(record []byte) (map[string]string, []byte, error) {
+	if len(record) == 0 || record[0] != MetaPrefix {
+		return nil, record, nil
+	}
+
+	i := bytes.IndexByte(record, '\n')
+	if i == -1 {
+		return nil, record, nil
+	}
+
+	values, err := url.ParseQuery(string(record[1:i]))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	meta := make(map[string]string, len(values))
+	for k, v := range values {
+		meta[k] = v[0]
+	}
+
+	return meta, record[i+1:], nil
+}
+
+// JSONEnvelope encodes records as newline-delimited JSON objects with
+// "meta" and "data" fields.
+type JSONEnvelope struct{}
+
+// JSON envelope record.
+type jsonRecord struct {
+	Meta map[string]string `json:"meta"`
+	Data string            `json:"data"`
+}
+
+// Encode implementation.
+func (JSONEnvelope) Encode(data []byte, meta map[string]string) ([]byte, error) {
+	buf, err := json.Marshal(jsonRecord{Meta: meta, Data: string(data)})
+	if err != nil {
+		return nil, err
+	}
+
+	return append(buf, '\n'), nil
+}
+
+// Decode implementation.
+func (JSONEnvelope) Decode(record []byte) (map[string]string, []byte, error) {
+	var r jsonRecord
+	err := json.Unmarshal(record, &r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return r.Meta, []byte(r.Data), nil
+}
@@ -0,0 +1,58 @@
+package buffer
+
+import (
+	"fmt"
+	"time"
+)
+
+// RenameError is returned when a flushed file could not be renamed
+// after all retries. The file remains open and is retried on the next flush.
+type RenameError struct {
+	Path     string
+	Target   string
+	Attempts int
+	Err      error
+}
+
+// Error implements error.
+func (e *RenameError) Error() string {
+	return fmt.Sprintf("renaming %q to %q failed after %d attempts: %s", e.Path, e.Target, e.Attempts, e.Err)
+}
+
+// Unwrap returns the last rename error.
+func (e *RenameError) Unwrap() error {
+	return e.Err
+}
+
+// StaleError is reported when a record remains unshipped for longer than
+// the bound given to MaxStaleness.
+type StaleError struct {
+	Path  string
+	First time.Time
+	Age   time.Duration
+	Bound time.Duration
+}
+
+// Error implements error.
+func (e *StaleError) Error() string {
+	return fmt.Sprintf("%q unshipped for %s, exceeding %s", e.Path, e.Age, e.Bound)
+}
+
+// DiskError is reported when a write or create of a buffer file failed
+// with a transient error, such as EIO or ENOSPC, after all retries.
+type DiskError struct {
+	Op       string
+	Path     string
+	Attempts int
+	Err      error
+}
+
+// Error implements error.
+func (e *DiskError) Error() string {
+	return fmt.Sprintf("%s %q failed after %d attempts: %s", e.Op, e.Path, e.Attempts, e.Err)
+}
+
+// Unwrap returns the last disk error.
+func (e *DiskError) Unwrap() error {
+	return e.Err
+}
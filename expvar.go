@@ -0,0 +1,36 @@
+package buffer
+
+import (
+	"expvar"
+	"fmt"
+)
+
+// Publish buffer state as an expvar.
+func (b *Buffer) publishExpvar() error {
+	if expvar.Get(b.Expvar) != nil {
+		return fmt.Errorf("expvar %q already published", b.Expvar)
+	}
+
+	expvar.Publish(b.Expvar, expvar.Func(b.vars))
+	return nil
+}
+
+// Variables published to expvar.
+func (b *Buffer) vars() interface{} {
+	sizes, ages := b.Histograms()
+	stats := b.Stats()
+
+	b.RLock()
+	defer b.RUnlock()
+
+	return map[string]interface{}{
+		"writes":     b.writes,
+		"bytes":      b.bytes,
+		"flushes":    b.flushes,
+		"last_flush": b.flushed,
+		"queue":      len(b.Queue),
+		"stats":      stats,
+		"sizes":      sizes,
+		"ages":       ages,
+	}
+}
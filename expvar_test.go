@@ -0,0 +1,49 @@
+package buffer
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test expvar publishing.
+func TestBuffer_Expvar(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 2,
+		Expvar:      "buffer_test",
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+	b.Write([]byte("hello"))
+	b.Write([]byte("world"))
+
+	var vars struct {
+		Writes  int64 `json:"writes"`
+		Bytes   int64 `json:"bytes"`
+		Flushes int64 `json:"flushes"`
+		Queue   int   `json:"queue"`
+	}
+
+	err = json.Unmarshal([]byte(expvar.Get("buffer_test").String()), &vars)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(1), vars.Writes)
+	assert.Equal(t, int64(5), vars.Bytes)
+	assert.Equal(t, int64(1), vars.Flushes)
+	assert.Equal(t, 1, vars.Queue)
+
+	_, err = New("/tmp/buffer", &Config{
+		FlushWrites: 2,
+		Expvar:      "buffer_test",
+	})
+
+	assert.Equal(t, `expvar "buffer_test" already published`, err.Error())
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
@@ -0,0 +1,158 @@
+package buffer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Hostname for filename templates.
+var hostname, _ = os.Hostname()
+
+// SortableFilename is a Filename template embedding the zero-padded open
+// time and sequence, so files sort chronologically by name across restarts.
+const SortableFilename = `{{.Path}}{{with .Key}}.{{.}}{{end}}` +
+	`.{{.Opened.UTC.Format "20060102T150405.000000000Z"}}` +
+	`.{{printf "%010d" .Seq}}.{{.PID}}.{{.ID}}`
+
+// Name holds the fields available to Config.Filename templates,
+// for example "{{.Path}}-{{.Hostname}}-{{.Seq}}.ndjson".
+type Name struct {
+	Path     string    // Base path given to New
+	PID      int       // Process id
+	ID       int64     // Buffer id
+	Seq      int64     // File sequence, persisted across restarts with Config.SeqFile
+	Opened   time.Time // Open time
+	Bucket   time.Time // Bucket time when FlushBucket is enabled
+	Hostname string    // Hostname
+	Key      string    // Partition key for keyed writes
+	UID      string    // Unique id when Config.NewID is set
+}
+
+// Pathname and sequence for a new buffer.
+func (b *Buffer) pathname() (string, int64, error) {
+	seq, err := b.next()
+	if err != nil {
+		return "", 0, err
+	}
+
+	path, err := b.nameOf(seq)
+	return path, seq, err
+}
+
+// Pathname of a new buffer with sequence `seq`, opened now.
+func (b *Buffer) nameOf(seq int64) (string, error) {
+	name := Name{
+		Path:     b.path,
+		PID:      pid,
+		ID:       b.id,
+		Seq:      seq,
+		Opened:   time.Now(),
+		Bucket:   b.bucket,
+		Hostname: hostname,
+		Key:      b.key,
+	}
+
+	if b.NewID != nil {
+		name.UID = b.NewID()
+	}
+
+	path := name.String()
+
+	if b.name != nil {
+		var buf bytes.Buffer
+		err := b.name.Execute(&buf, name)
+		if err != nil {
+			return "", err
+		}
+		path = buf.String()
+	}
+
+	switch {
+	case b.spilling:
+		path = filepath.Join(b.SpillDir, filepath.Base(path))
+	case b.SpoolDir != "":
+		path = filepath.Join(b.SpoolDir, filepath.Base(path))
+	}
+
+	if b.Logrotate {
+		path = b.unique(path)
+	}
+
+	return path + b.Staging, nil
+}
+
+// Closed path of the current file.
+func (b *Buffer) closed() string {
+	return b.closedPath(strings.TrimSuffix(b.staged, b.Staging))
+}
+
+// Closed path of the file at `path`, without the staging suffix.
+func (b *Buffer) closedPath(path string) string {
+	if b.OutDir != "" && !b.spilling {
+		path = filepath.Join(b.OutDir, filepath.Base(path))
+	}
+
+	switch {
+	case b.Suffix != "":
+		return path + b.Suffix
+	case b.Staging != "":
+		return path
+	default:
+		return path + ".closed"
+	}
+}
+
+// String returns the default filename, "{path}[.{key}][.{bucket}].{pid}.{id}.{seq}",
+// or "{path}[.{key}][.{bucket}].{uid}" when a unique id is present.
+func (n Name) String() string {
+	path := n.Path
+
+	if n.Key != "" {
+		path += "." + n.Key
+	}
+
+	if !n.Bucket.IsZero() {
+		path += "." + n.Bucket.UTC().Format("20060102T150405Z")
+	}
+
+	if n.UID != "" {
+		return path + "." + n.UID
+	}
+
+	return fmt.Sprintf("%s.%d.%d.%d", path, n.PID, n.ID, n.Seq)
+}
+
+// Crockford base32 alphabet for ULIDs.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULID returns a new universally unique lexicographically sortable
+// identifier, suitable for Config.NewID.
+func ULID() string {
+	var id [16]byte
+
+	ms := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	binary.BigEndian.PutUint64(id[:8], ms<<16)
+
+	_, err := rand.Read(id[6:])
+	if err != nil {
+		panic(err)
+	}
+
+	hi := binary.BigEndian.Uint64(id[:8])
+	lo := binary.BigEndian.Uint64(id[8:])
+
+	var s [26]byte
+	for i := len(s) - 1; i >= 0; i-- {
+		s[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+
+	return string(s[:])
+}
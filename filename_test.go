@@ -0,0 +1,154 @@
+package buffer
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test filename templates.
+func TestBuffer_Filename(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1,
+		Filename:    "{{.Path}}-{{.Key}}-{{.Seq}}.ndjson",
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	_, err = b.WriteKeyed("tobi", []byte("hello"))
+	assert.Equal(t, nil, err)
+
+	flush := <-b.Queue
+	assert.Equal(t, "/tmp/buffer-tobi-1.ndjson.closed", flush.Path)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test sortable filenames.
+func TestBuffer_Filename_Sortable(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1,
+		Filename:    SortableFilename,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	var paths []string
+	for i := 0; i < 12; i++ {
+		b.Write([]byte("hello"))
+		paths = append(paths, (<-b.Queue).Path)
+	}
+
+	assert.Equal(t, true, sort.StringsAreSorted(paths))
+	assert.Equal(t, true, strings.Contains(paths[11], ".0000000012."))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test unique file ids.
+func TestBuffer_Filename_NewID(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1,
+		NewID:       ULID,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+	flush := <-b.Queue
+
+	id := strings.TrimSuffix(strings.TrimPrefix(flush.Path, "/tmp/buffer."), ".closed")
+	assert.Equal(t, 26, len(id))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test ULIDs sort by time.
+func TestULID(t *testing.T) {
+	a := ULID()
+	time.Sleep(2 * time.Millisecond)
+	b := ULID()
+
+	assert.Equal(t, 26, len(a))
+	assert.Equal(t, true, a < b)
+	assert.NotEqual(t, ULID(), ULID())
+}
+
+// Test staging and closed suffixes.
+func TestBuffer_Filename_Suffix(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1,
+		Filename:    "{{.Path}}-{{.ID}}-{{.Seq}}.ndjson",
+		Staging:     ".tmp",
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, strings.HasSuffix(b.file.Name(), ".ndjson.tmp"))
+
+	b.Write([]byte("hello"))
+	flush := <-b.Queue
+	assert.Equal(t, true, strings.HasSuffix(flush.Path, ".ndjson"))
+
+	_, err = os.Stat(flush.Path)
+	assert.Equal(t, nil, err)
+
+	b.Suffix = ".done"
+	b.Write([]byte("hello"))
+	flush = <-b.Queue
+	assert.Equal(t, true, strings.HasSuffix(flush.Path, ".ndjson.done"))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test spool and output directories.
+func TestBuffer_Filename_Dirs(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1,
+		SpoolDir:    "/tmp/buffer-spool",
+		OutDir:      "/tmp/buffer-out",
+		Staging:     ".tmp",
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "/tmp/buffer-spool", filepath.Dir(b.file.Name()))
+
+	b.Write([]byte("hello"))
+	flush := <-b.Queue
+	assert.Equal(t, "/tmp/buffer-out", filepath.Dir(flush.Path))
+	assert.Equal(t, true, strings.HasPrefix(filepath.Base(flush.Path), "buffer."))
+
+	_, err = os.Stat(flush.Path)
+	assert.Equal(t, nil, err)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test invalid filename templates.
+func TestBuffer_Filename_Invalid(t *testing.T) {
+	_, err := New("/tmp/buffer", &Config{
+		FlushWrites: 1,
+		Filename:    "{{.Path",
+	})
+
+	assert.NotEqual(t, nil, err)
+}
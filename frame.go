@@ -0,0 +1,56 @@
+package buffer
+
+import (
+	"bytes"
+	"time"
+)
+
+// Write header or footer `data` to the current file, counting towards
+// its bytes and, by their delimiters, the records in its trailer, but
+// not its writes.
+func (b *Buffer) frame(data []byte) error {
+	n, err := b.w.Write(data)
+	b.bytes += int64(n)
+	if len(b.Delimiter) != 0 {
+		b.framed += int64(bytes.Count(data[:n], b.Delimiter))
+	}
+	return err
+}
+
+// Write the Hooks.Header preamble of the current file.
+func (b *Buffer) preamble() error {
+	b.log(2, "writing header")
+	var buf bytes.Buffer
+	err := b.Hooks.Header(&buf)
+	if err != nil {
+		return err
+	}
+
+	return b.frame(buf.Bytes())
+}
+
+// Write the Hooks.Footer of the current file flushed for `reason`, given
+// the Flush as it stands before the footer.
+func (b *Buffer) footer(reason Reason) error {
+	f := &Flush{
+		Version: FlushVersion,
+		Reason:  reason,
+		Writes:  b.writes,
+		Bytes:   b.bytes,
+		Opened:  b.opened,
+		First:   b.first,
+		Closed:  time.Now(),
+		Path:    b.closed(),
+		Key:     b.key,
+		Seq:     b.seq,
+	}
+
+	b.log(2, "writing footer")
+	var buf bytes.Buffer
+	err := b.Hooks.Footer(&buf, f)
+	if err != nil {
+		return err
+	}
+
+	return b.frame(buf.Bytes())
+}
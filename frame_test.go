@@ -0,0 +1,47 @@
+package buffer
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test writing headers and footers to each file.
+func TestBuffer_HeaderFooter(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 2,
+		Hooks: Hooks{
+			Header: func(w io.Writer) error {
+				_, err := io.WriteString(w, "MAGIC\n")
+				return err
+			},
+			Footer: func(w io.Writer, f *Flush) error {
+				_, err := fmt.Fprintf(w, "%s %d %d\n", f.Reason, f.Writes, f.Bytes)
+				return err
+			},
+		},
+	})
+
+	assert.Equal(t, nil, err)
+
+	_, err = b.Write([]byte("hello\n"))
+	assert.Equal(t, nil, err)
+
+	_, err = b.Write([]byte("world\n"))
+	assert.Equal(t, nil, err)
+
+	flush := <-b.Queue
+	assert.Equal(t, int64(2), flush.Writes)
+	assert.Equal(t, int64(30), flush.Bytes)
+
+	data, err := ioutil.ReadFile(flush.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "MAGIC\nhello\nworld\nwrites 2 18\n", string(data))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
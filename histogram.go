@@ -0,0 +1,142 @@
+package buffer
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Default file size buckets in bytes, 1KiB to 1GiB.
+var defaultSizeBuckets = []float64{1 << 10, 1 << 12, 1 << 14, 1 << 16, 1 << 18, 1 << 20, 1 << 22, 1 << 24, 1 << 26, 1 << 28, 1 << 30}
+
+// Default file age buckets in seconds.
+var defaultAgeBuckets = []float64{1, 5, 15, 30, 60, 300, 900, 1800, 3600}
+
+// Histogram of observed values.
+type Histogram struct {
+	Bounds []float64 `json:"bounds"` // Bucket upper bounds
+	Counts []int64   `json:"counts"` // Count per bucket, with a final +Inf bucket
+	Count  int64     `json:"count"`  // Total observations
+	Sum    float64   `json:"sum"`    // Sum of observations
+}
+
+// New histogram with upper `bounds`.
+func newHistogram(bounds []float64) *Histogram {
+	bounds = append([]float64(nil), bounds...)
+	sort.Float64s(bounds)
+
+	return &Histogram{
+		Bounds: bounds,
+		Counts: make([]int64, len(bounds)+1),
+	}
+}
+
+// Observe value `v`.
+func (h *Histogram) observe(v float64) {
+	i := sort.SearchFloat64s(h.Bounds, v)
+	h.Counts[i]++
+	h.Count++
+	h.Sum += v
+}
+
+// Copy of the histogram.
+func (h *Histogram) copy() Histogram {
+	return Histogram{
+		Bounds: append([]float64(nil), h.Bounds...),
+		Counts: append([]int64(nil), h.Counts...),
+		Count:  h.Count,
+		Sum:    h.Sum,
+	}
+}
+
+// Write the histogram in the Prometheus text format.
+func (h Histogram) write(w io.Writer, name, help, labels string) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	if err != nil {
+		return err
+	}
+
+	var n int64
+	for i, count := range h.Counts {
+		n += count
+
+		le := "+Inf"
+		if i < len(h.Bounds) {
+			le = strconv.FormatFloat(h.Bounds[i], 'g', -1, 64)
+		}
+
+		_, err = fmt.Fprintf(w, "%s_bucket{%s,le=%q} %d\n", name, labels, le, n)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintf(w, "%s_sum{%s} %s\n%s_count{%s} %d\n", name, labels, formatFloat(h.Sum), name, labels, h.Count)
+	return err
+}
+
+// Format a float for the Prometheus text format.
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// File histograms shared by a buffer and its partitions.
+type histograms struct {
+	sync.Mutex
+	sizes *Histogram
+	ages  *Histogram
+}
+
+// New histograms from the configured buckets.
+func newHistograms(c *Config) *histograms {
+	sizes, ages := c.SizeBuckets, c.AgeBuckets
+
+	if sizes == nil {
+		sizes = defaultSizeBuckets
+	}
+
+	if ages == nil {
+		ages = defaultAgeBuckets
+	}
+
+	return &histograms{
+		sizes: newHistogram(sizes),
+		ages:  newHistogram(ages),
+	}
+}
+
+// Observe flushed file `f`.
+func (h *histograms) observe(f *Flush) {
+	h.Lock()
+	defer h.Unlock()
+	h.sizes.observe(float64(f.Bytes))
+	h.ages.observe(f.Age.Seconds())
+}
+
+// Histograms returns the distribution of file sizes in bytes
+// and file ages in seconds at flush time.
+func (b *Buffer) Histograms() (sizes, ages Histogram) {
+	b.histograms.Lock()
+	defer b.histograms.Unlock()
+	return b.histograms.sizes.copy(), b.histograms.ages.copy()
+}
+
+// WritePrometheus writes the file histograms in the Prometheus text format.
+func (b *Buffer) WritePrometheus(w io.Writer) error {
+	sizes, ages := b.Histograms()
+	labels := fmt.Sprintf("path=%q", b.path)
+
+	err := sizes.write(w, "buffer_file_bytes", "Size of flushed files in bytes.", labels)
+	if err != nil {
+		return err
+	}
+
+	return ages.write(w, "buffer_file_age_seconds", "Age of flushed files in seconds.", labels)
+}
@@ -0,0 +1,43 @@
+package buffer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test file histograms.
+func TestBuffer_Histograms(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1,
+		SizeBuckets: []float64{5, 10},
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+	b.Write([]byte("hello world"))
+	b.WriteKeyed("tobi", []byte("hi"))
+
+	sizes, ages := b.Histograms()
+	assert.Equal(t, []int64{2, 0, 1}, sizes.Counts)
+	assert.Equal(t, int64(3), sizes.Count)
+	assert.Equal(t, float64(18), sizes.Sum)
+	assert.Equal(t, int64(3), ages.Counts[0])
+
+	var buf bytes.Buffer
+	err = b.WritePrometheus(&buf)
+	assert.Equal(t, nil, err)
+
+	out := buf.String()
+	assert.Equal(t, true, strings.Contains(out, `buffer_file_bytes_bucket{path="/tmp/buffer",le="10"} 2`))
+	assert.Equal(t, true, strings.Contains(out, `buffer_file_bytes_bucket{path="/tmp/buffer",le="+Inf"} 3`))
+	assert.Equal(t, true, strings.Contains(out, `buffer_file_age_seconds_count{path="/tmp/buffer"} 3`))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
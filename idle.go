@@ -0,0 +1,34 @@
+package buffer
+
+import "time"
+
+// Reset the idle timer after a write.
+func (b *Buffer) touch() {
+	b.last = time.Now()
+
+	if b.idle == nil {
+		b.idle = time.AfterFunc(b.FlushIdle, b.idled)
+		return
+	}
+
+	b.idle.Reset(b.FlushIdle)
+}
+
+// Flush when no write has arrived for FlushIdle.
+func (b *Buffer) idled() {
+	b.label("idle")
+
+	b.Lock()
+	defer b.Unlock()
+
+	if b.idle == nil {
+		return
+	}
+
+	if wait := b.FlushIdle - time.Since(b.last); wait > 0 {
+		b.idle.Reset(wait)
+		return
+	}
+
+	b.error(b.attempt(func() error { return b.flush(Idle) }))
+}
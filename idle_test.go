@@ -0,0 +1,37 @@
+package buffer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test flushing after writes go idle.
+func TestBuffer_Write_FlushOnIdle(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:     make(chan *Flush, 100),
+		FlushIdle: 100 * time.Millisecond,
+	})
+
+	assert.Equal(t, nil, err)
+
+	for i := 0; i < 5; i++ {
+		b.Write([]byte("hello\n"))
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	select {
+	case <-b.Queue:
+		t.Fatal("flushed mid-burst")
+	default:
+	}
+
+	flush := <-b.Queue
+	assert.Equal(t, int64(5), flush.Writes)
+	assert.Equal(t, Idle, flush.Reason)
+	assert.Equal(t, true, time.Since(flush.First) >= 300*time.Millisecond)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
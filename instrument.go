@@ -0,0 +1,16 @@
+package buffer
+
+import "time"
+
+// Instrument observes buffer operations for metrics and tracing.
+type Instrument interface {
+	// Wrote is called after each Write with the bytes written.
+	Wrote(n int, d time.Duration, err error)
+
+	// Flushing is called when a flush starts, returning a function
+	// called with the resulting flush, which may be nil on error.
+	Flushing(reason Reason) func(f *Flush, err error)
+
+	// Acked is called on Ack with the delivery latency of the flush.
+	Acked(f *Flush, d time.Duration)
+}
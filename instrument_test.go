@@ -0,0 +1,63 @@
+package buffer
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// Recording instrument.
+type recorder struct {
+	sync.Mutex
+	writes  int
+	flushes []*Flush
+	acks    int
+}
+
+func (r *recorder) Wrote(n int, d time.Duration, err error) {
+	r.Lock()
+	defer r.Unlock()
+	r.writes++
+}
+
+func (r *recorder) Flushing(reason Reason) func(*Flush, error) {
+	return func(f *Flush, err error) {
+		r.Lock()
+		defer r.Unlock()
+		r.flushes = append(r.flushes, f)
+	}
+}
+
+func (r *recorder) Acked(f *Flush, d time.Duration) {
+	r.Lock()
+	defer r.Unlock()
+	r.acks++
+}
+
+// Test instrumentation.
+func TestBuffer_Instrument(t *testing.T) {
+	r := &recorder{}
+
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 2,
+		Instrument:  r,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+	b.Write([]byte("world"))
+	b.Ack(<-b.Queue)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	assert.Equal(t, 2, r.writes)
+	assert.Equal(t, 1, len(r.flushes))
+	assert.Equal(t, Writes, r.flushes[0].Reason)
+	assert.Equal(t, 1, r.acks)
+}
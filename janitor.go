@@ -0,0 +1,49 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Remove zero-byte files older than Config.RemoveStale from the spool
+// directory, left behind by runs which crashed before writing.
+func (b *Buffer) clean() error {
+	dir := b.SpoolDir
+	if dir == "" {
+		dir = filepath.Dir(b.path)
+	}
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	prefix := filepath.Base(b.path) + "."
+	cutoff := time.Now().Add(-b.RemoveStale)
+
+	for _, info := range infos {
+		switch {
+		case !info.Mode().IsRegular():
+			continue
+		case info.Size() != 0:
+			continue
+		case !strings.HasPrefix(info.Name(), prefix):
+			continue
+		case info.ModTime().After(cutoff):
+			continue
+		}
+
+		path := filepath.Join(dir, info.Name())
+		b.log(1, "removing stale %q", path)
+
+		err := b.evict(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test removal of stale zero-byte files.
+func TestBuffer_RemoveStale(t *testing.T) {
+	os.RemoveAll("/tmp/buffer-stale")
+	os.MkdirAll("/tmp/buffer-stale", 0755)
+
+	old := time.Now().Add(-time.Hour)
+
+	ioutil.WriteFile("/tmp/buffer-stale/buffer.1.1.1", nil, 0644)
+	os.Chtimes("/tmp/buffer-stale/buffer.1.1.1", old, old)
+
+	ioutil.WriteFile("/tmp/buffer-stale/buffer.1.1.2", []byte("hello"), 0644)
+	os.Chtimes("/tmp/buffer-stale/buffer.1.1.2", old, old)
+
+	ioutil.WriteFile("/tmp/buffer-stale/buffer.1.1.3", nil, 0644)
+
+	b, err := New("/tmp/buffer-stale/buffer", &Config{
+		FlushWrites: 10,
+		RemoveStale: time.Minute,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	_, err = os.Stat("/tmp/buffer-stale/buffer.1.1.1")
+	assert.Equal(t, true, os.IsNotExist(err))
+
+	_, err = os.Stat("/tmp/buffer-stale/buffer.1.1.2")
+	assert.Equal(t, nil, err)
+
+	_, err = os.Stat("/tmp/buffer-stale/buffer.1.1.3")
+	assert.Equal(t, nil, err)
+
+	path := b.file.Name()
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	_, err = os.Stat(path)
+	assert.Equal(t, true, os.IsNotExist(err))
+}
@@ -0,0 +1,37 @@
+package buffer
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+)
+
+// Encoding buffers reused across WriteJSON calls.
+var encodings = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// WriteJSON writes `v` as a JSON Lines record, marshaled as with
+// json.Marshal without escaping HTML, and followed by a newline unless
+// Config.Delimiter is set.
+func (b *Buffer) WriteJSON(v interface{}) error {
+	buf := encodings.Get().(*bytes.Buffer)
+	defer encodings.Put(buf)
+	buf.Reset()
+
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+
+	err := enc.Encode(v)
+	if err != nil {
+		return err
+	}
+
+	data := buf.Bytes()
+	if len(b.Delimiter) != 0 {
+		data = data[:len(data)-1]
+	}
+
+	_, err = b.Write(data)
+	return err
+}
@@ -0,0 +1,39 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test writing JSON Lines records.
+func TestBuffer_WriteJSON(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 2,
+	})
+
+	assert.Equal(t, nil, err)
+
+	err = b.WriteJSON(map[string]interface{}{"name": "<tobi>"})
+	assert.Equal(t, nil, err)
+
+	err = b.WriteJSON(struct {
+		Age int `json:"age"`
+	}{3})
+	assert.Equal(t, nil, err)
+
+	err = b.WriteJSON(make(chan int))
+	assert.NotEqual(t, nil, err)
+
+	flush := <-b.Queue
+	assert.Equal(t, int64(28), flush.Bytes)
+
+	data, err := ioutil.ReadFile(flush.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "{\"name\":\"<tobi>\"}\n{\"age\":3}\n", string(data))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
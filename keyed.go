@@ -0,0 +1,78 @@
+package buffer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WriteKeyed writes `data` to the partition for `key`. Each key is
+// buffered to its own files, named with the key appended to the
+// base path, and flushed independently with the key set on the Flush.
+func (b *Buffer) WriteKeyed(key string, data []byte) (int, error) {
+	k, err := b.partition(key)
+	if err != nil {
+		return 0, err
+	}
+
+	return k.Write(data)
+}
+
+// Keys returns the partition keys written so far.
+func (b *Buffer) Keys() []string {
+	b.RLock()
+	defer b.RUnlock()
+
+	keys := make([]string, 0, len(b.keys))
+	for key := range b.keys {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// Partition buffer for `key`, created on first use.
+func (b *Buffer) partition(key string) (*Buffer, error) {
+	b.RLock()
+	k, ok := b.keys[key]
+	b.RUnlock()
+
+	if ok {
+		return k, nil
+	}
+
+	if key == "" || strings.ContainsAny(key, `/\`) {
+		return nil, fmt.Errorf("invalid partition key %q", key)
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	if k, ok := b.keys[key]; ok {
+		return k, nil
+	}
+
+	if b.draining {
+		return nil, ErrDraining
+	}
+
+	if b.stopped {
+		return nil, ErrClosed
+	}
+
+	b.log(1, "creating partition %q", key)
+	k, err := newBuffer(b.path, b.Config, b, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.keys == nil {
+		b.keys = make(map[string]*Buffer)
+	}
+
+	k.Lock()
+	k.paused = b.paused
+	k.Unlock()
+
+	b.keys[key] = k
+	return k, nil
+}
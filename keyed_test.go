@@ -0,0 +1,57 @@
+package buffer
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test keyed writes are partitioned.
+func TestBuffer_WriteKeyed(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 2,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	_, err = b.WriteKeyed("tobi", []byte("hello"))
+	assert.Equal(t, nil, err)
+
+	_, err = b.WriteKeyed("loki", []byte("hello"))
+	assert.Equal(t, nil, err)
+
+	_, err = b.WriteKeyed("tobi", []byte("world"))
+	assert.Equal(t, nil, err)
+
+	flush := <-b.Queue
+	assert.Equal(t, "tobi", flush.Key)
+	assert.Equal(t, int64(2), flush.Writes)
+	assert.Equal(t, true, strings.HasPrefix(flush.Path, "/tmp/buffer.tobi."))
+
+	keys := b.Keys()
+	sort.Strings(keys)
+	assert.Equal(t, []string{"loki", "tobi"}, keys)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	flush = <-b.Queue
+	assert.Equal(t, "loki", flush.Key)
+	assert.Equal(t, int64(1), flush.Writes)
+}
+
+// Test invalid partition keys.
+func TestBuffer_WriteKeyed_Invalid(t *testing.T) {
+	b, err := New("/tmp/buffer", config)
+	assert.Equal(t, nil, err)
+
+	_, err = b.WriteKeyed("../tobi", []byte("hello"))
+	assert.Equal(t, `invalid partition key "../tobi"`, err.Error())
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
@@ -0,0 +1,45 @@
+package buffer
+
+import "log"
+
+// Logger is a leveled logger with key-value fields, satisfied
+// by *slog.Logger and the adapters in this package, see Config.Log.
+type Logger interface {
+	Debug(msg string, keyvals ...interface{})
+	Info(msg string, keyvals ...interface{})
+	Error(msg string, keyvals ...interface{})
+}
+
+// Standard library logger adapter.
+type stdLogger struct {
+	*log.Logger
+	verbosity int
+}
+
+// StdLogger returns a Logger writing to `l`, logging errors and info at
+// verbosity 1 and above, and debug at 2 and above. Fields are omitted,
+// as the default logger includes the buffer in its prefix.
+func StdLogger(l *log.Logger, verbosity int) Logger {
+	return &stdLogger{Logger: l, verbosity: verbosity}
+}
+
+// Debug implements Logger.
+func (l *stdLogger) Debug(msg string, keyvals ...interface{}) {
+	if l.verbosity >= 2 {
+		l.Print(msg)
+	}
+}
+
+// Info implements Logger.
+func (l *stdLogger) Info(msg string, keyvals ...interface{}) {
+	if l.verbosity >= 1 {
+		l.Print(msg)
+	}
+}
+
+// Error implements Logger.
+func (l *stdLogger) Error(msg string, keyvals ...interface{}) {
+	if l.verbosity >= 1 {
+		l.Print("error: " + msg)
+	}
+}
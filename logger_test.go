@@ -0,0 +1,61 @@
+package buffer
+
+import (
+	"bytes"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test logging with the standard library logger.
+func TestBuffer_Logger(t *testing.T) {
+	var buf bytes.Buffer
+
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1,
+		Verbosity:   1,
+		Logger:      log.New(&buf, "buffer ", 0),
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+	<-b.Queue
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	out := buf.String()
+	assert.Equal(t, true, strings.Contains(out, "buffer flushing (writes)\n"))
+	assert.Equal(t, false, strings.Contains(out, "reset state"))
+}
+
+// Test structured logging with slog.
+func TestBuffer_Logger_Slog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1,
+		Log:         logger,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.WriteKeyed("tobi", []byte("hello"))
+	<-b.Queue
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	out := buf.String()
+	assert.Equal(t, true, strings.Contains(out, `level=INFO msg="flushing (writes)"`))
+	assert.Equal(t, true, strings.Contains(out, `path=/tmp/buffer key=tobi`))
+	assert.Equal(t, true, strings.Contains(out, `level=DEBUG msg="reset state"`))
+	assert.Equal(t, false, strings.Contains(out, `write hello`))
+}
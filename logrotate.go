@@ -0,0 +1,59 @@
+package buffer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LogrotateFilename is the Filename template of Config.Logrotate, naming
+// files like logrotate's dateext, such as "app-20240131-103015.log".
+// Set Staging, such as ".tmp", so only flushed files end in ".log".
+const LogrotateFilename = `{{.Path}}{{with .Key}}-{{.}}{{end}}-{{.Opened.Format "20060102-150405"}}.log`
+
+// Filename template, defaulting to LogrotateFilename in logrotate mode.
+func (b *Buffer) filename() string {
+	if b.Filename == "" && b.Logrotate {
+		return LogrotateFilename
+	}
+
+	return b.Filename
+}
+
+// Unique `path` for files opened within the same second, numbering them
+// before the extension as "app-20240131-103015-1.log".
+func (b *Buffer) unique(path string) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+
+	for n := 1; exists(path+b.Staging) || exists(b.closedPath(path)); n++ {
+		path = fmt.Sprintf("%s-%d%s", base, n, ext)
+	}
+
+	return path
+}
+
+// Whether a file exists at `path`.
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// Flush on rotation signals until closed.
+func (b *Buffer) rotator() {
+	b.label("signal")
+
+	for {
+		select {
+		case <-b.quit:
+			return
+		case sig := <-b.signals:
+			b.log(1, "received %s", sig)
+			_, err := b.flushWith(Signaled)
+			if err != ErrClosed {
+				b.error(err)
+			}
+		}
+	}
+}
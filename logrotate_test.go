@@ -0,0 +1,44 @@
+//go:build !windows
+
+package buffer
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"syscall"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test logrotate naming and rotation on SIGUSR1.
+func TestBuffer_Logrotate(t *testing.T) {
+	dir := "/tmp/buffer-logrotate"
+	os.RemoveAll(dir)
+
+	b, err := New(filepath.Join(dir, "app"), &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1000,
+		Staging:     ".tmp",
+		Logrotate:   true,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello\n"))
+	err = syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	assert.Equal(t, nil, err)
+
+	flush := <-b.Queue
+	assert.Equal(t, Signaled, flush.Reason)
+	assert.Equal(t, true, regexp.MustCompile(`^app-\d{8}-\d{6}(-\d+)?\.log$`).MatchString(filepath.Base(flush.Path)))
+
+	b.Write([]byte("world\n"))
+	second, err := b.FlushFile()
+	assert.Equal(t, nil, err)
+	assert.NotEqual(t, flush.Path, second.Path)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
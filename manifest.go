@@ -0,0 +1,222 @@
+package buffer
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// Manifest events.
+const (
+	flushed = "flush"
+	acked   = "ack"
+	nacked  = "nack"
+	dropped = "drop"
+	evicted = "evict"
+	dead    = "dead_letter"
+	merged  = "compact"
+)
+
+// Manifest entry.
+type entry struct {
+	Event string    `json:"event"`
+	Path  string    `json:"path"`
+	Time  time.Time `json:"time"`
+	Error string    `json:"error,omitempty"`
+	Flush *Flush    `json:"flush,omitempty"`
+}
+
+// Append-only journal of flushed files and their delivery state, shared
+// by a buffer and its partitions.
+type manifest struct {
+	sync.Mutex
+	path string
+	mode os.FileMode
+	file *os.File
+	sync bool
+}
+
+// Open the manifest at `path`, returning the files pending delivery in
+// the order they were flushed. The journal is compacted to those files,
+// and appends are fsynced when `durable` is set.
+func openManifest(path string, mode os.FileMode, durable bool) (*manifest, []*Flush, error) {
+	pending, err := replay(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if mode == 0 {
+		mode = 0666
+	}
+
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	m := &manifest{path: path, mode: mode, file: f}
+	for _, p := range pending {
+		err := m.append(entry{Event: flushed, Path: p.Path, Time: p.Closed, Flush: p})
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+	}
+
+	if durable {
+		err = f.Sync()
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+	}
+
+	err = f.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = os.Rename(tmp, path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if durable {
+		err = syncDir(path)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	m.file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, mode)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	m.sync = durable
+	return m, pending, nil
+}
+
+// Replay the journal at `path`, returning flushed files which were not
+// acked or dropped and still exist. A torn final entry is ignored.
+func replay(path string) ([]*Flush, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var order []string
+	files := make(map[string]*Flush)
+
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		var e entry
+		if json.Unmarshal(s.Bytes(), &e) != nil {
+			continue
+		}
+
+		switch e.Event {
+		case flushed:
+			if e.Flush == nil {
+				continue
+			}
+			if _, ok := files[e.Path]; !ok {
+				order = append(order, e.Path)
+			}
+			files[e.Path] = e.Flush
+		case nacked:
+			if p, ok := files[e.Path]; ok {
+				p.Attempts = append(p.Attempts, Attempt{Time: e.Time, Error: e.Error})
+			}
+		case acked, dropped, evicted, dead, merged:
+			delete(files, e.Path)
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	var pending []*Flush
+	for _, path := range order {
+		p, ok := files[path]
+		if !ok {
+			continue
+		}
+
+		_, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+
+		pending = append(pending, p)
+	}
+
+	return pending, nil
+}
+
+// Append entry `e`.
+func (m *manifest) append(e entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	_, err = m.file.Write(append(line, '\n'))
+	if err != nil || !m.sync {
+		return err
+	}
+
+	return m.file.Sync()
+}
+
+// Reopen the journal for appends after close.
+func (m *manifest) reopen() error {
+	m.Lock()
+	defer m.Unlock()
+
+	f, err := os.OpenFile(m.path, os.O_WRONLY|os.O_APPEND, m.mode)
+	if err != nil {
+		return err
+	}
+
+	m.file = f
+	return nil
+}
+
+// Close the journal.
+func (m *manifest) close() error {
+	m.Lock()
+	defer m.Unlock()
+	return m.file.Close()
+}
+
+// Journal `event` for `f`, a no-op without a manifest.
+func (b *Buffer) journal(event string, f *Flush, err error) error {
+	if b.manifest == nil {
+		return nil
+	}
+
+	e := entry{Event: event, Path: f.Path, Time: time.Now()}
+
+	switch event {
+	case flushed:
+		e.Flush = f
+	case nacked:
+		e.Error = err.Error()
+	}
+
+	return b.manifest.append(e)
+}
@@ -0,0 +1,64 @@
+package buffer
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test replaying the manifest on startup.
+func TestBuffer_Manifest(t *testing.T) {
+	os.RemoveAll("/tmp/buffer-manifest")
+	os.Remove("/tmp/buffer.manifest")
+
+	config := &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1,
+		OutDir:      "/tmp/buffer-manifest",
+		Manifest:    "/tmp/buffer.manifest",
+		Verbosity:   0,
+	}
+
+	b, err := New("/tmp/buffer", config)
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+	b.Write([]byte("world"))
+	b.WriteKeyed("tobi", []byte("hello"))
+
+	first := <-b.Queue
+	second := <-b.Queue
+	third := <-b.Queue
+
+	b.Ack(first)
+	b.Nack(second, errors.New("timeout"))
+	os.Remove(third.Path)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	config.Queue = make(chan *Flush, 100)
+	b, err = New("/tmp/buffer", config)
+	assert.Equal(t, nil, err)
+
+	f := <-b.Queue
+	assert.Equal(t, second.Path, f.Path)
+	assert.Equal(t, Writes, f.Reason)
+	assert.Equal(t, "timeout", f.LastError())
+	assert.Equal(t, 0, len(b.Queue))
+
+	b.Ack(f)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	config.Queue = make(chan *Flush, 100)
+	b, err = New("/tmp/buffer", config)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, len(b.Queue))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
@@ -0,0 +1,136 @@
+package buffer
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"time"
+)
+
+// Writer holding the data of the current file in memory while it is
+// within Config.MemoryBytes and MemoryAge, creating the file at `path`
+// and writing the data out once either is exceeded.
+type memory struct {
+	b     *Buffer
+	w     io.Writer
+	path  string
+	data  bytes.Buffer
+	out   bool
+	timer *time.Timer
+}
+
+// New memory writer for the file at `path`, armed to write out after
+// MemoryAge.
+func newMemory(b *Buffer, path string) *memory {
+	m := &memory{b: b, path: path}
+	if b.MemoryAge != 0 {
+		m.timer = time.AfterFunc(b.MemoryAge, m.expire)
+	}
+	return m
+}
+
+// Write implements io.Writer.
+func (m *memory) Write(p []byte) (int, error) {
+	if !m.out && m.fits(len(p)) {
+		return m.data.Write(p)
+	}
+
+	if !m.out {
+		err := m.writeOut()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return m.w.Write(p)
+}
+
+// Create the file and write out the data held in memory.
+func (m *memory) writeOut() error {
+	b := m.b
+
+	if b.file == nil {
+		f, err := b.createNew(m.path)
+		if err != nil && b.spill(err) {
+			f, err = m.respill()
+		}
+
+		if err != nil {
+			return err
+		}
+
+		b.file = f
+		m.w = b.retried(f)
+	}
+
+	b.log(2, "writing out %d bytes held in memory to %q", m.data.Len(), m.path)
+	n, err := m.w.Write(m.data.Bytes())
+	m.data.Next(n)
+	if err != nil {
+		return err
+	}
+
+	m.out = true
+	m.stop()
+	return nil
+}
+
+// Create the file in SpillDir instead.
+func (m *memory) respill() (*os.File, error) {
+	b := m.b
+
+	path, err := b.nameOf(b.seq)
+	if err != nil {
+		return nil, err
+	}
+
+	b.states.move(m.path, path, Writing, nil)
+	b.staged = path
+	m.path = path
+	return b.createNew(path)
+}
+
+// Write out the data once open for MemoryAge.
+func (m *memory) expire() {
+	b := m.b
+	b.label("memory-age")
+
+	b.Lock()
+	defer b.Unlock()
+
+	if b.stopped || b.memory != m || m.out {
+		return
+	}
+
+	b.error(m.writeOut())
+}
+
+// Stop the MemoryAge timer.
+func (m *memory) stop() {
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+}
+
+// Whether `n` more bytes may be held.
+func (m *memory) fits(n int) bool {
+	if int64(m.data.Len()+n) > m.b.MemoryBytes {
+		return false
+	}
+
+	return m.b.MemoryAge == 0 || time.Since(m.b.opened) < m.b.MemoryAge
+}
+
+// Whether the data of the current file is held in memory.
+func (b *Buffer) inMemory() bool {
+	return b.memory != nil && !b.memory.out
+}
+
+// Data of the current file held in memory, nil when written out.
+func (b *Buffer) held() []byte {
+	if !b.inMemory() {
+		return nil
+	}
+
+	return b.memory.data.Bytes()
+}
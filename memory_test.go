@@ -0,0 +1,174 @@
+package buffer
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test publishing small files from memory.
+func TestBuffer_MemoryBytes(t *testing.T) {
+	os.RemoveAll("/tmp/buffer-memory")
+
+	b, err := New("/tmp/buffer-memory/buffer", &Config{
+		Queue:       make(chan *Flush, 10),
+		FlushWrites: 2,
+		Delimiter:   []byte("\n"),
+		MemoryBytes: 10,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("a"))
+	b.Write([]byte("b"))
+
+	f := <-b.Queue
+	assert.Equal(t, "a\nb\n", string(f.Data))
+	assert.Equal(t, int64(4), f.Bytes)
+
+	_, err = os.Stat(f.Path)
+	assert.Equal(t, true, os.IsNotExist(err))
+
+	b.Write([]byte("hello"))
+	b.Write([]byte("world"))
+
+	f = <-b.Queue
+	assert.Equal(t, []byte(nil), f.Data)
+
+	data, err := ioutil.ReadFile(f.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "hello\nworld\n", string(data))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test options which need files on disk.
+func TestBuffer_MemoryBytes_Validate(t *testing.T) {
+	_, err := New("/tmp/buffer", &Config{
+		FlushWrites: 1,
+		MemoryBytes: 10,
+		MetaFile:    true,
+	})
+
+	assert.Equal(t, "files held in memory cannot be given sidecars, metafiles, manifests or done directories", err.Error())
+}
+
+// Test files held in memory are never created.
+func TestBuffer_MemoryBytes_noFile(t *testing.T) {
+	os.RemoveAll("/tmp/buffer-memory")
+
+	b, err := New("/tmp/buffer-memory/buffer", &Config{
+		Queue:       make(chan *Flush, 10),
+		FlushWrites: 2,
+		Delimiter:   []byte("\n"),
+		MemoryBytes: 10,
+		Durability:  PowerSafe,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("a"))
+
+	files, _ := ioutil.ReadDir("/tmp/buffer-memory")
+	assert.Equal(t, 0, len(files))
+
+	b.Write([]byte("b"))
+
+	f := <-b.Queue
+	assert.Equal(t, "a\nb\n", string(f.Data))
+
+	files, _ = ioutil.ReadDir("/tmp/buffer-memory")
+	assert.Equal(t, 0, len(files))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test writing out files held in memory after MemoryAge.
+func TestBuffer_MemoryAge(t *testing.T) {
+	os.RemoveAll("/tmp/buffer-memory")
+
+	b, err := New("/tmp/buffer-memory/buffer", &Config{
+		Queue:       make(chan *Flush, 10),
+		FlushWrites: 2,
+		Delimiter:   []byte("\n"),
+		MemoryBytes: 10,
+		MemoryAge:   50 * time.Millisecond,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("a"))
+	time.Sleep(150 * time.Millisecond)
+
+	files, _ := ioutil.ReadDir("/tmp/buffer-memory")
+	assert.Equal(t, 1, len(files))
+
+	b.Write([]byte("b"))
+
+	f := <-b.Queue
+	assert.Equal(t, []byte(nil), f.Data)
+
+	data, err := ioutil.ReadFile(f.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "a\nb\n", string(data))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test reading files held in memory.
+func TestBuffer_MemoryBytes_read(t *testing.T) {
+	b, err := New("/tmp/buffer-memory/buffer", &Config{
+		Queue:       make(chan *Flush, 10),
+		FlushWrites: 2,
+		Delimiter:   []byte("\n"),
+		MemoryBytes: 100,
+		Codec:       "gzip",
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("a"))
+	b.Write([]byte("b"))
+
+	f := <-b.Queue
+
+	r, err := OpenFlush(f, nil)
+	assert.Equal(t, nil, err)
+	data, err := ioutil.ReadAll(r)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "a\nb\n", string(data))
+	r.Close()
+
+	s, err := RecordsOf(f, &Config{Delimiter: []byte("\n")})
+	assert.Equal(t, nil, err)
+	var records []string
+	for s.Scan() {
+		records = append(records, string(s.Record()))
+	}
+	assert.Equal(t, []string{"a", "b"}, records)
+
+	r, err = NewThrottle(0, 1).Open(context.Background(), f)
+	assert.Equal(t, nil, err)
+	data, err = ioutil.ReadAll(r)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, f.Data, data)
+	r.Close()
+
+	c, err := Convert("gzip", f)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, c.Data != nil)
+	assert.Equal(t, int64(len(c.Data)), c.Bytes)
+
+	_, err = b.Compact(context.Background(), 100, 0)
+	assert.Equal(t, "files held in memory cannot be compacted", err.Error())
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
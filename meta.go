@@ -0,0 +1,81 @@
+package buffer
+
+import (
+	"sort"
+)
+
+// WriteWithMeta writes `data` annotated with `meta`, such as the source,
+// level or tenant of the record. Annotations are encoded into the record
+// by Config.Envelope, and aggregated into Flush.Meta when Config.Meta is
+// set. Records without annotations are written as is.
+func (b *Buffer) WriteWithMeta(data []byte, meta map[string]string) (int, error) {
+	if len(meta) == 0 {
+		return b.Write(data)
+	}
+
+	record, err := b.envelope().Encode(data, meta)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := b.submit(record, meta)
+	if n > len(data) {
+		n = len(data)
+	}
+
+	return n, err
+}
+
+// ParseMeta splits a record written with WriteWithMeta and the default
+// envelope into its annotations and data. Records without annotations
+// are returned as is.
+func ParseMeta(record []byte) (map[string]string, []byte, error) {
+	return QueryEnvelope{}.Decode(record)
+}
+
+// Envelope encoding.
+func (b *Buffer) envelope() Envelope {
+	if b.Envelope == nil {
+		return QueryEnvelope{}
+	}
+
+	return b.Envelope
+}
+
+// Record annotations for the open file.
+func (b *Buffer) annotate(meta map[string]string) {
+	if !b.Meta || len(meta) == 0 {
+		return
+	}
+
+	if b.meta == nil {
+		b.meta = make(map[string]map[string]bool)
+	}
+
+	for k, v := range meta {
+		if b.meta[k] == nil {
+			b.meta[k] = make(map[string]bool)
+		}
+		b.meta[k][v] = true
+	}
+}
+
+// Distinct annotation values of the open file, resetting them.
+func (b *Buffer) aggregated() map[string][]string {
+	if len(b.meta) == 0 {
+		return nil
+	}
+
+	m := make(map[string][]string, len(b.meta))
+	for k, set := range b.meta {
+		values := make([]string, 0, len(set))
+		for v := range set {
+			values = append(values, v)
+		}
+		sort.Strings(values)
+		m[k] = values
+	}
+
+	b.meta = nil
+	return m
+}
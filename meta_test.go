@@ -0,0 +1,101 @@
+package buffer
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test writing annotated records.
+func TestBuffer_WriteWithMeta(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 3,
+		Meta:        true,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	n, err := b.WriteWithMeta([]byte("hello\n"), map[string]string{"level": "info", "tenant": "tobi"})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 6, n)
+
+	b.WriteWithMeta([]byte("world\n"), map[string]string{"level": "error", "tenant": "tobi"})
+	b.Write([]byte("plain\n"))
+
+	f := <-b.Queue
+	assert.Equal(t, []string{"error", "info"}, f.Meta["level"])
+	assert.Equal(t, []string{"tobi"}, f.Meta["tenant"])
+
+	file, err := os.Open(f.Path)
+	assert.Equal(t, nil, err)
+	defer file.Close()
+
+	s := bufio.NewScanner(file)
+	var records []string
+	var levels []string
+	for s.Scan() {
+		line := s.Bytes()
+		if len(line) > 0 && line[0] == MetaPrefix {
+			meta, _, err := ParseMeta(append(line, '\n'))
+			assert.Equal(t, nil, err)
+			levels = append(levels, meta["level"])
+			continue
+		}
+		records = append(records, string(line))
+	}
+
+	assert.Equal(t, []string{"hello", "world", "plain"}, records)
+	assert.Equal(t, []string{"info", "error"}, levels)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test parsing annotated records.
+func TestParseMeta(t *testing.T) {
+	record, err := QueryEnvelope{}.Encode([]byte("hello"), map[string]string{"source": "a b&c"})
+	assert.Equal(t, nil, err)
+
+	meta, data, err := ParseMeta(record)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, map[string]string{"source": "a b&c"}, meta)
+	assert.Equal(t, "hello", string(data))
+
+	meta, data, err = ParseMeta([]byte("hello"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, len(meta))
+	assert.Equal(t, "hello", string(data))
+}
+
+// Test writing annotated records with a custom envelope.
+func TestBuffer_WriteWithMeta_envelope(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1,
+		Envelope:    JSONEnvelope{},
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	_, err = b.WriteWithMeta([]byte("hello"), map[string]string{"level": "info"})
+	assert.Equal(t, nil, err)
+
+	f := <-b.Queue
+	record, err := ioutil.ReadFile(f.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, `{"meta":{"level":"info"},"data":"hello"}`+"\n", string(record))
+
+	meta, data, err := JSONEnvelope{}.Decode(record)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "info", meta["level"])
+	assert.Equal(t, "hello", string(data))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
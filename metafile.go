@@ -0,0 +1,20 @@
+package buffer
+
+import "encoding/json"
+
+// MetaFileSuffix is appended to the path of flushed files for the
+// metadata written when Config.MetaFile is set.
+const MetaFileSuffix = ".meta.json"
+
+// Write `f` as JSON to a sidecar file for consumers watching the directory.
+func (b *Buffer) metafile(f *Flush) error {
+	path := f.Path + MetaFileSuffix
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	b.log(2, "writing metadata %q", path)
+	return b.writeFile(path, append(data, '\n'))
+}
@@ -0,0 +1,40 @@
+package buffer
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test metadata files of flushed files.
+func TestBuffer_MetaFile(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1,
+		Checksum:    "sha256",
+		MetaFile:    true,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+
+	f := <-b.Queue
+
+	data, err := ioutil.ReadFile(f.Path + ".meta.json")
+	assert.Equal(t, nil, err)
+
+	var meta Flush
+	err = json.Unmarshal(data, &meta)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, Writes, meta.Reason)
+	assert.Equal(t, f.Path, meta.Path)
+	assert.Equal(t, int64(1), meta.Writes)
+	assert.Equal(t, f.Checksum, meta.Checksum)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
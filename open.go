@@ -0,0 +1,169 @@
+package buffer
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Reader of a flushed file, closing its decompressor and the file.
+type reader struct {
+	io.Reader
+	closers []io.Closer
+	trailer []byte
+	crc     hash.Hash32
+}
+
+// Close implements io.Closer.
+func (r *reader) Close() (err error) {
+	for i := len(r.closers) - 1; i >= 0; i-- {
+		if e := r.closers[i].Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	return err
+}
+
+// Open returns a reader of the records in the flushed file at `path`,
+// reversing the buffer's write pipeline as detected from the file itself:
+// trailers are stripped, and gzip and zstd data decompressed, loading
+// dictionaries from the file's directory. Encrypted files require
+// OpenEncrypted. Framing, such as Config.Delimiter, is left in place.
+func Open(path string) (io.ReadCloser, error) {
+	return OpenEncrypted(path, nil)
+}
+
+// OpenEncrypted opens like Open, decrypting files written with a
+// KeyProvider by looking up their key with `p`.
+func OpenEncrypted(path string, p KeyProvider) (io.ReadCloser, error) {
+	return OpenFlush(&Flush{Path: path}, p)
+}
+
+// OpenFlush opens the file of `f` like OpenEncrypted, reading Flush.Data
+// of files held in memory.
+func OpenFlush(f *Flush, p KeyProvider) (io.ReadCloser, error) {
+	src, size, err := openData(f)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := unwrap(src, size, f.Path, p)
+	if err != nil {
+		src.Close()
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Data of a flushed file, on disk or held in memory.
+type data interface {
+	io.Reader
+	io.ReaderAt
+	io.Closer
+}
+
+// Data held in memory.
+type held struct {
+	*bytes.Reader
+}
+
+// Close implements io.Closer.
+func (held) Close() error {
+	return nil
+}
+
+// Open the data of `f` and its size, from Flush.Data when held in memory.
+func openData(f *Flush) (data, int64, error) {
+	if f.Data != nil {
+		return held{bytes.NewReader(f.Data)}, int64(len(f.Data)), nil
+	}
+
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+
+	return file, info.Size(), nil
+}
+
+// Reader of the `size` bytes of data `f` of the file at `path`, looking
+// up keys with `p` and dictionaries in its directory.
+func unwrap(f data, size int64, path string, p KeyProvider) (*reader, error) {
+	r := &reader{closers: []io.Closer{f}}
+	dir := filepath.Dir(path)
+
+	var err error
+	var src io.Reader = f
+	if trailed(f, size) {
+		r.trailer = make([]byte, TrailerSize)
+		_, err = f.ReadAt(r.trailer, size-TrailerSize)
+		if err != nil {
+			return nil, err
+		}
+
+		r.crc = crc32.New(castagnoli)
+		src = io.TeeReader(io.LimitReader(f, size-TrailerSize), r.crc)
+	}
+
+	br := bufio.NewReader(src)
+	head, _ := br.Peek(len(magic))
+	if bytes.Equal(head, magic) {
+		if p == nil {
+			return nil, fmt.Errorf("encrypted file %q requires a key provider", path)
+		}
+
+		d, err := Decrypt(br, p)
+		if err != nil {
+			return nil, err
+		}
+
+		br = bufio.NewReader(d)
+	}
+
+	head, _ = br.Peek(len(zstdHead))
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		z, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		r.Reader = z
+		r.closers = append(r.closers, z)
+	case bytes.HasPrefix(head, zstdHead):
+		z, err := Decompress(br, dir)
+		if err != nil {
+			return nil, err
+		}
+		r.Reader = z
+		r.closers = append(r.closers, z)
+	default:
+		r.Reader = br
+	}
+
+	return r, nil
+}
+
+// Whether file `f` of `size` bytes ends with a trailer.
+func trailed(f io.ReaderAt, size int64) bool {
+	if size < TrailerSize {
+		return false
+	}
+
+	tag := make([]byte, len(trailerMagic))
+	_, err := f.ReadAt(tag, size-TrailerSize)
+	return err == nil && bytes.Equal(tag, trailerMagic)
+}
@@ -0,0 +1,54 @@
+package buffer
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test reading flushed files back through the write pipeline.
+func TestOpen(t *testing.T) {
+	provider := keys{"tenant-": bytes.Repeat([]byte("r"), 32)}
+
+	configs := []*Config{
+		{},
+		{Codec: "gzip"},
+		{Codec: "zstd"},
+		{Trailer: true},
+		{Codec: "gzip", KeyProvider: provider},
+	}
+
+	for _, c := range configs {
+		c.Queue = make(chan *Flush, 100)
+		c.FlushWrites = 2
+
+		b, err := New("/tmp/buffer", c)
+		assert.Equal(t, nil, err)
+
+		_, err = b.Write([]byte("hello\n"))
+		assert.Equal(t, nil, err)
+
+		_, err = b.Write([]byte("world\n"))
+		assert.Equal(t, nil, err)
+
+		flush := <-b.Queue
+
+		_, err = Open(flush.Path)
+		assert.Equal(t, c.KeyProvider != nil, err != nil)
+
+		r, err := OpenEncrypted(flush.Path, provider)
+		assert.Equal(t, nil, err)
+
+		data, err := ioutil.ReadAll(r)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "hello\nworld\n", string(data))
+
+		err = r.Close()
+		assert.Equal(t, nil, err)
+
+		err = b.Close()
+		assert.Equal(t, nil, err)
+	}
+}
@@ -0,0 +1,142 @@
+// Package otelbuffer provides OpenTelemetry metrics and tracing
+// for disk buffers via buffer.Config.Instrument.
+package otelbuffer
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/tj/go-disk-buffer"
+)
+
+// Instrumentation name.
+const name = "github.com/tj/go-disk-buffer"
+
+// Instrument implements buffer.Instrument.
+type Instrument struct {
+	tracer   trace.Tracer
+	writes   metric.Int64Counter
+	bytes    metric.Int64Counter
+	errors   metric.Int64Counter
+	flushes  metric.Int64Counter
+	write    metric.Float64Histogram
+	flush    metric.Float64Histogram
+	delivery metric.Float64Histogram
+}
+
+// New instrument using the given providers, or the global providers when nil.
+func New(tp trace.TracerProvider, mp metric.MeterProvider) (*Instrument, error) {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+
+	if mp == nil {
+		mp = otel.GetMeterProvider()
+	}
+
+	meter := mp.Meter(name)
+	i := &Instrument{tracer: tp.Tracer(name)}
+
+	var err error
+
+	i.writes, err = meter.Int64Counter("buffer.writes", metric.WithDescription("Writes to the buffer"))
+	if err != nil {
+		return nil, err
+	}
+
+	i.bytes, err = meter.Int64Counter("buffer.bytes", metric.WithDescription("Bytes written to the buffer"), metric.WithUnit("By"))
+	if err != nil {
+		return nil, err
+	}
+
+	i.errors, err = meter.Int64Counter("buffer.errors", metric.WithDescription("Failed writes and flushes"))
+	if err != nil {
+		return nil, err
+	}
+
+	i.flushes, err = meter.Int64Counter("buffer.flushes", metric.WithDescription("Flushed files"))
+	if err != nil {
+		return nil, err
+	}
+
+	i.write, err = meter.Float64Histogram("buffer.write.duration", metric.WithDescription("Write duration"), metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+
+	i.flush, err = meter.Float64Histogram("buffer.flush.duration", metric.WithDescription("Flush duration"), metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+
+	i.delivery, err = meter.Float64Histogram("buffer.delivery.duration", metric.WithDescription("Time from first write to ack"), metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+
+	return i, nil
+}
+
+// Wrote implements buffer.Instrument.
+func (i *Instrument) Wrote(n int, d time.Duration, err error) {
+	ctx := context.Background()
+
+	if err != nil {
+		i.errors.Add(ctx, 1, metric.WithAttributes(attribute.String("buffer.op", "write")))
+		return
+	}
+
+	i.writes.Add(ctx, 1)
+	i.bytes.Add(ctx, int64(n))
+	i.write.Record(ctx, d.Seconds())
+}
+
+// Flushing implements buffer.Instrument, recording a span for the flush.
+func (i *Instrument) Flushing(reason buffer.Reason) func(*buffer.Flush, error) {
+	reasonAttr := attribute.String("buffer.reason", string(reason))
+	ctx, span := i.tracer.Start(context.Background(), "buffer.flush", trace.WithAttributes(reasonAttr))
+	start := time.Now()
+
+	return func(f *buffer.Flush, err error) {
+		defer span.End()
+
+		if f != nil {
+			span.SetAttributes(
+				attribute.String("buffer.path", f.Path),
+				attribute.Int64("buffer.writes", f.Writes),
+				attribute.Int64("buffer.bytes", f.Bytes))
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			i.errors.Add(ctx, 1, metric.WithAttributes(attribute.String("buffer.op", "flush")))
+			return
+		}
+
+		i.flushes.Add(ctx, 1, metric.WithAttributes(reasonAttr))
+		i.flush.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(reasonAttr))
+	}
+}
+
+// Acked implements buffer.Instrument, recording a span from the
+// file being closed until its delivery was acknowledged.
+func (i *Instrument) Acked(f *buffer.Flush, d time.Duration) {
+	attrs := []attribute.KeyValue{
+		attribute.String("buffer.path", f.Path),
+		attribute.String("buffer.reason", string(f.Reason)),
+	}
+
+	ctx, span := i.tracer.Start(context.Background(), "buffer.delivery",
+		trace.WithTimestamp(f.Closed),
+		trace.WithAttributes(attrs...))
+
+	span.End()
+	i.delivery.Record(ctx, d.Seconds(), metric.WithAttributes(attrs[1]))
+}
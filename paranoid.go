@@ -0,0 +1,53 @@
+package buffer
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Whether the current file holds records as written, so they can be
+// read back and its size compared with the byte count.
+func (b *Buffer) plain() bool {
+	return b.codec == nil && b.keyID == ""
+}
+
+// Re-read `data` just written at offset `off`, panicking on a mismatch.
+func (b *Buffer) reread(data []byte, off int64) {
+	if !b.plain() || len(data) == 0 {
+		return
+	}
+
+	if b.buf != nil {
+		err := b.buf.Flush()
+		if err != nil {
+			panic(fmt.Sprintf("buffer: flushing %q: %s", b.file.Name(), err))
+		}
+	}
+
+	got := make([]byte, len(data))
+	_, err := b.file.ReadAt(got, off)
+	if err != nil {
+		panic(fmt.Sprintf("buffer: re-reading %q at %d: %s", b.file.Name(), off, err))
+	}
+
+	if !bytes.Equal(got, data) {
+		panic(fmt.Sprintf("buffer: record at %d of %q reads back as %q, wrote %q", off, b.file.Name(), got, data))
+	}
+}
+
+// Verify the size of the current file matches its byte count, panicking
+// on a mismatch.
+func (b *Buffer) verify() {
+	if !b.plain() {
+		return
+	}
+
+	info, err := b.file.Stat()
+	if err != nil {
+		panic(fmt.Sprintf("buffer: stat %q: %s", b.file.Name(), err))
+	}
+
+	if info.Size() != b.bytes {
+		panic(fmt.Sprintf("buffer: %q is %d bytes, counted %d in %d writes", b.file.Name(), info.Size(), b.bytes, b.writes))
+	}
+}
@@ -0,0 +1,38 @@
+package buffer
+
+import (
+	"os"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test paranoid writes and flushes.
+func TestBuffer_Paranoid(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 3,
+		BufferSize:  1 << 10,
+		Paranoid:    true,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello\n"))
+	b.Write([]byte("world\n"))
+	b.Write([]byte("again\n"))
+
+	flush := <-b.Queue
+	assert.Equal(t, int64(18), flush.Bytes)
+
+	b.Write([]byte("hello\n"))
+	err = os.Truncate(b.file.Name(), 3)
+	assert.Equal(t, nil, err)
+
+	defer func() {
+		assert.NotEqual(t, nil, recover())
+	}()
+
+	b.Flush()
+	t.Fatal("expected a panic")
+}
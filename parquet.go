@@ -0,0 +1,488 @@
+package buffer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+// ErrParquet is returned by raw writes to buffers with Config.Parquet,
+// which only accept rows through WriteRow.
+var ErrParquet = errors.New("parquet files only accept rows")
+
+// Parquet magic bytes.
+var parquetMagic = []byte("PAR1")
+
+// Parquet physical types.
+const (
+	parquetBoolean   = 0
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+)
+
+// Parquet converted types.
+const (
+	parquetUTF8      = 0
+	parquetTimestamp = 9
+)
+
+// ParquetSchema describes the rows of Parquet files, derived from a
+// struct type, see NewParquetSchema.
+type ParquetSchema struct {
+	typ     reflect.Type
+	columns []parquetColumn
+}
+
+// Parquet column of a struct field.
+type parquetColumn struct {
+	name      string
+	index     int
+	kind      int32
+	converted int32
+}
+
+// NewParquetSchema returns the schema of rows of the struct type of `v`.
+// Exported fields become required columns named by their "parquet" tag,
+// or the field name, and fields tagged "-" are skipped. Booleans, integers,
+// floats, strings, byte slices and time.Time, stored as milliseconds, are
+// supported.
+func NewParquetSchema(v interface{}) (*ParquetSchema, error) {
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("parquet rows must be structs, got %v", t)
+	}
+
+	s := &ParquetSchema{typ: t}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := field.Tag.Get("parquet")
+		if name == "-" {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		c := parquetColumn{name: name, index: i, converted: -1}
+		switch {
+		case field.Type == reflect.TypeOf(time.Time{}):
+			c.kind, c.converted = parquetInt64, parquetTimestamp
+		case field.Type.Kind() == reflect.Bool:
+			c.kind = parquetBoolean
+		case field.Type.Kind() >= reflect.Int && field.Type.Kind() <= reflect.Uint64:
+			c.kind = parquetInt64
+		case field.Type.Kind() == reflect.Float32 || field.Type.Kind() == reflect.Float64:
+			c.kind = parquetDouble
+		case field.Type.Kind() == reflect.String:
+			c.kind, c.converted = parquetByteArray, parquetUTF8
+		case field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Uint8:
+			c.kind = parquetByteArray
+		default:
+			return nil, fmt.Errorf("unsupported parquet field %s of type %s", field.Name, field.Type)
+		}
+
+		s.columns = append(s.columns, c)
+	}
+
+	if len(s.columns) == 0 {
+		return nil, fmt.Errorf("parquet rows of type %s have no columns", t)
+	}
+
+	return s, nil
+}
+
+// Row group of the current file, holding plain-encoded column values
+// until the file is flushed.
+type rowGroup struct {
+	schema  *ParquetSchema
+	rows    int64
+	columns []bytes.Buffer
+}
+
+// New empty row group of schema `s`.
+func newRowGroup(s *ParquetSchema) *rowGroup {
+	return &rowGroup{schema: s, columns: make([]bytes.Buffer, len(s.columns))}
+}
+
+// Append row `v`, returning its encoded size.
+func (g *rowGroup) append(v interface{}) (int64, error) {
+	r := reflect.ValueOf(v)
+	if r.Kind() == reflect.Ptr && !r.IsNil() {
+		r = r.Elem()
+	}
+
+	if !r.IsValid() || r.Type() != g.schema.typ {
+		return 0, fmt.Errorf("row of type %T does not match parquet rows of type %s", v, g.schema.typ)
+	}
+
+	values := make([]reflect.Value, len(g.schema.columns))
+	for i, c := range g.schema.columns {
+		values[i] = r.Field(c.index)
+	}
+
+	return g.put(values), nil
+}
+
+// Append the row of column `values`, returning its encoded size.
+func (g *rowGroup) put(values []reflect.Value) int64 {
+	var size int64
+	var scratch [8]byte
+	for i, c := range g.schema.columns {
+		col := &g.columns[i]
+		f := values[i]
+		before := col.Len()
+
+		switch c.kind {
+		case parquetBoolean:
+			if g.rows%8 == 0 {
+				col.WriteByte(0)
+			}
+			if f.Bool() {
+				col.Bytes()[col.Len()-1] |= 1 << uint(g.rows%8)
+			}
+		case parquetInt64:
+			binary.LittleEndian.PutUint64(scratch[:], uint64(integer(f)))
+			col.Write(scratch[:])
+		case parquetDouble:
+			binary.LittleEndian.PutUint64(scratch[:], math.Float64bits(f.Float()))
+			col.Write(scratch[:])
+		case parquetByteArray:
+			binary.LittleEndian.PutUint32(scratch[:4], uint32(f.Len()))
+			col.Write(scratch[:4])
+			if f.Kind() == reflect.String {
+				col.WriteString(f.String())
+			} else {
+				col.Write(f.Bytes())
+			}
+		}
+
+		size += int64(col.Len() - before)
+	}
+
+	g.rows++
+	return size
+}
+
+// Integer value of field `f`.
+func integer(f reflect.Value) int64 {
+	switch {
+	case f.Type() == reflect.TypeOf(time.Time{}):
+		return f.Interface().(time.Time).UnixNano() / int64(time.Millisecond)
+	case f.Kind() >= reflect.Uint && f.Kind() <= reflect.Uint64:
+		return int64(f.Uint())
+	default:
+		return f.Int()
+	}
+}
+
+// Write the row group to `w` as a Parquet file, with one data page per
+// column, returning the bytes written.
+func (g *rowGroup) encode(w io.Writer) (int64, error) {
+	var out bytes.Buffer
+	out.Write(parquetMagic)
+
+	offsets := make([]int64, len(g.columns))
+	sizes := make([]int64, len(g.columns))
+	for i := range g.columns {
+		data := g.columns[i].Bytes()
+
+		var h compact
+		h.begin()
+		h.i32(1, 0)
+		h.i32(2, int32(len(data)))
+		h.i32(3, int32(len(data)))
+		h.structure(5)
+		h.i32(1, int32(g.rows))
+		h.i32(2, 0)
+		h.i32(3, 3)
+		h.i32(4, 3)
+		h.end()
+		h.end()
+
+		offsets[i] = int64(out.Len())
+		sizes[i] = int64(h.Len() + len(data))
+		out.Write(h.Bytes())
+		out.Write(data)
+	}
+
+	total := int64(out.Len()) - int64(len(parquetMagic))
+
+	var m compact
+	m.begin()
+	m.i32(1, 1)
+	m.list(2, ctStruct, len(g.schema.columns)+1)
+	m.begin()
+	m.binary(4, "schema")
+	m.i32(5, int32(len(g.schema.columns)))
+	m.end()
+	for _, c := range g.schema.columns {
+		m.begin()
+		m.i32(1, c.kind)
+		m.i32(3, 0)
+		m.binary(4, c.name)
+		if c.converted >= 0 {
+			m.i32(6, c.converted)
+		}
+		m.end()
+	}
+	m.i64(3, g.rows)
+	m.list(4, ctStruct, 1)
+	m.begin()
+	m.list(1, ctStruct, len(g.schema.columns))
+	for i, c := range g.schema.columns {
+		m.begin()
+		m.i64(2, offsets[i])
+		m.structure(3)
+		m.i32(1, c.kind)
+		m.list(2, ctI32, 1)
+		m.zigzag(0)
+		m.list(3, ctBinary, 1)
+		m.str(c.name)
+		m.i32(4, 0)
+		m.i64(5, g.rows)
+		m.i64(6, sizes[i])
+		m.i64(7, sizes[i])
+		m.i64(9, offsets[i])
+		m.end()
+		m.end()
+	}
+	m.i64(2, total)
+	m.i64(3, g.rows)
+	m.end()
+	m.binary(6, "go-disk-buffer")
+	m.end()
+
+	out.Write(m.Bytes())
+	var size [4]byte
+	binary.LittleEndian.PutUint32(size[:], uint32(m.Len()))
+	out.Write(size[:])
+	out.Write(parquetMagic)
+
+	n, err := w.Write(out.Bytes())
+	return int64(n), err
+}
+
+// Thrift compact protocol types.
+const (
+	ctI32    = 5
+	ctI64    = 6
+	ctBinary = 8
+	ctList   = 9
+	ctStruct = 12
+)
+
+// Thrift compact protocol encoder of Parquet metadata.
+type compact struct {
+	bytes.Buffer
+	fields []int16
+}
+
+// Begin a struct.
+func (c *compact) begin() {
+	c.fields = append(c.fields, 0)
+}
+
+// End a struct.
+func (c *compact) end() {
+	c.WriteByte(0)
+	c.fields = c.fields[:len(c.fields)-1]
+}
+
+// Field header of field `id` of type `t`.
+func (c *compact) field(id int16, t byte) {
+	last := &c.fields[len(c.fields)-1]
+	if d := id - *last; d > 0 && d <= 15 {
+		c.WriteByte(byte(d)<<4 | t)
+	} else {
+		c.WriteByte(t)
+		c.zigzag(int64(id))
+	}
+	*last = id
+}
+
+// Zigzag varint `v`.
+func (c *compact) zigzag(v int64) {
+	c.uvarint(uint64(v<<1 ^ v>>63))
+}
+
+// Varint `v`.
+func (c *compact) uvarint(v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	c.Write(buf[:binary.PutUvarint(buf[:], v)])
+}
+
+// String `s`.
+func (c *compact) str(s string) {
+	c.uvarint(uint64(len(s)))
+	c.WriteString(s)
+}
+
+// Field `id` of i32 `v`.
+func (c *compact) i32(id int16, v int32) {
+	c.field(id, ctI32)
+	c.zigzag(int64(v))
+}
+
+// Field `id` of i64 `v`.
+func (c *compact) i64(id int16, v int64) {
+	c.field(id, ctI64)
+	c.zigzag(v)
+}
+
+// Field `id` of string `s`.
+func (c *compact) binary(id int16, s string) {
+	c.field(id, ctBinary)
+	c.str(s)
+}
+
+// Field `id` of a list of `n` elements of type `t`.
+func (c *compact) list(id int16, t byte, n int) {
+	c.field(id, ctList)
+	if n < 15 {
+		c.WriteByte(byte(n)<<4 | t)
+		return
+	}
+	c.WriteByte(0xf0 | t)
+	c.uvarint(uint64(n))
+}
+
+// Field `id` beginning a struct.
+func (c *compact) structure(id int16) {
+	c.field(id, ctStruct)
+	c.begin()
+}
+
+// Plain encoding of row `v`, its columns concatenated.
+func (s *ParquetSchema) encode(v interface{}) ([]byte, error) {
+	g := newRowGroup(s)
+	_, err := g.append(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	for i := range g.columns {
+		data = append(data, g.columns[i].Bytes()...)
+	}
+
+	return data, nil
+}
+
+// WriteRow appends `v`, of the struct type of Config.Parquet, to the row
+// group of the current file. Rows are held in memory and written out as
+// one row group when the file is flushed, and count towards FlushWrites
+// and FlushBytes by their plain-encoded size. Rows are admitted as writes
+// are, subject to MaxWriteSize, StrictBytes, rate limits and the breaker,
+// and mirrored to Config.Shadow.
+func (b *Buffer) WriteRow(v interface{}) (err error) {
+	if b.Parquet == nil {
+		return fmt.Errorf("rows require a parquet schema")
+	}
+
+	data, err := b.Parquet.encode(v)
+	if err != nil {
+		atomic.AddInt64(&b.errors, 1)
+		return err
+	}
+
+	if b.Shadow != nil {
+		defer func() {
+			if err == nil {
+				b.mirrorRow(v, data)
+			}
+		}()
+	}
+
+	if b.Instrument != nil {
+		start := time.Now()
+		defer func() {
+			n := len(data)
+			if err != nil {
+				n = 0
+			}
+			b.Instrument.Wrote(n, time.Since(start), err)
+		}()
+	}
+
+	err = b.checkLimit(len(data))
+	if err != nil {
+		return err
+	}
+
+	drop, err := b.throttle(1, len(data), false)
+	if err == nil && !drop {
+		drop, err = b.guard()
+	}
+
+	if err != nil || drop {
+		return err
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	defer func() {
+		if err != nil {
+			atomic.AddInt64(&b.errors, 1)
+		}
+	}()
+
+	err = b.admit()
+	if err != nil {
+		return err
+	}
+
+	err = b.fit(len(data))
+	if err != nil {
+		return err
+	}
+
+	size, err := b.rows.append(v)
+	if err != nil {
+		return err
+	}
+
+	if b.writes == 0 {
+		b.first = time.Now()
+	}
+
+	b.record()
+
+	if b.FlushIdle != 0 {
+		b.touch()
+	}
+
+	b.writes++
+	b.bytes += size
+	b.total.writes++
+	b.total.bytes += size
+
+	return b.thresholds(nil)
+}
+
+// Write out the row group of the current file.
+func (b *Buffer) endRows() error {
+	n, err := b.rows.encode(b.w)
+	if err != nil {
+		return err
+	}
+
+	b.bytes = n
+	return nil
+}
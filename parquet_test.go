@@ -0,0 +1,238 @@
+package buffer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Parquet test row.
+type event struct {
+	Name    string `parquet:"name"`
+	Count   int64  `parquet:"count"`
+	OK      bool   `parquet:"ok"`
+	private int
+}
+
+// Test writing rows as a Parquet file.
+func TestBuffer_WriteRow(t *testing.T) {
+	schema, err := NewParquetSchema(event{})
+	assert.Equal(t, nil, err)
+
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 3,
+		Parquet:     schema,
+	})
+
+	assert.Equal(t, nil, err)
+
+	_, err = b.Write([]byte("raw"))
+	assert.Equal(t, ErrParquet, err)
+
+	err = b.WriteRow(struct{ Name string }{"tobi"})
+	assert.NotEqual(t, nil, err)
+
+	err = b.WriteRow(event{Name: "tobi", Count: 1, OK: true})
+	assert.Equal(t, nil, err)
+
+	err = b.WriteRow(&event{Name: "loki", Count: 2})
+	assert.Equal(t, nil, err)
+
+	err = b.WriteRow(event{Name: "jane", Count: 3, OK: true})
+	assert.Equal(t, nil, err)
+
+	flush := <-b.Queue
+	assert.Equal(t, int64(3), flush.Writes)
+
+	data, err := ioutil.ReadFile(flush.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(len(data)), flush.Bytes)
+	assert.Equal(t, "PAR1", string(data[:4]))
+	assert.Equal(t, "PAR1", string(data[len(data)-4:]))
+
+	footer := binary.LittleEndian.Uint32(data[len(data)-8:])
+	assert.Equal(t, true, int(footer) < len(data)-12)
+	assert.Equal(t, true, bytes.Contains(data, []byte("\x04\x00\x00\x00tobi\x04\x00\x00\x00loki\x04\x00\x00\x00jane")))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test rejecting unsupported Parquet rows.
+func TestNewParquetSchema(t *testing.T) {
+	_, err := NewParquetSchema(1)
+	assert.NotEqual(t, nil, err)
+
+	_, err = NewParquetSchema(struct{ Tags []string }{})
+	assert.NotEqual(t, nil, err)
+
+	_, err = NewParquetSchema(struct{ private int }{})
+	assert.NotEqual(t, nil, err)
+}
+
+// Thrift compact protocol decoder of Parquet metadata, decoding structs
+// to maps of field ids to values.
+type thrift struct {
+	*bytes.Reader
+}
+
+// Decode a struct.
+func (t thrift) structure() map[int16]interface{} {
+	fields := make(map[int16]interface{})
+	var id int16
+	for {
+		b, _ := t.ReadByte()
+		if b == 0 {
+			return fields
+		}
+
+		if d := int16(b >> 4); d != 0 {
+			id += d
+		} else {
+			id = int16(t.zigzag())
+		}
+
+		fields[id] = t.value(b & 0x0f)
+	}
+}
+
+// Decode a value of type `kind`.
+func (t thrift) value(kind byte) interface{} {
+	switch kind {
+	case 1:
+		return true
+	case 2:
+		return false
+	case 3, 4, 5, 6:
+		return t.zigzag()
+	case 8:
+		n, _ := binary.ReadUvarint(t)
+		buf := make([]byte, n)
+		t.Read(buf)
+		return string(buf)
+	case 9:
+		h, _ := t.ReadByte()
+		n := uint64(h >> 4)
+		if n == 15 {
+			n, _ = binary.ReadUvarint(t)
+		}
+		list := make([]interface{}, n)
+		for i := range list {
+			list[i] = t.value(h & 0x0f)
+		}
+		return list
+	case 12:
+		return t.structure()
+	default:
+		panic(fmt.Sprintf("unsupported thrift type %d", kind))
+	}
+}
+
+// Decode a zigzag varint.
+func (t thrift) zigzag() int64 {
+	v, _ := binary.ReadUvarint(t)
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+// Test reading rows back by the Parquet metadata.
+func TestBuffer_WriteRow_read(t *testing.T) {
+	schema, err := NewParquetSchema(event{})
+	assert.Equal(t, nil, err)
+
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 3,
+		Parquet:     schema,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.WriteRow(event{Name: "tobi", Count: 1, OK: true})
+	b.WriteRow(event{Name: "loki", Count: 2})
+	b.WriteRow(event{Name: "jane", Count: 3, OK: true})
+
+	flush := <-b.Queue
+	data, err := ioutil.ReadFile(flush.Path)
+	assert.Equal(t, nil, err)
+
+	size := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	meta := thrift{bytes.NewReader(data[len(data)-8-size : len(data)-8])}.structure()
+	assert.Equal(t, int64(3), meta[3])
+
+	var names []string
+	for _, s := range meta[2].([]interface{})[1:] {
+		names = append(names, s.(map[int16]interface{})[4].(string))
+	}
+	assert.Equal(t, []string{"name", "count", "ok"}, names)
+
+	group := meta[4].([]interface{})[0].(map[int16]interface{})
+	assert.Equal(t, int64(3), group[3])
+
+	var columns [][]byte
+	for _, c := range group[1].([]interface{}) {
+		m := c.(map[int16]interface{})[3].(map[int16]interface{})
+		assert.Equal(t, int64(3), m[5])
+
+		r := bytes.NewReader(data[m[9].(int64):])
+		page := thrift{r}.structure()
+		assert.Equal(t, int64(0), page[1])
+		assert.Equal(t, int64(3), page[5].(map[int16]interface{})[1])
+
+		column := make([]byte, page[3].(int64))
+		r.Read(column)
+		columns = append(columns, column)
+	}
+
+	assert.Equal(t, "\x04\x00\x00\x00tobi\x04\x00\x00\x00loki\x04\x00\x00\x00jane", string(columns[0]))
+	assert.Equal(t, uint64(1), binary.LittleEndian.Uint64(columns[1][0:]))
+	assert.Equal(t, uint64(2), binary.LittleEndian.Uint64(columns[1][8:]))
+	assert.Equal(t, uint64(3), binary.LittleEndian.Uint64(columns[1][16:]))
+	assert.Equal(t, []byte{0x05}, columns[2])
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test rows are admitted as writes are.
+func TestBuffer_WriteRow_admit(t *testing.T) {
+	schema, err := NewParquetSchema(event{})
+	assert.Equal(t, nil, err)
+
+	shadow, err := New("/tmp/buffer-shadow", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 100,
+		Parquet:     schema,
+	})
+	assert.Equal(t, nil, err)
+
+	b, err := New("/tmp/buffer", &Config{
+		Queue:        make(chan *Flush, 100),
+		FlushWrites:  100,
+		Parquet:      schema,
+		MaxWriteSize: 20,
+		Shadow:       shadow,
+	})
+
+	assert.Equal(t, nil, err)
+
+	err = b.WriteRow(event{Name: "tobi", Count: 1})
+	assert.Equal(t, nil, err)
+
+	err = b.WriteRow(event{Name: "a very long name indeed"})
+	assert.Equal(t, true, errors.Is(err, ErrTooLarge))
+	assert.Equal(t, int64(1), b.Writes())
+
+	r := b.ShadowReport()
+	assert.Equal(t, int64(1), r.Writes)
+	assert.Equal(t, int64(1), r.ShadowWrites)
+	assert.Equal(t, r.Digest, r.ShadowDigest)
+
+	assert.Equal(t, nil, b.Close())
+	assert.Equal(t, nil, shadow.Close())
+}
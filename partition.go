@@ -0,0 +1,119 @@
+package buffer
+
+import (
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"strconv"
+)
+
+// Partitioner assigns keys to one of `n` partitions, see WriteHashed.
+type Partitioner interface {
+	Partition(key []byte, n int) int
+}
+
+// HashPartitioner assigns keys by a 32-bit hash modulo the partition count.
+type HashPartitioner struct {
+	New func() hash.Hash32 // Hash function, defaults to FNV-1a
+}
+
+// Partition implementation.
+func (p HashPartitioner) Partition(key []byte, n int) int {
+	newHash := p.New
+	if newHash == nil {
+		newHash = fnv.New32a
+	}
+
+	h := newHash()
+	h.Write(key)
+	return int(h.Sum32() % uint32(n))
+}
+
+// KafkaPartitioner assigns keys like the default Kafka partitioner, by
+// the positive murmur2 hash modulo the partition count, so partitions
+// match downstream topics.
+type KafkaPartitioner struct{}
+
+// Partition implementation.
+func (KafkaPartitioner) Partition(key []byte, n int) int {
+	return int(uint32(murmur2(key))&0x7fffffff) % n
+}
+
+// JumpPartitioner assigns keys with jump consistent hashing, so only
+// 1/n of keys move when the partition count changes to n.
+type JumpPartitioner struct{}
+
+// Partition implementation.
+func (JumpPartitioner) Partition(key []byte, n int) int {
+	h := fnv.New64a()
+	h.Write(key)
+	return jump(h.Sum64(), n)
+}
+
+// WriteHashed writes `data` to the keyed partition assigned to `key` by
+// Config.Partitioner, named by its index among Config.Partitions.
+func (b *Buffer) WriteHashed(key, data []byte) (int, error) {
+	if b.Partitions <= 0 {
+		return 0, fmt.Errorf("hashed writes require partitions")
+	}
+
+	p := b.Partitioner
+	if p == nil {
+		p = HashPartitioner{}
+	}
+
+	i := p.Partition(key, b.Partitions)
+	return b.WriteKeyed(strconv.Itoa(i), data)
+}
+
+// Murmur2 hash as implemented by Kafka.
+func murmur2(data []byte) int32 {
+	const (
+		seed uint32 = 0x9747b28c
+		m    uint32 = 0x5bd1e995
+		r           = 24
+	)
+
+	length := len(data)
+	h := seed ^ uint32(length)
+
+	for i := 0; i+4 <= length; i += 4 {
+		k := uint32(data[i]) | uint32(data[i+1])<<8 | uint32(data[i+2])<<16 | uint32(data[i+3])<<24
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+	}
+
+	tail := length &^ 3
+	switch length % 4 {
+	case 3:
+		h ^= uint32(data[tail+2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(data[tail+1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(data[tail])
+		h *= m
+	}
+
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return int32(h)
+}
+
+// Jump consistent hash of `key` into `n` buckets.
+func jump(key uint64, n int) int {
+	var b, j int64 = -1, 0
+
+	for j < int64(n) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+
+	return int(b)
+}
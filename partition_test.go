@@ -0,0 +1,63 @@
+package buffer
+
+import (
+	"hash/crc32"
+	"strconv"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test the murmur2 hash matches Kafka.
+func TestMurmur2(t *testing.T) {
+	assert.Equal(t, int32(-973932308), murmur2([]byte("21")))
+	assert.Equal(t, int32(-790332482), murmur2([]byte("foobar")))
+	assert.Equal(t, int32(-985981536), murmur2([]byte("a-little-bit-long-string")))
+	assert.Equal(t, int32(-1486304829), murmur2([]byte("a-little-bit-longer-string")))
+	assert.Equal(t, int32(-58897971), murmur2([]byte("lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8")))
+	assert.Equal(t, int32(479470107), murmur2([]byte("abc")))
+}
+
+// Test jump consistent hashing moves few keys.
+func TestJumpPartitioner(t *testing.T) {
+	p := JumpPartitioner{}
+	moved := 0
+
+	for i := 0; i < 1000; i++ {
+		key := []byte{byte(i), byte(i >> 8)}
+		a := p.Partition(key, 10)
+		b := p.Partition(key, 11)
+		if a != b {
+			moved++
+			assert.Equal(t, 10, b)
+		}
+	}
+
+	assert.Equal(t, true, moved > 0 && moved < 200)
+}
+
+// Test writing to hashed partitions.
+func TestBuffer_WriteHashed(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1,
+		Partitions:  4,
+		Partitioner: HashPartitioner{New: crc32.NewIEEE},
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.WriteHashed([]byte("tobi"), []byte("hello"))
+	b.WriteHashed([]byte("tobi"), []byte("world"))
+
+	f1 := <-b.Queue
+	f2 := <-b.Queue
+	assert.Equal(t, f1.Key, f2.Key)
+
+	i := int(crc32.ChecksumIEEE([]byte("tobi")) % 4)
+	assert.Equal(t, strconv.Itoa(i), f1.Key)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
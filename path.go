@@ -0,0 +1,82 @@
+package buffer
+
+import "sync/atomic"
+
+// SetPath finishes the current file under the old path and opens
+// subsequent files under `path`, including those of keyed partitions, so
+// spool volumes can be swapped without restarting producers. Stale files
+// are removed and flushed files recovered under the new path when enabled,
+// recovery being skipped when OutDir is set as it does not change.
+func (b *Buffer) SetPath(path string) error {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.stopped {
+		return ErrClosed
+	}
+
+	for _, k := range b.keys {
+		err := k.SetPath(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	if path == b.path {
+		return nil
+	}
+
+	b.log(1, "moving to %q", path)
+
+	err := b.release()
+	if err != nil {
+		return err
+	}
+
+	err = b.flush(Forced)
+	if err != nil {
+		return err
+	}
+
+	err = b.remove()
+	if err != nil {
+		return err
+	}
+
+	b.path = path
+	b.fields[3] = path
+
+	if b.RemoveStale != 0 && b.key == "" {
+		err := b.clean()
+		if err != nil {
+			return err
+		}
+	}
+
+	var backlog []*Flush
+	if b.Recover && b.OutDir == "" && b.key == "" {
+		backlog, err = b.recoverable()
+		if err != nil {
+			return err
+		}
+	}
+
+	if b.Segments != 0 {
+		err := b.preallocate()
+		if err != nil {
+			return err
+		}
+	}
+
+	err = b.open()
+	if err != nil {
+		return err
+	}
+
+	if len(backlog) != 0 {
+		atomic.AddInt64(&b.backlog.total, int64(len(backlog)))
+		go b.deliver(interleave(backlog, b.RecoverRatio))
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package buffer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test moving to a new path.
+func TestBuffer_SetPath(t *testing.T) {
+	os.RemoveAll("/tmp/buffer-moved")
+
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 2,
+		Segments:    2,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+	b.WriteKeyed("tobi", []byte("hello"))
+
+	err = b.SetPath("/tmp/buffer-moved/buffer")
+	assert.Equal(t, nil, err)
+
+	f := <-b.Queue
+	assert.Equal(t, "/tmp", filepath.Dir(f.Path))
+	f = <-b.Queue
+	assert.Equal(t, "/tmp", filepath.Dir(f.Path))
+
+	b.Write([]byte("hello"))
+	b.Write([]byte("world"))
+
+	f = <-b.Queue
+	assert.Equal(t, "/tmp/buffer-moved", filepath.Dir(f.Path))
+	assert.Equal(t, int64(2), f.Writes)
+
+	b.WriteKeyed("tobi", []byte("hello"))
+	b.WriteKeyed("tobi", []byte("world"))
+
+	f = <-b.Queue
+	assert.Equal(t, "/tmp/buffer-moved", filepath.Dir(f.Path))
+	assert.Equal(t, "tobi", f.Key)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	files, _ := filepath.Glob("/tmp/buffer-moved/*")
+	assert.Equal(t, 2, len(files))
+}
@@ -0,0 +1,53 @@
+package buffer
+
+// Pause stops automatic flushes, including those of keyed partitions,
+// so writes accumulate in the current file until Resume. Explicit
+// flushes and Close still flush.
+func (b *Buffer) Pause() {
+	b.Lock()
+	defer b.Unlock()
+
+	b.log(1, "pausing")
+	b.paused = true
+
+	for _, k := range b.keys {
+		k.Pause()
+	}
+}
+
+// Resume automatic flushes after Pause, flushing files which reached
+// the write or byte thresholds while paused.
+func (b *Buffer) Resume() error {
+	b.Lock()
+	defer b.Unlock()
+
+	b.log(1, "resuming")
+	b.paused = false
+
+	for _, k := range b.keys {
+		err := k.Resume()
+		if err != nil {
+			return err
+		}
+	}
+
+	if b.stopped {
+		return nil
+	}
+
+	switch {
+	case b.FlushWrites != 0 && b.writes >= b.FlushWrites:
+		return b.attempt(func() error { return b.flush(Writes) })
+	case b.FlushBytes != 0 && b.bytes >= b.FlushBytes:
+		return b.attempt(func() error { return b.flush(Bytes) })
+	default:
+		return nil
+	}
+}
+
+// Paused returns true when automatic flushes are paused.
+func (b *Buffer) Paused() bool {
+	b.RLock()
+	defer b.RUnlock()
+	return b.paused
+}
@@ -0,0 +1,45 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test pausing and resuming flushes.
+func TestBuffer_Pause(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 2,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Pause()
+	assert.Equal(t, true, b.Paused())
+
+	for i := 0; i < 5; i++ {
+		_, err := b.Write([]byte("hello\n"))
+		assert.Equal(t, nil, err)
+	}
+
+	assert.Equal(t, 0, len(b.Queue))
+	assert.Equal(t, int64(5), b.Writes())
+
+	err = b.Resume()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, false, b.Paused())
+
+	flush := <-b.Queue
+	assert.Equal(t, Writes, flush.Reason)
+	assert.Equal(t, int64(5), flush.Writes)
+
+	b.Pause()
+	b.Write([]byte("hello\n"))
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	flush = <-b.Queue
+	assert.Equal(t, Forced, flush.Reason)
+	assert.Equal(t, int64(1), flush.Writes)
+}
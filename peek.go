@@ -0,0 +1,48 @@
+package buffer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Peek returns a copy of the data written to the current file so far,
+// without flushing it. Buffered data is written out first. Compressed,
+// encrypted and Parquet files cannot be peeked, as their data is not
+// readable until the file is flushed.
+func (b *Buffer) Peek() (io.Reader, error) {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.stopped {
+		return nil, ErrClosed
+	}
+
+	if !b.plain() || b.rows != nil {
+		return nil, fmt.Errorf("only plain files can be peeked")
+	}
+
+	if b.buf != nil && b.buf.Buffered() != 0 {
+		b.log(2, "writing out buffered data")
+		err := b.buf.Flush()
+		if err != nil {
+			return nil, err
+		}
+
+		if b.live != nil {
+			b.live.commit(b.committed())
+		}
+	}
+
+	if b.inMemory() {
+		return bytes.NewReader(append([]byte(nil), b.held()...)), nil
+	}
+
+	data := make([]byte, b.bytes)
+	_, err := b.file.ReadAt(data, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(data), nil
+}
@@ -0,0 +1,44 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test peeking at the current file.
+func TestBuffer_Peek(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 3,
+		BufferSize:  1 << 10,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello "))
+	b.Write([]byte("world"))
+
+	r, err := b.Peek()
+	assert.Equal(t, nil, err)
+
+	data, err := ioutil.ReadAll(r)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "hello world", string(data))
+
+	b.Write([]byte("!"))
+
+	flush := <-b.Queue
+	assert.Equal(t, int64(12), flush.Bytes)
+
+	data, err = ioutil.ReadFile(flush.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "hello world!", string(data))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	_, err = b.Peek()
+	assert.Equal(t, ErrClosed, err)
+}
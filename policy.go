@@ -0,0 +1,359 @@
+package buffer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrQueueFull is returned when a flush cannot be queued under the Error policy.
+var ErrQueueFull = errors.New("queue full")
+
+// ErrClosed is returned when the buffer is used after Close.
+var ErrClosed = errors.New("buffer closed")
+
+// Policy for handling a failure condition.
+type Policy int
+
+// Failure policies.
+const (
+	Block      Policy = iota // Wait until the condition clears
+	DropNewest               // Drop the newest data or notification
+	DropOldest               // Drop the oldest queued notification
+	Error                    // Return an error to the caller
+)
+
+// String returns the policy name.
+func (p Policy) String() string {
+	switch p {
+	case Block:
+		return "block"
+	case DropNewest:
+		return "drop_newest"
+	case DropOldest:
+		return "drop_oldest"
+	case Error:
+		return "error"
+	default:
+		return fmt.Sprintf("policy(%d)", int(p))
+	}
+}
+
+// Condition under which a policy applies.
+type Condition string
+
+// Failure conditions.
+const (
+	QueueFull     Condition = "queue_full"     // Queue has no room for a flush
+	DiskFull      Condition = "disk_full"      // Write failed with ENOSPC, DropOldest evicts the oldest undelivered file
+	RotateFailed  Condition = "rotate_failed"  // Write-triggered rotation failed to rename
+	QuotaExceeded Condition = "quota_exceeded" // Flushed files exceed Config.Quota
+	RateLimited   Condition = "rate_limited"   // Writes exceed Config.RateBytes or RateWrites
+	BreakerOpen   Condition = "breaker_open"   // Circuit breaker tripped by Config.TripAfter
+)
+
+// Supported policies per condition, the first being the default.
+var supported = map[Condition][]Policy{
+	QueueFull:     {Block, DropNewest, DropOldest, Error},
+	DiskFull:      {Error, DropNewest, DropOldest, Block},
+	RotateFailed:  {DropNewest, Block, Error},
+	QuotaExceeded: {DropOldest, Error},
+	RateLimited:   {Block, DropNewest, Error},
+	BreakerOpen:   {Error, DropNewest},
+}
+
+// Decision counted when a policy is applied.
+type Decision struct {
+	Condition Condition
+	Policy    Policy
+}
+
+// Policies shared by a buffer and its partitions.
+type policies struct {
+	sync.RWMutex
+	m         map[Condition]Policy
+	decisions map[Decision]int64
+}
+
+// New policies from the configured overrides.
+func newPolicies(m map[Condition]Policy) *policies {
+	p := &policies{
+		m:         make(map[Condition]Policy),
+		decisions: make(map[Decision]int64),
+	}
+
+	for c, list := range supported {
+		p.m[c] = list[0]
+	}
+
+	for c, policy := range m {
+		p.m[c] = policy
+	}
+
+	return p
+}
+
+// Check policy `p` is supported for condition `c`.
+func checkPolicy(c Condition, p Policy) error {
+	list, ok := supported[c]
+	if !ok {
+		return fmt.Errorf("unknown condition %q", c)
+	}
+
+	for _, s := range list {
+		if s == p {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("policy %s is not supported for %s", p, c)
+}
+
+// Check policy `p` for condition `cond` suits the configuration.
+func (c *Config) checkPolicy(cond Condition, p Policy) error {
+	switch {
+	case cond == QueueFull && p == DropOldest && cap(c.Queue) == 0:
+		return fmt.Errorf("policy drop_oldest for queue_full requires a buffered queue")
+	case cond == DiskFull && p == DropNewest && (c.Codec != "" && !c.Deferred || c.KeyProvider != nil || c.MemoryBytes != 0):
+		return fmt.Errorf("policy drop_newest for disk_full requires uncompressed, unencrypted files on disk")
+	default:
+		return nil
+	}
+}
+
+// SetPolicy sets the policy applied under condition `c`.
+func (b *Buffer) SetPolicy(c Condition, p Policy) error {
+	err := checkPolicy(c, p)
+	if err == nil {
+		err = b.Config.checkPolicy(c, p)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	b.policies.Lock()
+	defer b.policies.Unlock()
+	b.policies.m[c] = p
+	return nil
+}
+
+// Policy returns the policy applied under condition `c`.
+func (b *Buffer) Policy(c Condition) Policy {
+	b.policies.RLock()
+	defer b.policies.RUnlock()
+	return b.policies.m[c]
+}
+
+// Decisions returns the number of times each policy has been applied.
+func (b *Buffer) Decisions() map[Decision]int64 {
+	b.policies.RLock()
+	defer b.policies.RUnlock()
+
+	m := make(map[Decision]int64, len(b.policies.decisions))
+	for d, n := range b.policies.decisions {
+		m[d] = n
+	}
+
+	return m
+}
+
+// Decide the policy for condition `c`, counting the decision.
+func (b *Buffer) decide(c Condition) Policy {
+	b.policies.Lock()
+	defer b.policies.Unlock()
+
+	p := b.policies.m[c]
+	b.policies.decisions[Decision{c, p}]++
+	b.log(1, "%s: %s", c, p)
+	return p
+}
+
+// Publish `f` to the queue, applying the QueueFull policy.
+func (b *Buffer) publish(f *Flush) error {
+	b.states.set(f.Path, Queued, nil)
+
+	if b.Policy(QueueFull) == Block {
+		b.Queue <- f
+		return nil
+	}
+
+	select {
+	case b.Queue <- f:
+		return nil
+	default:
+	}
+
+	switch b.decide(QueueFull) {
+	case DropNewest:
+		b.drop(f)
+		return nil
+	case DropOldest:
+		for {
+			select {
+			case b.Queue <- f:
+				return nil
+			default:
+			}
+
+			select {
+			case old := <-b.Queue:
+				b.drop(old)
+			default:
+			}
+		}
+	case Error:
+		b.states.set(f.Path, Sealed, ErrQueueFull)
+		return ErrQueueFull
+	default:
+		b.Queue <- f
+		return nil
+	}
+}
+
+// Drop the queued flush `f`.
+func (b *Buffer) drop(f *Flush) {
+	b.log(1, "dropped %q", f.Path)
+	b.states.set(f.Path, Dropped, nil)
+	b.error(b.journal(dropped, f, nil))
+	b.staleness.untrack(f)
+	b.batches.settle(f.Batches, Dropped)
+
+	if b.Quota != nil {
+		b.Quota.remove(f)
+	}
+
+	if b.Hooks.OnDrop != nil {
+		b.Hooks.OnDrop(QueueFull, f)
+	}
+}
+
+// Attempt a write-triggered rotation, applying the RotateFailed policy.
+func (b *Buffer) attempt(rotate func() error) error {
+	for attempts := 1; ; attempts++ {
+		err := rotate()
+		e, ok := err.(*RenameError)
+		if !ok {
+			return err
+		}
+
+		switch b.decide(RotateFailed) {
+		case Error:
+			return err
+		case Block:
+			b.error(e)
+			backoff := b.backoff().Backoff(attempts)
+			if backoff == 0 {
+				backoff = time.Second
+			}
+			time.Sleep(backoff)
+		default:
+			b.error(e)
+			return nil
+		}
+	}
+}
+
+// Handle a failed write of `data`, of which `n` bytes reached the file,
+// applying the DiskFull policy and returning whether it was dropped. The
+// write is `pending` when it failed writing out earlier buffered data.
+func (b *Buffer) writeFailed(data []byte, n int, pending bool, err error) (bool, error) {
+	if !errors.Is(err, syscall.ENOSPC) {
+		return false, err
+	}
+
+	if b.decide(DiskFull) != DropNewest {
+		return false, err
+	}
+
+	rerr := b.rollback(n, pending)
+	if rerr != nil {
+		b.log(1, "error rolling back write: %s", rerr)
+		return false, err
+	}
+
+	if b.Hooks.OnDrop != nil {
+		b.Hooks.OnDrop(DiskFull, nil)
+	}
+
+	b.unwind(int64(len(data) + len(b.Delimiter)))
+	return true, nil
+}
+
+// Writer beneath the write buffer recording the data it failed to write,
+// so buffered records can be restored after a failed flush.
+type tap struct {
+	w    io.Writer
+	rest []byte
+	err  error
+}
+
+// Write implements io.Writer.
+func (t *tap) Write(p []byte) (int, error) {
+	n, err := t.w.Write(p)
+	if err != nil {
+		t.rest = append(t.rest[:0], p[n:]...)
+		t.err = err
+	}
+
+	return n, err
+}
+
+// Write out buffered records before a write of `size` bytes which does
+// not fit the write buffer under the DropNewest policy for DiskFull, so a
+// failure never leaves part of the write buffered.
+func (b *Buffer) preflush(size int) error {
+	if b.buf == nil {
+		return nil
+	}
+
+	if b.tap.err != nil {
+		return b.tap.err
+	}
+
+	if size <= b.buf.Available() || b.buf.Buffered() == 0 || b.Policy(DiskFull) != DropNewest {
+		return nil
+	}
+
+	return b.buf.Flush()
+}
+
+// Roll the current file back to the end of the last record after a failed
+// write, truncating the `n` bytes of it which reached the file. Buffered
+// records of a `pending` write are restored to the write buffer.
+func (b *Buffer) rollback(n int, pending bool) error {
+	var rest []byte
+	if b.buf != nil {
+		if pending {
+			rest = b.tap.rest
+		}
+
+		b.tap.rest, b.tap.err = nil, nil
+		b.buf.Reset(b.tap)
+	}
+
+	if n != 0 {
+		off, err := b.file.Seek(0, io.SeekCurrent)
+		if err == nil {
+			err = b.file.Truncate(off - int64(n))
+		}
+
+		if err == nil {
+			_, err = b.file.Seek(off-int64(n), io.SeekStart)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if rest == nil {
+		return nil
+	}
+
+	_, err := b.buf.Write(rest)
+	return err
+}
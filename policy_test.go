@@ -0,0 +1,91 @@
+package buffer
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test queue full policies.
+func TestBuffer_Policy_QueueFull(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 1),
+		FlushWrites: 1,
+		Policies:    map[Condition]Policy{QueueFull: DropOldest},
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("a"))
+	b.Write([]byte("b"))
+
+	flush := <-b.Queue
+	assert.Equal(t, int64(1), flush.Bytes)
+	assert.Equal(t, int64(1), b.Decisions()[Decision{QueueFull, DropOldest}])
+
+	err = b.SetPolicy(QueueFull, Error)
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("c"))
+	_, err = b.Write([]byte("d"))
+	assert.Equal(t, ErrQueueFull, err)
+
+	err = b.SetPolicy(QueueFull, DropNewest)
+	assert.Equal(t, nil, err)
+
+	_, err = b.Write([]byte("e"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(1), b.Decisions()[Decision{QueueFull, DropNewest}])
+
+	<-b.Queue
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test unsupported policies.
+func TestBuffer_SetPolicy_Unsupported(t *testing.T) {
+	_, err := New("/tmp/buffer", &Config{
+		FlushWrites: 1,
+		Policies:    map[Condition]Policy{RateLimited: DropOldest},
+	})
+
+	assert.Equal(t, "policy drop_oldest is not supported for rate_limited", err.Error())
+}
+
+// Test files remain writable after a failed write-triggered rename.
+func TestBuffer_Policy_RotateFailed(t *testing.T) {
+	os.RemoveAll("/tmp/buffer-rotate")
+	os.MkdirAll("/tmp/buffer-rotate/buffer.1.closed/taken", 0755)
+
+	b, err := New("/tmp/buffer-rotate/buffer", &Config{
+		Queue:         make(chan *Flush, 100),
+		FlushWrites:   2,
+		BufferSize:    1 << 10,
+		Codec:         "gzip",
+		Filename:      "{{.Path}}.{{.Seq}}",
+		RenameBackoff: time.Millisecond,
+		Verbosity:     0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello\n"))
+	_, err = b.Write([]byte("world\n"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(1), b.Decisions()[Decision{RotateFailed, DropNewest}])
+
+	os.RemoveAll("/tmp/buffer-rotate/buffer.1.closed")
+
+	_, err = b.Write([]byte("again\n"))
+	assert.Equal(t, nil, err)
+
+	flush := <-b.Queue
+	assert.Equal(t, int64(3), flush.Writes)
+	assert.Equal(t, "hello\nworld\nagain\n", gunzip(t, flush.Path))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
@@ -0,0 +1,146 @@
+package buffer
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrQuotaExceeded is returned by writes when the quota is exceeded
+// under the Error policy.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// QuotaManager enforces a cap on the total bytes of flushed files which
+// have not been acked, shared by the buffers it is configured for, see
+// Config.Quota. Under the DropOldest policy for QuotaExceeded, files of
+// the buffers with the lowest Config.QuotaPriority are evicted first,
+// oldest first. Under the Error policy, writes fail until files are acked.
+// With Config.SpillDir, new files spill over instead.
+type QuotaManager struct {
+	sync.Mutex
+	limit   int64
+	usage   int64
+	pending map[string]*quotaFile
+}
+
+// Pending file accounted by a quota.
+type quotaFile struct {
+	b *Buffer
+	f *Flush
+}
+
+// NewQuotaManager returns a quota of `limit` bytes.
+func NewQuotaManager(limit int64) *QuotaManager {
+	return &QuotaManager{
+		limit:   limit,
+		pending: make(map[string]*quotaFile),
+	}
+}
+
+// Usage returns the bytes of flushed files which have not been acked.
+func (q *QuotaManager) Usage() int64 {
+	q.Lock()
+	defer q.Unlock()
+	return q.usage
+}
+
+// Exceeded returns true when usage is over the limit.
+func (q *QuotaManager) Exceeded() bool {
+	q.Lock()
+	defer q.Unlock()
+	return q.usage > q.limit
+}
+
+// Whether the file may be evicted.
+func (p *quotaFile) evictable() bool {
+	s, ok := p.b.FileState(p.f.Path)
+	return ok && s.State.evictable()
+}
+
+// Account for file `f` flushed by `b`.
+func (q *QuotaManager) add(b *Buffer, f *Flush) {
+	q.Lock()
+	defer q.Unlock()
+
+	if _, ok := q.pending[f.Path]; ok {
+		return
+	}
+
+	q.pending[f.Path] = &quotaFile{b: b, f: f}
+	q.usage += f.Bytes
+}
+
+// Release file `f` once acked or dropped.
+func (q *QuotaManager) remove(f *Flush) {
+	q.Lock()
+	defer q.Unlock()
+
+	if p, ok := q.pending[f.Path]; ok {
+		delete(q.pending, f.Path)
+		q.usage -= p.f.Bytes
+	}
+}
+
+// Evict files until usage is within the limit, skipping files taken by
+// consumers.
+func (q *QuotaManager) enforce() {
+	q.Lock()
+	defer q.Unlock()
+
+	for q.usage > q.limit {
+		var victim *quotaFile
+		for _, p := range q.pending {
+			switch {
+			case !p.evictable():
+			case victim == nil:
+				victim = p
+			case p.b.QuotaPriority < victim.b.QuotaPriority:
+				victim = p
+			case p.b.QuotaPriority == victim.b.QuotaPriority && p.f.Closed.Before(victim.f.Closed):
+				victim = p
+			}
+		}
+
+		if victim == nil {
+			return
+		}
+
+		delete(q.pending, victim.f.Path)
+		q.usage -= victim.f.Bytes
+		victim.b.log(1, "quota exceeded, evicting %q", victim.f.Path)
+		victim.b.error(victim.b.evict(victim.f.Path))
+		victim.b.staleness.untrack(victim.f)
+		victim.b.batches.settle(victim.f.Batches, Evicted)
+
+		if victim.b.Hooks.OnDrop != nil {
+			victim.b.Hooks.OnDrop(QuotaExceeded, victim.f)
+		}
+	}
+}
+
+// Account for flushed file `f`.
+func (b *Buffer) charge(f *Flush) {
+	if b.Quota != nil && !f.Spilled && f.Data == nil {
+		b.Quota.add(b, f)
+	}
+}
+
+// Evict files when the quota is exceeded under the DropOldest policy.
+func (b *Buffer) reclaim() {
+	if b.Quota == nil || b.SpillDir != "" {
+		return
+	}
+
+	if b.Quota.Exceeded() && b.decide(QuotaExceeded) == DropOldest {
+		b.Quota.enforce()
+	}
+}
+
+// Check the quota before a write under the Error policy.
+func (b *Buffer) overQuota() error {
+	if b.Quota == nil || b.SpillDir != "" || b.Policy(QuotaExceeded) != Error || !b.Quota.Exceeded() {
+		return nil
+	}
+
+	b.decide(QuotaExceeded)
+	return ErrQuotaExceeded
+}
@@ -0,0 +1,117 @@
+package buffer
+
+import (
+	"os"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test evicting files across buffers by priority.
+func TestQuotaManager(t *testing.T) {
+	q := NewQuotaManager(12)
+
+	low, err := New("/tmp/buffer-low", &Config{
+		Queue:         make(chan *Flush, 100),
+		FlushWrites:   1,
+		Quota:         q,
+		QuotaPriority: 0,
+		Verbosity:     0,
+	})
+	assert.Equal(t, nil, err)
+
+	var dropped []*Flush
+	high, err := New("/tmp/buffer-high", &Config{
+		Queue:         make(chan *Flush, 100),
+		FlushWrites:   1,
+		Quota:         q,
+		QuotaPriority: 1,
+		Verbosity:     0,
+	})
+	assert.Equal(t, nil, err)
+
+	low.Hooks.OnDrop = func(c Condition, f *Flush) {
+		dropped = append(dropped, f)
+	}
+
+	low.Write([]byte("hello"))
+	high.Write([]byte("hello"))
+	assert.Equal(t, int64(10), q.Usage())
+
+	l := <-low.Queue
+	h := <-high.Queue
+
+	high.Write([]byte("world"))
+	assert.Equal(t, int64(10), q.Usage())
+	assert.Equal(t, 1, len(dropped))
+	assert.Equal(t, l.Path, dropped[0].Path)
+
+	_, err = os.Stat(l.Path)
+	assert.Equal(t, true, os.IsNotExist(err))
+
+	high.Ack(h)
+	assert.Equal(t, int64(5), q.Usage())
+
+	assert.Equal(t, nil, low.Close())
+	assert.Equal(t, nil, high.Close())
+}
+
+// Test failing writes when the quota is exceeded.
+func TestQuotaManager_error(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1,
+		Quota:       NewQuotaManager(4),
+		Policies:    map[Condition]Policy{QuotaExceeded: Error},
+		Verbosity:   0,
+	})
+	assert.Equal(t, nil, err)
+
+	_, err = b.Write([]byte("hello"))
+	assert.Equal(t, nil, err)
+
+	_, err = b.Write([]byte("hello"))
+	assert.Equal(t, ErrQuotaExceeded, err)
+
+	b.Ack(<-b.Queue)
+
+	_, err = b.Write([]byte("hello"))
+	assert.Equal(t, nil, err)
+
+	assert.Equal(t, nil, b.Close())
+}
+
+// Test files taken by consumers are not evicted.
+func TestQuotaManager_delivering(t *testing.T) {
+	q := NewQuotaManager(12)
+
+	var dropped []*Flush
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1,
+		Quota:       q,
+		Hooks: Hooks{
+			OnDrop: func(c Condition, f *Flush) {
+				dropped = append(dropped, f)
+			},
+		},
+	})
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+	b.Write([]byte("hello"))
+
+	first := <-b.Queue
+	second := <-b.Queue
+	b.Delivering(first)
+
+	b.Write([]byte("world"))
+	assert.Equal(t, 1, len(dropped))
+	assert.Equal(t, second.Path, dropped[0].Path)
+
+	_, err = os.Stat(first.Path)
+	assert.Equal(t, nil, err)
+
+	b.Ack(first)
+	assert.Equal(t, nil, b.Close())
+}
@@ -0,0 +1,132 @@
+package buffer
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrRateLimited is returned by writes over Config.RateBytes or
+// RateWrites under the Error policy.
+var ErrRateLimited = errors.New("rate limited")
+
+// Token bucket refilled at `rate` tokens per second, holding up to one
+// second's worth.
+type tokenBucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// New token bucket of `rate` per second, nil when zero.
+func newTokenBucket(rate int64) *tokenBucket {
+	if rate == 0 {
+		return nil
+	}
+
+	return &tokenBucket{rate: float64(rate), tokens: float64(rate), last: time.Now()}
+}
+
+// Refill tokens accrued until `now`.
+func (t *tokenBucket) refill(now time.Time) {
+	t.tokens += now.Sub(t.last).Seconds() * t.rate
+	if t.tokens > t.rate {
+		t.tokens = t.rate
+	}
+	t.last = now
+}
+
+// Wait until `n` tokens are available.
+func (t *tokenBucket) wait(n float64) time.Duration {
+	if t == nil || t.tokens >= n {
+		return 0
+	}
+
+	return time.Duration((n - t.tokens) / t.rate * float64(time.Second))
+}
+
+// Take `n` tokens, going into debt when short.
+func (t *tokenBucket) take(n float64) {
+	if t != nil {
+		t.tokens -= n
+	}
+}
+
+// Write rate limits shared by a buffer and its partitions.
+type limiter struct {
+	sync.Mutex
+	writes *tokenBucket
+	bytes  *tokenBucket
+}
+
+// New limiter of the configured rates, nil when unlimited.
+func newLimiter(c *Config) *limiter {
+	if c.RateWrites == 0 && c.RateBytes == 0 {
+		return nil
+	}
+
+	return &limiter{
+		writes: newTokenBucket(c.RateWrites),
+		bytes:  newTokenBucket(c.RateBytes),
+	}
+}
+
+// Take tokens for `writes` of `bytes` when available, or return the wait
+// until they are. With `reserve` they are taken regardless, to be paid
+// off by waiting.
+func (l *limiter) take(writes, bytes int, reserve bool) time.Duration {
+	l.Lock()
+	defer l.Unlock()
+
+	now := time.Now()
+	for _, t := range []*tokenBucket{l.writes, l.bytes} {
+		if t != nil {
+			t.refill(now)
+		}
+	}
+
+	wait := l.writes.wait(float64(writes))
+	if w := l.bytes.wait(float64(bytes)); w > wait {
+		wait = w
+	}
+
+	if wait == 0 || reserve {
+		l.writes.take(float64(writes))
+		l.bytes.take(float64(bytes))
+	}
+
+	return wait
+}
+
+// Apply the rate limits to `writes` of `bytes`, waiting under the Block
+// policy or failing with ErrWouldBlock when `try` is set. It returns true
+// when the writes are to be dropped under the DropNewest policy.
+func (b *Buffer) throttle(writes, bytes int, try bool) (bool, error) {
+	if b.limiter == nil {
+		return false, nil
+	}
+
+	bytes += writes * len(b.Delimiter)
+	if b.limiter.take(writes, bytes, false) == 0 {
+		return false, nil
+	}
+
+	if try && b.Policy(RateLimited) == Block {
+		return false, ErrWouldBlock
+	}
+
+	switch b.decide(RateLimited) {
+	case Block:
+		time.Sleep(b.limiter.take(writes, bytes, true))
+		return false, nil
+	case DropNewest:
+		if b.Hooks.OnDrop != nil {
+			b.Hooks.OnDrop(RateLimited, nil)
+		}
+		return true, nil
+	default:
+		atomic.AddInt64(&b.errors, 1)
+		return false, ErrRateLimited
+	}
+}
@@ -0,0 +1,68 @@
+package buffer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test blocking writes over the rate.
+func TestBuffer_RateLimit(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 100,
+		RateWrites:  10,
+	})
+
+	assert.Equal(t, nil, err)
+
+	start := time.Now()
+	for i := 0; i < 15; i++ {
+		_, err = b.Write([]byte("hello"))
+		assert.Equal(t, nil, err)
+	}
+
+	assert.Equal(t, true, time.Since(start) >= 400*time.Millisecond)
+	assert.Equal(t, int64(15), b.Writes())
+
+	_, err = b.TryWrite([]byte("hello"))
+	assert.Equal(t, ErrWouldBlock, err)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test failing and dropping writes over the rate.
+func TestBuffer_RateLimit_policies(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 100,
+		RateBytes:   10,
+		Policies:    map[Condition]Policy{RateLimited: Error},
+	})
+
+	assert.Equal(t, nil, err)
+
+	_, err = b.Write([]byte("hello"))
+	assert.Equal(t, nil, err)
+
+	_, err = b.Write([]byte("world"))
+	assert.Equal(t, nil, err)
+
+	n, err := b.Write([]byte("!"))
+	assert.Equal(t, ErrRateLimited, err)
+	assert.Equal(t, 0, n)
+
+	b.SetPolicy(RateLimited, DropNewest)
+	n, err = b.Write([]byte("!"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, int64(10), b.Bytes())
+
+	assert.Equal(t, int64(1), b.Decisions()[Decision{RateLimited, Error}])
+	assert.Equal(t, int64(1), b.Decisions()[Decision{RateLimited, DropNewest}])
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
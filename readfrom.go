@@ -0,0 +1,93 @@
+package buffer
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// Chunk size of ReadFrom without a BufferSize.
+const readChunk = 32 << 10
+
+// ReadFrom implements io.ReaderFrom, writing chunks of up to BufferSize
+// and MaxWriteSize read from `r` until io.EOF. Each chunk counts as one
+// write towards the flush thresholds, so records may span files unless
+// FlushWrites and FlushBytes are zero.
+// With a Delimiter the stream is instead split into records on it, each
+// written as one write, ending with a final record missing its delimiter.
+func (b *Buffer) ReadFrom(r io.Reader) (n int64, err error) {
+	if len(b.Delimiter) != 0 {
+		return b.readRecords(r)
+	}
+
+	size := b.BufferSize
+	if size == 0 {
+		size = readChunk
+	}
+
+	if b.MaxWriteSize != 0 && size > b.MaxWriteSize {
+		size = b.MaxWriteSize
+	}
+
+	buf := make([]byte, size)
+	for {
+		m, rerr := r.Read(buf)
+		if m > 0 {
+			w, err := b.Write(buf[:m])
+			n += int64(w)
+			if err != nil {
+				return n, err
+			}
+		}
+
+		if rerr == io.EOF {
+			return n, nil
+		}
+
+		if rerr != nil {
+			return n, rerr
+		}
+	}
+}
+
+// Write the records of `r` split on the Delimiter, returning the bytes
+// read including delimiters.
+func (b *Buffer) readRecords(r io.Reader) (n int64, err error) {
+	delim := b.Delimiter
+
+	max := b.MaxWriteSize
+	if max == 0 {
+		max = maxRecord
+	}
+
+	tail := false
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, max+len(delim))
+	s.Split(func(data []byte, eof bool) (int, []byte, error) {
+		if i := bytes.Index(data, delim); i >= 0 {
+			return i + len(delim), data[:i], nil
+		}
+
+		if eof && len(data) != 0 {
+			tail = true
+			return len(data), data, nil
+		}
+
+		return 0, nil, nil
+	})
+
+	for s.Scan() {
+		record := s.Bytes()
+		_, err = b.Write(record)
+		if err != nil {
+			return n, err
+		}
+
+		n += int64(len(record))
+		if !tail {
+			n += int64(len(delim))
+		}
+	}
+
+	return n, s.Err()
+}
@@ -0,0 +1,92 @@
+package buffer
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test copying readers into the buffer.
+func TestBuffer_ReadFrom(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:      make(chan *Flush, 100),
+		FlushBytes: 64 << 10,
+	})
+
+	assert.Equal(t, nil, err)
+
+	data := strings.Repeat("hello world\n", 10<<10)
+	n, err := io.Copy(b, struct{ io.Reader }{strings.NewReader(data)})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(len(data)), n)
+
+	flush := <-b.Queue
+	assert.Equal(t, Bytes, flush.Reason)
+	assert.Equal(t, int64(2), flush.Writes)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	rest := <-b.Queue
+	first, err := ioutil.ReadFile(flush.Path)
+	assert.Equal(t, nil, err)
+	second, err := ioutil.ReadFile(rest.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, data, string(first)+string(second))
+}
+
+// Test copying delimited readers record by record.
+func TestBuffer_ReadFrom_Delimiter(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 2,
+		Delimiter:   []byte("\n"),
+		BufferSize:  4,
+	})
+
+	assert.Equal(t, nil, err)
+
+	n, err := b.ReadFrom(strings.NewReader("hello\nworld\nagain"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(17), n)
+
+	flush := <-b.Queue
+	assert.Equal(t, int64(2), flush.Writes)
+
+	data, err := ioutil.ReadFile(flush.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "hello\nworld\n", string(data))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	flush = <-b.Queue
+	data, err = ioutil.ReadFile(flush.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "again\n", string(data))
+}
+
+// Test copying readers in chunks within MaxWriteSize.
+func TestBuffer_ReadFrom_MaxWriteSize(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:        make(chan *Flush, 100),
+		FlushWrites:  100,
+		MaxWriteSize: 10,
+	})
+
+	assert.Equal(t, nil, err)
+
+	data := strings.Repeat("a", 95)
+	n, err := b.ReadFrom(strings.NewReader(data))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(95), n)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	flush := <-b.Queue
+	assert.Equal(t, int64(10), flush.Writes)
+}
@@ -0,0 +1,161 @@
+package buffer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// ErrTruncated is returned for a final record cut short, such as by a
+// crash mid-write.
+var ErrTruncated = errors.New("truncated record")
+
+// Largest record read by Records without a MaxWriteSize.
+const maxRecord = 1 << 20
+
+// RecordScanner reads the records of a flushed file, see Records.
+type RecordScanner struct {
+	r      *reader
+	lines  *bufio.Scanner
+	frames *DelimitedReader
+	record []byte
+	count  int64
+	err    error
+}
+
+// Records returns a scanner of the records of the flushed file at `path`,
+// written by a buffer with the configuration `c`. Records are split on
+// Config.Delimiter, or read as length-delimited when it is unset, up to
+// Config.MaxWriteSize or 1 MiB. The file is unwrapped as with Open,
+// using Config.KeyProvider for encrypted files. A torn final record fails
+// with ErrTruncated, and files with trailers fail with ErrChecksum when
+// their data or record count does not match.
+func Records(path string, c *Config) (*RecordScanner, error) {
+	return RecordsOf(&Flush{Path: path}, c)
+}
+
+// RecordsOf returns a scanner of the records of the file of `f` like
+// Records, reading Flush.Data of files held in memory.
+func RecordsOf(f *Flush, c *Config) (*RecordScanner, error) {
+	if c == nil {
+		c = &Config{}
+	}
+
+	src, size, err := openData(f)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := unwrap(src, size, f.Path, c.KeyProvider)
+	if err != nil {
+		src.Close()
+		return nil, err
+	}
+
+	max := c.MaxWriteSize
+	if max == 0 {
+		max = maxRecord
+	}
+
+	s := &RecordScanner{r: r}
+	if len(c.Delimiter) == 0 {
+		s.frames = NewDelimitedReader(r, max)
+		return s, nil
+	}
+
+	s.lines = bufio.NewScanner(r)
+	s.lines.Buffer(nil, max+len(c.Delimiter))
+	s.lines.Split(splitOn(c.Delimiter))
+	return s, nil
+}
+
+// Split function of records ending with `delim`.
+func splitOn(delim []byte) bufio.SplitFunc {
+	return func(data []byte, eof bool) (int, []byte, error) {
+		if i := bytes.Index(data, delim); i >= 0 {
+			return i + len(delim), data[:i], nil
+		}
+
+		if eof && len(data) != 0 {
+			return 0, nil, ErrTruncated
+		}
+
+		return 0, nil, nil
+	}
+}
+
+// Scan advances to the next record, returning false after the last or
+// on error.
+func (s *RecordScanner) Scan() bool {
+	if s.err != nil {
+		return false
+	}
+
+	if s.lines != nil {
+		if s.lines.Scan() {
+			s.record = s.lines.Bytes()
+			s.count++
+			return true
+		}
+
+		s.err = s.lines.Err()
+	} else {
+		s.record, s.err = s.frames.Next()
+		if s.err == nil {
+			s.count++
+			return true
+		}
+
+		if s.err == io.ErrUnexpectedEOF {
+			s.err = ErrTruncated
+		}
+	}
+
+	if s.err == nil || s.err == io.EOF {
+		s.err = s.verify()
+	}
+
+	if s.err == nil {
+		s.err = io.EOF
+	}
+
+	s.record = nil
+	return false
+}
+
+// Verify the trailer, if any, against the data and records read.
+func (s *RecordScanner) verify() error {
+	t := s.r.trailer
+	if t == nil {
+		return nil
+	}
+
+	crc := binary.BigEndian.Uint32(t[len(trailerMagic):])
+	count := int64(binary.BigEndian.Uint64(t[len(trailerMagic)+4:]))
+	if crc != s.r.crc.Sum32() || count != s.count {
+		return ErrChecksum
+	}
+
+	return nil
+}
+
+// Record returns the current record, valid until the next call to Scan.
+func (s *RecordScanner) Record() []byte {
+	return s.record
+}
+
+// Err returns the error which stopped the scan, nil at the end of file.
+func (s *RecordScanner) Err() error {
+	if s.err == io.EOF {
+		return nil
+	}
+
+	return s.err
+}
+
+// Close the file.
+func (s *RecordScanner) Close() error {
+	return s.r.Close()
+}
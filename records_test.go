@@ -0,0 +1,95 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Scan all records of `path`, returning them and the scan error.
+func scanRecords(t *testing.T, path string, c *Config) ([]string, error) {
+	s, err := Records(path, c)
+	assert.Equal(t, nil, err)
+	defer s.Close()
+
+	var records []string
+	for s.Scan() {
+		records = append(records, string(s.Record()))
+	}
+
+	return records, s.Err()
+}
+
+// Test scanning delimited records with a trailer.
+func TestRecords(t *testing.T) {
+	c := &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 2,
+		Delimiter:   []byte("\n"),
+		Trailer:     true,
+	}
+
+	b, err := New("/tmp/buffer", c)
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+	b.Write([]byte("world"))
+
+	flush := <-b.Queue
+
+	records, err := scanRecords(t, flush.Path, c)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"hello", "world"}, records)
+
+	data, err := ioutil.ReadFile(flush.Path)
+	assert.Equal(t, nil, err)
+
+	data[0] = 'j'
+	err = ioutil.WriteFile(flush.Path, data, 0666)
+	assert.Equal(t, nil, err)
+
+	_, err = scanRecords(t, flush.Path, c)
+	assert.Equal(t, ErrChecksum, err)
+
+	err = os.Truncate(flush.Path, 8)
+	assert.Equal(t, nil, err)
+
+	records, err = scanRecords(t, flush.Path, c)
+	assert.Equal(t, ErrTruncated, err)
+	assert.Equal(t, []string{"jello"}, records)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test scanning length-delimited records.
+func TestRecords_delimited(t *testing.T) {
+	c := &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 2,
+	}
+
+	b, err := New("/tmp/buffer", c)
+	assert.Equal(t, nil, err)
+
+	b.WriteDelimited([]byte("hello"))
+	b.WriteDelimited([]byte("world\n"))
+
+	flush := <-b.Queue
+
+	records, err := scanRecords(t, flush.Path, nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"hello", "world\n"}, records)
+
+	err = os.Truncate(flush.Path, 9)
+	assert.Equal(t, nil, err)
+
+	records, err = scanRecords(t, flush.Path, nil)
+	assert.Equal(t, ErrTruncated, err)
+	assert.Equal(t, []string{"hello"}, records)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
@@ -0,0 +1,135 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// Backlog progress of recovered files.
+type backlog struct {
+	total     int64
+	delivered int64
+}
+
+// Backlog returns the number of recovered files published and the total
+// found on startup when Config.Recover is enabled.
+func (b *Buffer) Backlog() (delivered, total int64) {
+	return atomic.LoadInt64(&b.backlog.delivered), atomic.LoadInt64(&b.backlog.total)
+}
+
+// Flushed files left by previous runs, including in SpillDir, oldest
+// first.
+func (b *Buffer) recoverable() ([]*Flush, error) {
+	dir := filepath.Dir(b.path)
+	if b.OutDir != "" {
+		dir = b.OutDir
+	}
+
+	files, err := b.recoverableIn(dir, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.SpillDir != "" && b.SpillDir != dir {
+		spilled, err := b.recoverableIn(b.SpillDir, true)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		files = append(files, spilled...)
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].Closed.Before(files[j].Closed)
+	})
+
+	b.log(1, "recovered %d files", len(files))
+	return files, nil
+}
+
+// Flushed files left in `dir`, which is SpillDir when `spilled`.
+func (b *Buffer) recoverableIn(dir string, spilled bool) ([]*Flush, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := filepath.Base(b.path) + "."
+	suffix := b.Suffix
+	if suffix == "" && b.Staging == "" {
+		suffix = ".closed"
+	}
+
+	var files []*Flush
+	for _, info := range infos {
+		name := info.Name()
+
+		switch {
+		case info.IsDir():
+			continue
+		case !strings.HasPrefix(name, prefix):
+			continue
+		case !strings.HasSuffix(name, suffix):
+			continue
+		case b.Staging != "" && strings.HasSuffix(name, b.Staging):
+			continue
+		case b.Sidecar && strings.HasSuffix(name, "."+b.Checksum):
+			continue
+		case b.MetaFile && strings.HasSuffix(name, MetaFileSuffix):
+			continue
+		case b.Dictionary && strings.HasSuffix(name, DictionarySuffix):
+			continue
+		}
+
+		files = append(files, &Flush{
+			Version: FlushVersion,
+			Reason:  Recovered,
+			Path:    filepath.Join(dir, name),
+			Bytes:   info.Size(),
+			Closed:  info.ModTime(),
+			Spilled: spilled,
+		})
+	}
+
+	return files, nil
+}
+
+// Interleave `files`, ordered oldest first, publishing the newest file
+// after every `ratio` oldest, or oldest first when ratio is zero.
+func interleave(files []*Flush, ratio int) []*Flush {
+	if ratio == 0 {
+		return files
+	}
+
+	out := make([]*Flush, 0, len(files))
+	i, j := 0, len(files)-1
+
+	for i <= j {
+		out = append(out, files[j])
+		j--
+
+		for n := 0; n < ratio && i <= j; n++ {
+			out = append(out, files[i])
+			i++
+		}
+	}
+
+	return out
+}
+
+// Deliver recovered files in the background.
+func (b *Buffer) deliver(files []*Flush) {
+	b.label("recover")
+
+	for _, f := range files {
+		if b.ExpireBacklog {
+			b.error(b.Expire(f))
+		}
+
+		b.error(b.publish(f))
+		atomic.AddInt64(&b.backlog.delivered, 1)
+	}
+}
@@ -0,0 +1,77 @@
+package buffer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test interleaving of recovered files.
+func TestInterleave(t *testing.T) {
+	var files []*Flush
+	for i := 0; i < 7; i++ {
+		files = append(files, &Flush{Path: fmt.Sprint(i)})
+	}
+
+	var paths []string
+	for _, f := range interleave(files, 2) {
+		paths = append(paths, f.Path)
+	}
+
+	assert.Equal(t, []string{"6", "0", "1", "5", "2", "3", "4"}, paths)
+}
+
+// Test recovery of files left by previous runs.
+func TestBuffer_Recover(t *testing.T) {
+	os.RemoveAll("/tmp/buffer-recover")
+	os.MkdirAll("/tmp/buffer-recover", 0755)
+
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		path := fmt.Sprintf("/tmp/buffer-recover/buffer.1.1.%d.closed", i)
+		ioutil.WriteFile(path, []byte("hello"), 0644)
+		os.Chtimes(path, now, now.Add(time.Duration(i-10)*time.Second))
+	}
+
+	ioutil.WriteFile("/tmp/buffer-recover/other.1.1.1.closed", []byte("hello"), 0644)
+
+	b, err := New("/tmp/buffer-recover/buffer", &Config{
+		Queue:        make(chan *Flush, 100),
+		FlushWrites:  10,
+		Recover:      true,
+		RecoverRatio: 1,
+		Verbosity:    0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	var paths []string
+	for i := 0; i < 3; i++ {
+		flush := <-b.Queue
+		assert.Equal(t, Recovered, flush.Reason)
+		assert.Equal(t, int64(5), flush.Bytes)
+		paths = append(paths, flush.Path)
+	}
+
+	assert.Equal(t, []string{
+		"/tmp/buffer-recover/buffer.1.1.2.closed",
+		"/tmp/buffer-recover/buffer.1.1.0.closed",
+		"/tmp/buffer-recover/buffer.1.1.1.closed",
+	}, paths)
+
+	for {
+		delivered, total := b.Backlog()
+		assert.Equal(t, int64(3), total)
+		if delivered == total {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
@@ -0,0 +1,60 @@
+package buffer
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Reopen a closed buffer with the same path and config, including its
+// keyed partitions, preserving sequences, stats and the staleness bound.
+func (b *Buffer) Reopen() error {
+	b.Lock()
+	defer b.Unlock()
+
+	switch {
+	case b.draining:
+		return fmt.Errorf("drained buffers cannot be reopened")
+	case !b.stopped:
+		return fmt.Errorf("buffer is open")
+	}
+
+	b.log(1, "reopening")
+
+	if b.manifest != nil && b.key == "" {
+		err := b.manifest.reopen()
+		if err != nil {
+			return err
+		}
+	}
+
+	if b.Segments != 0 {
+		err := b.preallocate()
+		if err != nil {
+			return err
+		}
+	}
+
+	err := b.open()
+	if err != nil {
+		return err
+	}
+
+	b.stopped = false
+	b.quit = make(chan struct{})
+	b.halt = sync.Once{}
+
+	b.start()
+
+	if d := b.staleness.current(); d != 0 && b.key == "" {
+		b.MaxStaleness(d)
+	}
+
+	for _, k := range b.keys {
+		err := k.Reopen()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package buffer
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test reopening a closed buffer.
+func TestBuffer_Reopen(t *testing.T) {
+	os.Remove("/tmp/buffer.seq")
+
+	b, err := New("/tmp/buffer", &Config{
+		Queue:         make(chan *Flush, 100),
+		FlushWrites:   2,
+		FlushInterval: time.Hour,
+		SeqFile:       "/tmp/buffer.seq",
+		Async:         4,
+		Verbosity:     0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	err = b.Reopen()
+	assert.Equal(t, "buffer is open", err.Error())
+
+	b.Write([]byte("hello"))
+	b.WriteKeyed("tobi", []byte("hello"))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	assert.Equal(t, int64(1), (<-b.Queue).Seq)
+	assert.Equal(t, int64(1), (<-b.Queue).Seq)
+
+	_, err = b.Write([]byte("hello"))
+	assert.Equal(t, ErrClosed, err)
+
+	err = b.Reopen()
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+	b.Write([]byte("world"))
+	b.WriteKeyed("tobi", []byte("hello"))
+
+	err = b.Barrier()
+	assert.Equal(t, nil, err)
+
+	f := <-b.Queue
+	assert.Equal(t, int64(2), f.Writes)
+	assert.Equal(t, int64(3), f.Seq)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	f = <-b.Queue
+	assert.Equal(t, "tobi", f.Key)
+	assert.Equal(t, int64(3), f.Seq)
+
+	s := b.Stats()
+	assert.Equal(t, int64(5), s.Writes)
+	assert.Equal(t, int64(4), s.Flushes)
+}
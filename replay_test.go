@@ -0,0 +1,70 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test replaying flushed files in order, filtered by reason and time.
+func TestReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replay")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+
+	b, err := New(filepath.Join(dir, "buffer"), &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 2,
+		MetaFile:    true,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("first"))
+	b.Write([]byte("file"))
+	first := <-b.Queue
+
+	b.Write([]byte("second"))
+	b.FlushWithReason("deploy")
+	second := <-b.Queue
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "old.closed"), []byte("old"), 0666)
+	assert.Equal(t, nil, err)
+
+	past := time.Now().Add(-time.Hour)
+	err = os.Chtimes(filepath.Join(dir, "old.closed"), past, past)
+	assert.Equal(t, nil, err)
+
+	queue := make(chan *Flush, 10)
+	n, err := Replay(dir, queue, nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 3, n)
+
+	f := <-queue
+	assert.Equal(t, filepath.Join(dir, "old.closed"), f.Path)
+	assert.Equal(t, Recovered, f.Reason)
+	assert.Equal(t, int64(3), f.Bytes)
+
+	f = <-queue
+	assert.Equal(t, first.Path, f.Path)
+	assert.Equal(t, Writes, f.Reason)
+	assert.Equal(t, int64(2), f.Writes)
+
+	f = <-queue
+	assert.Equal(t, second.Path, f.Path)
+	assert.Equal(t, Reason("deploy"), f.Reason)
+
+	n, err = Replay(dir, queue, &ReplayFilter{Reasons: []Reason{Writes}, Since: past.Add(time.Minute)})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, n)
+
+	f = <-queue
+	assert.Equal(t, first.Path, f.Path)
+}
@@ -0,0 +1,93 @@
+package buffer
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+	"time"
+)
+
+// Transient disk errors retried with WriteRetries.
+var transients = []error{
+	syscall.EIO,
+	syscall.ENOSPC,
+	syscall.EAGAIN,
+	syscall.EINTR,
+}
+
+// Whether `err` is a transient disk error.
+func transient(err error) bool {
+	for _, t := range transients {
+		if errors.Is(err, t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Writer retrying transient failures of the file at `path`, resuming
+// from the bytes already written, and making room when the disk is full.
+type retrying struct {
+	b    *Buffer
+	w    io.Writer
+	path string
+}
+
+// Write implements io.Writer.
+func (r retrying) Write(p []byte) (int, error) {
+	var n int
+	for attempt := 1; ; attempt++ {
+		m, err := r.w.Write(p[n:])
+		n += m
+		if err == nil {
+			return n, nil
+		}
+
+		if errors.Is(err, syscall.ENOSPC) && r.b.makeRoom(attempt) {
+			continue
+		}
+
+		if !transient(err) || attempt > r.b.WriteRetries {
+			if attempt == 1 {
+				return n, err
+			}
+
+			err = &DiskError{Op: "write", Path: r.path, Attempts: attempt, Err: err}
+			r.b.report(err)
+			return n, err
+		}
+
+		r.b.log(1, "retrying write to %s after %s", r.path, err)
+		time.Sleep(r.b.backoff().Backoff(attempt))
+	}
+}
+
+// Create the next file, retrying transient failures.
+func (b *Buffer) createRetried() (*os.File, int64, error) {
+	for attempt := 1; ; attempt++ {
+		f, seq, err := b.create()
+		if err == nil || b.WriteRetries == 0 || !transient(err) {
+			return f, seq, err
+		}
+
+		if attempt > b.WriteRetries {
+			err = &DiskError{Op: "create", Path: b.path, Attempts: attempt, Err: err}
+			b.error(err)
+			return nil, 0, err
+		}
+
+		b.log(1, "retrying create after %s", err)
+		time.Sleep(b.backoff().Backoff(attempt))
+	}
+}
+
+// Unwind the counters of a failed write of which `n` bytes were not
+// accepted, so they reflect only the data on its way to disk.
+func (b *Buffer) unwind(n int64) {
+	b.writes--
+	b.bytes -= n
+	b.total.writes--
+	b.total.bytes -= n
+}
@@ -0,0 +1,123 @@
+package buffer
+
+import (
+	"errors"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// Writer failing its first writes.
+type flaky struct {
+	fails int
+	err   error
+	data  []byte
+}
+
+func (f *flaky) Write(p []byte) (int, error) {
+	if f.fails > 0 {
+		f.fails--
+		f.data = append(f.data, p[:1]...)
+		return 1, f.err
+	}
+
+	f.data = append(f.data, p...)
+	return len(p), nil
+}
+
+// Test retrying transient write errors.
+func TestBuffer_WriteRetries(t *testing.T) {
+	var reported []error
+	b, err := New("/tmp/buffer", &Config{
+		FlushWrites:   100,
+		WriteRetries:  2,
+		RenameBackoff: time.Millisecond,
+		Hooks:         Hooks{OnError: func(err error) { reported = append(reported, err) }},
+	})
+
+	assert.Equal(t, nil, err)
+
+	w := &flaky{fails: 2, err: syscall.EIO}
+	n, err := retrying{b: b, w: w, path: "file"}.Write([]byte("hello"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, "hello", string(w.data))
+	assert.Equal(t, 0, len(reported))
+
+	w = &flaky{fails: 3, err: syscall.ENOSPC}
+	_, err = retrying{b: b, w: w, path: "file"}.Write([]byte("hello"))
+	assert.Equal(t, true, errors.Is(err, syscall.ENOSPC))
+	assert.Equal(t, 1, len(reported))
+
+	e := reported[0].(*DiskError)
+	assert.Equal(t, "write", e.Op)
+	assert.Equal(t, 3, e.Attempts)
+
+	w = &flaky{fails: 1, err: io.ErrShortWrite}
+	_, err = retrying{b: b, w: w, path: "file"}.Write([]byte("hello"))
+	assert.Equal(t, io.ErrShortWrite, err)
+	assert.Equal(t, 1, len(reported))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test counters are unwound when writes fail.
+func TestBuffer_WriteRetries_unwind(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:         make(chan *Flush, 1),
+		FlushWrites:   100,
+		WriteRetries:  1,
+		RenameBackoff: time.Millisecond,
+	})
+
+	assert.Equal(t, nil, err)
+
+	_, err = b.Write([]byte("hello"))
+	assert.Equal(t, nil, err)
+
+	b.Lock()
+	w := b.w
+	b.w = retrying{b: b, w: &flaky{fails: 5, err: syscall.EIO}, path: "file"}
+	_, err = b.write([]byte("world"))
+	b.w = w
+	b.Unlock()
+
+	assert.Equal(t, true, errors.Is(err, syscall.EIO))
+	assert.Equal(t, int64(1), b.Writes())
+	assert.Equal(t, int64(7), b.Bytes())
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test failed writes are counted once.
+func TestBuffer_WriteRetries_errors(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:         make(chan *Flush, 1),
+		FlushWrites:   100,
+		WriteRetries:  1,
+		RenameBackoff: time.Millisecond,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Lock()
+	w := b.w
+	b.w = retrying{b: b, w: &flaky{fails: 5, err: syscall.EIO}, path: "file"}
+	b.Unlock()
+
+	_, err = b.Write([]byte("world"))
+	assert.Equal(t, true, errors.Is(err, syscall.EIO))
+	assert.Equal(t, int64(1), b.Stats().Errors)
+
+	b.Lock()
+	b.w = w
+	b.Unlock()
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
@@ -0,0 +1,299 @@
+// Package s3buffer serves flushed files over a minimal read-only
+// S3-compatible API, so tools which speak S3 can read files which are
+// not yet uploaded or archived locally, during outages or testing.
+//
+// Path-style requests are supported: ListBuckets, ListObjects (v1 and
+// v2, with prefix, delimiter and pagination), GetObject with ranges,
+// and HeadObject. Requests are not authenticated.
+package s3buffer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Timestamp format of listings.
+const timeFormat = "2006-01-02T15:04:05.000Z"
+
+// Server serves the files of a directory as a bucket.
+type Server struct {
+	Bucket string // Bucket name
+	Dir    string // Directory served, such as Config.OutDir or Config.DoneDir
+}
+
+// New server for `dir` as `bucket`.
+func New(bucket, dir string) *Server {
+	return &Server{Bucket: bucket, Dir: dir}
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		s.error(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "read-only")
+		return
+	}
+
+	p := strings.TrimPrefix(r.URL.Path, "/")
+	bucket, key := p, ""
+	if i := strings.IndexByte(p, '/'); i != -1 {
+		bucket, key = p[:i], p[i+1:]
+	}
+
+	switch {
+	case bucket == "":
+		s.buckets(w)
+	case bucket != s.Bucket:
+		s.error(w, http.StatusNotFound, "NoSuchBucket", bucket)
+	case key == "":
+		s.list(w, r)
+	default:
+		s.get(w, r, key)
+	}
+}
+
+// Bucket listing.
+type listAllMyBucketsResult struct {
+	XMLName xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListAllMyBucketsResult"`
+	Buckets []bucket `xml:"Buckets>Bucket"`
+}
+
+// Bucket entry.
+type bucket struct {
+	Name         string
+	CreationDate string
+}
+
+// Object listing.
+type listBucketResult struct {
+	XMLName               xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult"`
+	Name                  string
+	Prefix                string
+	Delimiter             string `xml:",omitempty"`
+	Marker                string `xml:",omitempty"`
+	NextMarker            string `xml:",omitempty"`
+	StartAfter            string `xml:",omitempty"`
+	ContinuationToken     string `xml:",omitempty"`
+	NextContinuationToken string `xml:",omitempty"`
+	KeyCount              int    `xml:",omitempty"`
+	MaxKeys               int
+	IsTruncated           bool
+	Contents              []object
+	CommonPrefixes        []prefix `xml:",omitempty"`
+}
+
+// Object entry.
+type object struct {
+	Key          string
+	LastModified string
+	ETag         string
+	Size         int64
+	StorageClass string
+}
+
+// Common prefix entry.
+type prefix struct {
+	Prefix string
+}
+
+// Error response.
+type errorResponse struct {
+	XMLName  xml.Name `xml:"Error"`
+	Code     string
+	Message  string
+	Resource string `xml:",omitempty"`
+}
+
+// List the bucket.
+func (s *Server) buckets(w http.ResponseWriter) {
+	info, err := os.Stat(s.Dir)
+	if err != nil {
+		s.error(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+
+	s.xml(w, http.StatusOK, listAllMyBucketsResult{
+		Buckets: []bucket{{Name: s.Bucket, CreationDate: info.ModTime().UTC().Format(timeFormat)}},
+	})
+}
+
+// List objects.
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v2 := q.Get("list-type") == "2"
+
+	res := listBucketResult{
+		Name:      s.Bucket,
+		Prefix:    q.Get("prefix"),
+		Delimiter: q.Get("delimiter"),
+		MaxKeys:   1000,
+	}
+
+	if n, err := strconv.Atoi(q.Get("max-keys")); err == nil && n >= 0 && n < res.MaxKeys {
+		res.MaxKeys = n
+	}
+
+	after := q.Get("marker")
+	if v2 {
+		res.StartAfter = q.Get("start-after")
+		res.ContinuationToken = q.Get("continuation-token")
+		after = res.StartAfter
+		if res.ContinuationToken != "" {
+			after = res.ContinuationToken
+		}
+	} else {
+		res.Marker = after
+	}
+
+	objects, err := s.objects()
+	if err != nil {
+		s.error(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+
+	seen := make(map[string]bool)
+	last := ""
+
+	for _, o := range objects {
+		if !strings.HasPrefix(o.Key, res.Prefix) || o.Key <= after {
+			continue
+		}
+
+		if res.KeyCount == res.MaxKeys {
+			res.IsTruncated = true
+			break
+		}
+
+		if res.Delimiter != "" {
+			rest := o.Key[len(res.Prefix):]
+			if i := strings.Index(rest, res.Delimiter); i != -1 {
+				p := res.Prefix + rest[:i+len(res.Delimiter)]
+				if !seen[p] {
+					seen[p] = true
+					res.CommonPrefixes = append(res.CommonPrefixes, prefix{p})
+					res.KeyCount++
+				}
+				last = o.Key
+				continue
+			}
+		}
+
+		res.Contents = append(res.Contents, o)
+		res.KeyCount++
+		last = o.Key
+	}
+
+	if res.IsTruncated {
+		if v2 {
+			res.NextContinuationToken = last
+		} else {
+			res.NextMarker = last
+		}
+	}
+
+	if !v2 {
+		res.KeyCount = 0
+	}
+
+	s.xml(w, http.StatusOK, res)
+}
+
+// Objects of the directory sorted by key.
+func (s *Server) objects() ([]object, error) {
+	var objects []object
+	err := filepath.Walk(s.Dir, s.visit(&objects))
+
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+
+	return objects, err
+}
+
+// Walk function appending regular files to `objects`, skipping entries
+// renamed or removed while walking, as flushed files are by consumers.
+func (s *Server) visit(objects *[]object) filepath.WalkFunc {
+	return func(p string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) && p != s.Dir {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.Dir, p)
+		if err != nil {
+			return err
+		}
+
+		*objects = append(*objects, object{
+			Key:          filepath.ToSlash(rel),
+			LastModified: info.ModTime().UTC().Format(timeFormat),
+			ETag:         etag(info),
+			Size:         info.Size(),
+			StorageClass: "STANDARD",
+		})
+
+		return nil
+	}
+}
+
+// Get or head an object.
+func (s *Server) get(w http.ResponseWriter, r *http.Request, key string) {
+	clean := path.Clean("/" + key)
+	if clean != "/"+key {
+		s.error(w, http.StatusBadRequest, "InvalidArgument", key)
+		return
+	}
+
+	f, err := os.Open(filepath.Join(s.Dir, filepath.FromSlash(key)))
+	if os.IsNotExist(err) {
+		s.error(w, http.StatusNotFound, "NoSuchKey", key)
+		return
+	}
+
+	if err != nil {
+		s.error(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		s.error(w, http.StatusNotFound, "NoSuchKey", key)
+		return
+	}
+
+	w.Header().Set("ETag", etag(info))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	http.ServeContent(w, r, "", info.ModTime(), f)
+}
+
+// Write `v` as XML.
+func (s *Server) xml(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).Encode(v)
+}
+
+// Write an error response.
+func (s *Server) error(w http.ResponseWriter, status int, code, msg string) {
+	s.xml(w, status, errorResponse{Code: code, Message: msg})
+}
+
+// ETag from the size and modification time of a file.
+func etag(info os.FileInfo) string {
+	return fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size())
+}
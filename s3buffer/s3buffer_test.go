@@ -0,0 +1,86 @@
+package s3buffer
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test serving files.
+func TestServer(t *testing.T) {
+	os.RemoveAll("/tmp/s3buffer")
+	os.MkdirAll("/tmp/s3buffer/tobi", 0755)
+	ioutil.WriteFile("/tmp/s3buffer/buffer.1.closed", []byte("hello"), 0644)
+	ioutil.WriteFile("/tmp/s3buffer/buffer.2.closed", []byte("world"), 0644)
+	ioutil.WriteFile("/tmp/s3buffer/tobi/buffer.1.closed", []byte("tobi"), 0644)
+
+	s := httptest.NewServer(New("logs", "/tmp/s3buffer"))
+	defer s.Close()
+
+	get := func(path string, header ...string) (int, string) {
+		req, _ := http.NewRequest("GET", s.URL+path, nil)
+		if len(header) == 2 {
+			req.Header.Set(header[0], header[1])
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		assert.Equal(t, nil, err)
+		defer res.Body.Close()
+
+		body, _ := ioutil.ReadAll(res.Body)
+		return res.StatusCode, string(body)
+	}
+
+	status, body := get("/logs/buffer.1.closed")
+	assert.Equal(t, 200, status)
+	assert.Equal(t, "hello", body)
+
+	status, body = get("/logs/buffer.2.closed", "Range", "bytes=1-2")
+	assert.Equal(t, 206, status)
+	assert.Equal(t, "or", body)
+
+	status, body = get("/logs/missing")
+	assert.Equal(t, 404, status)
+	assert.Equal(t, true, strings.Contains(body, "<Code>NoSuchKey</Code>"))
+
+	status, body = get("/logs/../etc/passwd")
+	assert.Equal(t, true, status >= 400)
+
+	status, body = get("/other/buffer.1.closed")
+	assert.Equal(t, 404, status)
+	assert.Equal(t, true, strings.Contains(body, "<Code>NoSuchBucket</Code>"))
+
+	status, body = get("/logs?list-type=2&delimiter=/")
+	assert.Equal(t, 200, status)
+	assert.Equal(t, true, strings.Contains(body, "<Key>buffer.1.closed</Key>"))
+	assert.Equal(t, true, strings.Contains(body, "<Prefix>tobi/</Prefix>"))
+	assert.Equal(t, false, strings.Contains(body, "<Key>tobi/buffer.1.closed</Key>"))
+
+	status, body = get("/logs?list-type=2&max-keys=1")
+	assert.Equal(t, true, strings.Contains(body, "<IsTruncated>true</IsTruncated>"))
+	assert.Equal(t, true, strings.Contains(body, "<NextContinuationToken>buffer.1.closed</NextContinuationToken>"))
+
+	status, body = get("/logs?list-type=2&continuation-token=buffer.2.closed")
+	assert.Equal(t, true, strings.Contains(body, "<Key>tobi/buffer.1.closed</Key>"))
+	assert.Equal(t, true, strings.Contains(body, "<IsTruncated>false</IsTruncated>"))
+}
+
+// Test listing skips files removed while walking.
+func TestServer_removed(t *testing.T) {
+	s := New("logs", "/tmp/s3buffer")
+
+	var objects []object
+	visit := s.visit(&objects)
+
+	removed := &os.PathError{Op: "lstat", Path: "/tmp/s3buffer/buffer.1.closed", Err: os.ErrNotExist}
+	assert.Equal(t, nil, visit("/tmp/s3buffer/buffer.1.closed", nil, removed))
+	assert.Equal(t, 0, len(objects))
+
+	missing := &os.PathError{Op: "lstat", Path: "/tmp/s3buffer", Err: os.ErrNotExist}
+	assert.Equal(t, missing, visit("/tmp/s3buffer", nil, missing))
+}
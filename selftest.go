@@ -0,0 +1,91 @@
+package buffer
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Probe data written by SelfTest.
+var probe = []byte("go-disk-buffer self-test\n")
+
+// SelfTest writes, fsyncs, renames, verifies and removes a probe file
+// where the buffer spools and flushes files, confirming permissions,
+// space and rename semantics on the target filesystem. Set
+// Config.Probe to run it in New.
+func (b *Buffer) SelfTest() error {
+	dir := filepath.Dir(b.path)
+	if b.SpoolDir != "" {
+		dir = b.SpoolDir
+	}
+
+	out := dir
+	if b.OutDir != "" {
+		out = b.OutDir
+	}
+
+	name := fmt.Sprintf(".%s.selftest.%d.%d", filepath.Base(b.path), pid, b.id)
+	path := filepath.Join(dir, name)
+	target := filepath.Join(out, name+".closed")
+
+	b.log(1, "self-testing %q", path)
+	err := b.selfTest(path, target)
+	os.Remove(path)
+	os.Remove(target)
+	return err
+}
+
+// Run the self-test steps on `path`, renamed to `target`.
+func (b *Buffer) selfTest(path, target string) error {
+	f, err := b.createFile(path)
+	if err != nil {
+		return fmt.Errorf("self-test: creating probe: %w", err)
+	}
+
+	_, err = f.Write(probe)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("self-test: writing probe: %w", err)
+	}
+
+	err = f.Sync()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("self-test: syncing probe: %w", err)
+	}
+
+	err = b.mkdir(target)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("self-test: creating output directory: %w", err)
+	}
+
+	err = os.Rename(path, target)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("self-test: renaming open probe: %w", err)
+	}
+
+	err = f.Close()
+	if err != nil {
+		return fmt.Errorf("self-test: closing probe: %w", err)
+	}
+
+	err = syncDir(target)
+	if err != nil {
+		return fmt.Errorf("self-test: syncing directory: %w", err)
+	}
+
+	data, err := ioutil.ReadFile(target)
+	if err != nil {
+		return fmt.Errorf("self-test: reading probe: %w", err)
+	}
+
+	if !bytes.Equal(data, probe) {
+		return fmt.Errorf("self-test: probe read back as %q", data)
+	}
+
+	return nil
+}
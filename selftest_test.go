@@ -0,0 +1,49 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test the spool path self-test.
+func TestBuffer_SelfTest(t *testing.T) {
+	dir := "/tmp/buffer-selftest"
+	os.RemoveAll(dir)
+
+	b, err := New(dir+"/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 10,
+		OutDir:      dir + "/out",
+		Probe:       true,
+	})
+
+	assert.Equal(t, nil, err)
+
+	err = b.SelfTest()
+	assert.Equal(t, nil, err)
+
+	files, err := ioutil.ReadDir(dir + "/out")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, len(files))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	if os.Getuid() == 0 {
+		return
+	}
+
+	os.Chmod(dir+"/out", 0500)
+	defer os.Chmod(dir+"/out", 0755)
+
+	_, err = New(dir+"/buffer", &Config{
+		FlushWrites: 10,
+		OutDir:      dir + "/out",
+		Probe:       true,
+	})
+
+	assert.NotEqual(t, nil, err)
+}
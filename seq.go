@@ -0,0 +1,111 @@
+package buffer
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// Pre-created file and its sequence.
+type segment struct {
+	*os.File
+	seq int64
+}
+
+// File sequences per partition key persisted to a state file, shared by
+// a buffer and its partitions.
+type sequences struct {
+	sync.Mutex
+	path    string
+	durable bool
+	m       map[string]int64
+}
+
+// Open the sequences persisted at `path`, fsyncing updates when `durable`.
+func openSequences(path string, durable bool) (*sequences, error) {
+	s := &sequences{
+		path:    path,
+		durable: durable,
+		m:       make(map[string]int64),
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &s.m)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Next sequence for `key`, persisted before it is returned so it is
+// never reused after a crash.
+func (s *sequences) next(key string) (int64, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.m[key]++
+
+	err := s.save()
+	if err != nil {
+		s.m[key]--
+		return 0, err
+	}
+
+	return s.m[key], nil
+}
+
+// Save the sequences atomically.
+func (s *sequences) save() error {
+	data, err := json.Marshal(s.m)
+	if err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if err == nil && s.durable {
+		err = f.Sync()
+	}
+
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmp, s.path)
+	if err != nil || !s.durable {
+		return err
+	}
+
+	return syncDir(s.path)
+}
+
+// Next file sequence.
+func (b *Buffer) next() (int64, error) {
+	if b.sequences == nil {
+		return atomic.AddInt64(&b.ids, 1), nil
+	}
+
+	return b.sequences.next(b.key)
+}
@@ -0,0 +1,46 @@
+package buffer
+
+import (
+	"os"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test sequences persisted across restarts.
+func TestBuffer_SeqFile(t *testing.T) {
+	os.Remove("/tmp/buffer.seq")
+
+	config := &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1,
+		SeqFile:     "/tmp/buffer.seq",
+		Verbosity:   0,
+	}
+
+	b, err := New("/tmp/buffer", config)
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+	b.Write([]byte("world"))
+	b.WriteKeyed("tobi", []byte("hello"))
+
+	assert.Equal(t, int64(1), (<-b.Queue).Seq)
+	assert.Equal(t, int64(2), (<-b.Queue).Seq)
+	assert.Equal(t, int64(1), (<-b.Queue).Seq)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	b, err = New("/tmp/buffer", config)
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+	b.WriteKeyed("tobi", []byte("hello"))
+
+	assert.Equal(t, int64(4), (<-b.Queue).Seq)
+	assert.Equal(t, int64(3), (<-b.Queue).Seq)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
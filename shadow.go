@@ -0,0 +1,74 @@
+package buffer
+
+import (
+	"hash/crc64"
+	"sync"
+)
+
+// Table of shadow digests.
+var crc64Table = crc64.MakeTable(crc64.ECMA)
+
+// ShadowReport compares writes accepted by a buffer and its shadow.
+type ShadowReport struct {
+	Writes       int64  `json:"writes"`        // Writes accepted by the buffer
+	Bytes        int64  `json:"bytes"`         // Bytes accepted by the buffer
+	Digest       uint64 `json:"digest"`        // CRC-64 of the accepted writes, in order
+	ShadowWrites int64  `json:"shadow_writes"` // Writes accepted by the shadow
+	ShadowBytes  int64  `json:"shadow_bytes"`  // Bytes accepted by the shadow
+	ShadowDigest uint64 `json:"shadow_digest"` // CRC-64 of the writes accepted by the shadow
+	Errors       int64  `json:"errors"`        // Writes the shadow rejected
+}
+
+// Match returns true when the shadow accepted every write.
+func (r ShadowReport) Match() bool {
+	return r.Writes == r.ShadowWrites && r.Bytes == r.ShadowBytes && r.Digest == r.ShadowDigest
+}
+
+// Shadow comparison shared by a buffer and its partitions.
+type shadowing struct {
+	sync.Mutex
+	report ShadowReport
+}
+
+// ShadowReport returns a comparison of writes accepted by the buffer and by
+// Config.Shadow.
+func (b *Buffer) ShadowReport() ShadowReport {
+	b.shadow.Lock()
+	defer b.shadow.Unlock()
+	return b.shadow.report
+}
+
+// Mirror the accepted write `data` to the shadow, which is not
+// authoritative, so its errors are only counted.
+func (b *Buffer) mirror(data []byte, meta map[string]string) {
+	_, err := b.Shadow.submit(data, meta)
+	b.shadowed(data, err)
+}
+
+// Mirror the accepted row `v`, plain-encoded as `data`, to the shadow.
+func (b *Buffer) mirrorRow(v interface{}, data []byte) {
+	b.shadowed(data, b.Shadow.WriteRow(v))
+}
+
+// Compare the accepted write `data` with its shadow write, which failed
+// with `err` when non-nil.
+func (b *Buffer) shadowed(data []byte, err error) {
+	s := b.shadow
+	s.Lock()
+	defer s.Unlock()
+
+	r := &s.report
+	r.Writes++
+	r.Bytes += int64(len(data))
+	r.Digest = crc64.Update(r.Digest, crc64Table, data)
+
+	if err != nil {
+		b.log(2, "shadow write failed: %s", err)
+		r.Errors++
+		return
+	}
+
+	r.ShadowWrites++
+	r.ShadowBytes += int64(len(data))
+	r.ShadowDigest = crc64.Update(r.ShadowDigest, crc64Table, data)
+}
@@ -0,0 +1,55 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test mirroring writes to a shadow buffer.
+func TestBuffer_Shadow(t *testing.T) {
+	shadow, err := New("/tmp/buffer-shadow", &Config{
+		Queue:        make(chan *Flush, 100),
+		FlushWrites:  10,
+		Codec:        "gzip",
+		MaxWriteSize: 6,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 10,
+		Shadow:      shadow,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello\n"))
+	b.WriteString("world\n")
+	b.WriteBatch([][]byte{[]byte("again\n")})
+
+	r := b.ShadowReport()
+	assert.Equal(t, int64(3), r.Writes)
+	assert.Equal(t, int64(3), r.ShadowWrites)
+	assert.Equal(t, true, r.Match())
+
+	b.Write([]byte("too large\n"))
+
+	r = b.ShadowReport()
+	assert.Equal(t, int64(4), r.Writes)
+	assert.Equal(t, int64(1), r.Errors)
+	assert.Equal(t, false, r.Match())
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	err = shadow.Close()
+	assert.Equal(t, nil, err)
+
+	primary := <-b.Queue
+	mirrored := <-shadow.Queue
+	assert.Equal(t, int64(4), primary.Writes)
+	assert.Equal(t, int64(3), mirrored.Writes)
+	assert.Equal(t, "gzip", mirrored.Codec)
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package buffer
+
+import (
+	"os"
+	"syscall"
+)
+
+// Signals flushing buffers in logrotate mode.
+var rotateSignals = []os.Signal{syscall.SIGUSR1}
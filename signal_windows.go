@@ -0,0 +1,6 @@
+package buffer
+
+import "os"
+
+// Signals flushing buffers in logrotate mode, none on Windows.
+var rotateSignals []os.Signal
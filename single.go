@@ -0,0 +1,40 @@
+package buffer
+
+import "sync"
+
+// Capacity of the ring of a single-writer buffer.
+const singleWriterRing = 1024
+
+// Result of a funneled write.
+type reply struct {
+	n   int
+	err error
+}
+
+// Reply channels reused across funneled writes.
+var replies = sync.Pool{
+	New: func() interface{} { return make(chan reply, 1) },
+}
+
+// Whether writes go through the ring.
+func (b *Buffer) ringed() bool {
+	return b.Async != 0 || b.SingleWriter
+}
+
+// Funnel a write of `data` through the ring to the single writer,
+// waiting for its result.
+func (b *Buffer) funnel(data []byte, meta map[string]string) (int, error) {
+	c := replies.Get().(chan reply)
+	defer replies.Put(c)
+
+	b.ringMu.RLock()
+	if b.ringClosed {
+		b.ringMu.RUnlock()
+		return 0, ErrClosed
+	}
+	b.ring <- op{data: data, meta: meta, reply: c}
+	b.ringMu.RUnlock()
+
+	r := <-c
+	return r.n, r.err
+}
@@ -0,0 +1,78 @@
+package buffer
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test funneling writes through a single writer.
+func TestBuffer_SingleWriter(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:        make(chan *Flush, 100),
+		FlushWrites:  100,
+		SingleWriter: true,
+	})
+
+	assert.Equal(t, nil, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				n, err := b.Write([]byte("hello\n"))
+				assert.Equal(t, nil, err)
+				assert.Equal(t, 6, n)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	flush := <-b.Queue
+	assert.Equal(t, int64(100), flush.Writes)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	_, err = b.Write([]byte("hello\n"))
+	assert.Equal(t, ErrClosed, err)
+
+	_, err = New("/tmp/buffer", &Config{
+		FlushWrites:  100,
+		Async:        10,
+		SingleWriter: true,
+	})
+
+	assert.Equal(t, "single writer cannot be combined with async writes", err.Error())
+}
+
+// Benchmark buffer writes through a single writer.
+func BenchmarkBuffer_Write_SingleWriter(t *testing.B) {
+	b, err := New("/tmp/buffer", &Config{
+		FlushWrites:   30000,
+		FlushBytes:    1 << 30,
+		FlushInterval: time.Minute,
+		BufferSize:    1 << 10,
+		SingleWriter:  true,
+		Verbosity:     0,
+	})
+
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+
+	discard(b)
+
+	t.ResetTimer()
+
+	t.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			b.Write([]byte("hello world"))
+		}
+	})
+}
@@ -0,0 +1,33 @@
+package buffer
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrTooLarge is returned for writes over Config.MaxWriteSize, or over
+// FlushBytes with StrictBytes.
+var ErrTooLarge = errors.New("write too large")
+
+// Check a write of `n` bytes is within MaxWriteSize, counting an error
+// when it is not. Raw writes to Parquet files are rejected.
+func (b *Buffer) checkSize(n int) error {
+	if b.Parquet != nil {
+		atomic.AddInt64(&b.errors, 1)
+		return ErrParquet
+	}
+
+	return b.checkLimit(n)
+}
+
+// Check a write or row of `n` bytes is within MaxWriteSize, counting an
+// error when it is not.
+func (b *Buffer) checkLimit(n int) error {
+	if b.MaxWriteSize != 0 && n > b.MaxWriteSize {
+		atomic.AddInt64(&b.errors, 1)
+		return fmt.Errorf("%w: %d bytes exceeds %d", ErrTooLarge, n, b.MaxWriteSize)
+	}
+
+	return nil
+}
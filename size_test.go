@@ -0,0 +1,38 @@
+package buffer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test rejecting writes over MaxWriteSize.
+func TestBuffer_MaxWriteSize(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:        make(chan *Flush, 100),
+		FlushWrites:  10,
+		MaxWriteSize: 8,
+	})
+
+	assert.Equal(t, nil, err)
+
+	_, err = b.Write([]byte("hello\n"))
+	assert.Equal(t, nil, err)
+
+	_, err = b.Write([]byte("too large\n"))
+	assert.Equal(t, true, errors.Is(err, ErrTooLarge))
+	assert.Equal(t, "write too large: 10 bytes exceeds 8", err.Error())
+
+	_, err = b.WriteBatch([][]byte{[]byte("ok\n"), []byte("too large\n")})
+	assert.Equal(t, true, errors.Is(err, ErrTooLarge))
+
+	_, err = b.TryWrite([]byte("too large\n"))
+	assert.Equal(t, true, errors.Is(err, ErrTooLarge))
+
+	assert.Equal(t, int64(1), b.Writes())
+	assert.Equal(t, int64(3), b.Stats().Errors)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
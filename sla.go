@@ -0,0 +1,181 @@
+package buffer
+
+import (
+	"sort"
+	"time"
+)
+
+// Report summarizes the delivery latency of acknowledged flushes,
+// measured from the first write to a file until its Ack.
+type Report struct {
+	Start     time.Time     `json:"start"`
+	End       time.Time     `json:"end"`
+	Target    time.Duration `json:"target"`
+	Delivered int           `json:"delivered"`
+	Breached  int           `json:"breached"`
+	Min       time.Duration `json:"min"`
+	Max       time.Duration `json:"max"`
+	Mean      time.Duration `json:"mean"`
+	P50       time.Duration `json:"p50"`
+	P90       time.Duration `json:"p90"`
+	P99       time.Duration `json:"p99"`
+}
+
+// Met returns true when every delivery was within the target.
+func (r *Report) Met() bool {
+	return r.Breached == 0
+}
+
+// Ack records the successful delivery of `f`.
+func (b *Buffer) Ack(f *Flush) {
+	first := f.First
+	if first.IsZero() {
+		first = f.Opened
+	}
+
+	d := time.Since(first)
+	b.log(2, "acked %q after %s", f.Path, d)
+	f.attempted(nil)
+	b.error(b.journal(acked, f, nil))
+	b.states.set(f.Path, Delivered, nil)
+	b.staleness.untrack(f)
+
+	if b.Quota != nil {
+		b.Quota.remove(f)
+	}
+
+	if b.DoneDir != "" {
+		b.error(b.done(f))
+	}
+
+	b.batches.settle(f.Batches, Delivered)
+
+	if b.Instrument != nil {
+		b.Instrument.Acked(f, d)
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	if b.window.IsZero() {
+		b.window = time.Now()
+	}
+
+	b.latency.add(d, b.SLA)
+}
+
+// Report returns a delivery report for acks since the last periodic report.
+func (b *Buffer) Report() *Report {
+	b.RLock()
+	defer b.RUnlock()
+	return b.sla()
+}
+
+// Report delivery latency on an interval.
+func (b *Buffer) reporter() {
+	b.label("report")
+
+	for {
+		select {
+		case <-b.quit:
+			return
+		case <-b.reports.C:
+		}
+
+		b.Lock()
+		r := b.sla()
+		b.window = time.Now()
+		b.latency = latencies{}
+		b.Unlock()
+
+		b.log(1, "delivered %d (%d breached)", r.Delivered, r.Breached)
+		if b.Reports == nil {
+			continue
+		}
+
+		select {
+		case b.Reports <- r:
+		case <-b.quit:
+			return
+		}
+	}
+}
+
+// Report for the current window.
+func (b *Buffer) sla() *Report {
+	l := &b.latency
+	r := &Report{
+		Start:     b.window,
+		End:       time.Now(),
+		Target:    b.SLA,
+		Delivered: l.count,
+		Breached:  l.breached,
+	}
+
+	if r.Start.IsZero() {
+		r.Start = r.End
+	}
+
+	if l.count == 0 {
+		return r
+	}
+
+	sorted := make([]time.Duration, len(l.samples))
+	copy(sorted, l.samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	r.Min = l.min
+	r.Max = l.max
+	r.Mean = l.sum / time.Duration(l.count)
+	r.P50 = percentile(sorted, 0.5)
+	r.P90 = percentile(sorted, 0.9)
+	r.P99 = percentile(sorted, 0.99)
+
+	return r
+}
+
+// Most recent delivery latencies kept per window for percentiles.
+const latencySamples = 1024
+
+// Delivery latency within a window. Counts and extremes are exact, while
+// percentiles are taken from the most recent latencySamples.
+type latencies struct {
+	count    int
+	breached int
+	min      time.Duration
+	max      time.Duration
+	sum      time.Duration
+	samples  []time.Duration
+	next     int
+}
+
+// Add latency `d`, breaching `target` when non-zero and exceeded.
+func (l *latencies) add(d, target time.Duration) {
+	if l.count == 0 || d < l.min {
+		l.min = d
+	}
+
+	if d > l.max {
+		l.max = d
+	}
+
+	if target != 0 && d > target {
+		l.breached++
+	}
+
+	l.count++
+	l.sum += d
+
+	if len(l.samples) < latencySamples {
+		l.samples = append(l.samples, d)
+		return
+	}
+
+	l.samples[l.next] = d
+	l.next = (l.next + 1) % latencySamples
+}
+
+// Percentile of sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	return sorted[int(p*float64(len(sorted)-1))]
+}
@@ -0,0 +1,114 @@
+package buffer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test delivery reports from acks.
+func TestBuffer_Ack(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1,
+		SLA:         time.Minute,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+	b.Write([]byte("world"))
+
+	b.Ack(<-b.Queue)
+
+	f := <-b.Queue
+	f.First = time.Now().Add(-time.Hour)
+	b.Ack(f)
+
+	r := b.Report()
+	assert.Equal(t, 2, r.Delivered)
+	assert.Equal(t, 1, r.Breached)
+	assert.Equal(t, false, r.Met())
+	assert.Equal(t, true, r.Max >= time.Hour)
+	assert.Equal(t, true, r.Min < time.Minute)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test delivery latency samples are bounded.
+func TestBuffer_Ack_bounded(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1000,
+		SLA:         time.Minute,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	for i := 0; i < 3*latencySamples; i++ {
+		b.Ack(&Flush{Path: "/tmp/buffer/acked", First: time.Now().Add(-time.Duration(i)*time.Second - time.Second/2)})
+	}
+
+	r := b.Report()
+	assert.Equal(t, 3*latencySamples, r.Delivered)
+	assert.Equal(t, 3*latencySamples-60, r.Breached)
+	assert.Equal(t, true, r.Min < time.Second)
+	assert.Equal(t, true, r.Max >= time.Duration(3*latencySamples-1)*time.Second)
+	assert.Equal(t, latencySamples, len(b.latency.samples))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test closing with unread periodic reports.
+func TestBuffer_Ack_Reports_unread(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Reports:        make(chan *Report),
+		FlushWrites:    1000,
+		ReportInterval: 10 * time.Millisecond,
+		Verbosity:      0,
+	})
+
+	assert.Equal(t, nil, err)
+	time.Sleep(50 * time.Millisecond)
+
+	done := make(chan error)
+	go func() { done <- b.Close() }()
+
+	select {
+	case err = <-done:
+		assert.Equal(t, nil, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("close blocked on reports")
+	}
+}
+
+// Test periodic delivery reports.
+func TestBuffer_Ack_Reports(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:          make(chan *Flush, 100),
+		Reports:        make(chan *Report, 10),
+		FlushWrites:    1,
+		SLA:            time.Minute,
+		ReportInterval: 50 * time.Millisecond,
+		Verbosity:      0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+	b.Ack(<-b.Queue)
+
+	r := <-b.Reports
+	assert.Equal(t, 1, r.Delivered)
+	assert.Equal(t, true, r.Met())
+
+	assert.Equal(t, 0, b.Report().Delivered)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
@@ -0,0 +1,19 @@
+package buffer
+
+// Whether the next file spills over as the primary path exceeds its quota.
+func (b *Buffer) overflowing() bool {
+	return b.SpillDir != "" && b.Quota != nil && b.Quota.Exceeded()
+}
+
+// Spill over to Config.SpillDir after the primary path failed to open
+// a file with `err`, returning whether to retry there.
+func (b *Buffer) spill(err error) bool {
+	if b.SpillDir == "" || b.spilling {
+		return false
+	}
+
+	b.log(1, "spilling over to %s: %s", b.SpillDir, err)
+	b.error(err)
+	b.spilling = true
+	return true
+}
@@ -0,0 +1,117 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test spilling over when the quota is exceeded.
+func TestBuffer_SpillDir_quota(t *testing.T) {
+	os.RemoveAll("/tmp/buffer-spill")
+
+	q := NewQuotaManager(1)
+	b, err := New("/tmp/buffer-spill/primary/buffer", &Config{
+		Queue:       make(chan *Flush, 10),
+		FlushWrites: 1,
+		Quota:       q,
+		SpillDir:    "/tmp/buffer-spill/spill",
+		Policies:    map[Condition]Policy{QuotaExceeded: Error},
+	})
+
+	assert.Equal(t, nil, err)
+
+	_, err = b.Write([]byte("hello"))
+	assert.Equal(t, nil, err)
+
+	f := <-b.Queue
+	assert.Equal(t, false, f.Spilled)
+	assert.Equal(t, "/tmp/buffer-spill/primary", filepath.Dir(f.Path))
+
+	_, err = b.Write([]byte("world"))
+	assert.Equal(t, nil, err)
+
+	f = <-b.Queue
+	assert.Equal(t, true, f.Spilled)
+	assert.Equal(t, "/tmp/buffer-spill/spill", filepath.Dir(f.Path))
+	assert.Equal(t, int64(5), q.Usage())
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test spilling over when the primary path fails.
+func TestBuffer_SpillDir_error(t *testing.T) {
+	os.RemoveAll("/tmp/buffer-spill")
+	os.MkdirAll("/tmp/buffer-spill", 0755)
+	ioutil.WriteFile("/tmp/buffer-spill/primary", nil, 0644)
+
+	var errs []error
+	b, err := New("/tmp/buffer-spill/primary/buffer", &Config{
+		Queue:       make(chan *Flush, 10),
+		FlushWrites: 1,
+		SpillDir:    "/tmp/buffer-spill/spill",
+		Hooks:       Hooks{OnError: func(err error) { errs = append(errs, err) }},
+	})
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, len(errs))
+
+	_, err = b.Write([]byte("hello"))
+	assert.Equal(t, nil, err)
+
+	f := <-b.Queue
+	assert.Equal(t, true, f.Spilled)
+	assert.Equal(t, "/tmp/buffer-spill/spill", filepath.Dir(f.Path))
+
+	data, err := ioutil.ReadFile(f.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "hello", string(data))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test recovering files left in the spill directory.
+func TestBuffer_SpillDir_recover(t *testing.T) {
+	os.RemoveAll("/tmp/buffer-spill")
+
+	config := &Config{
+		Queue:       make(chan *Flush, 10),
+		FlushWrites: 1,
+		Quota:       NewQuotaManager(1),
+		SpillDir:    "/tmp/buffer-spill/spill",
+		Policies:    map[Condition]Policy{QuotaExceeded: Error},
+	}
+
+	b, err := New("/tmp/buffer-spill/primary/buffer", config)
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+	b.Write([]byte("world"))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	config.Queue = make(chan *Flush, 10)
+	config.Quota = NewQuotaManager(1)
+	config.Recover = true
+
+	b, err = New("/tmp/buffer-spill/primary/buffer", config)
+	assert.Equal(t, nil, err)
+
+	first := <-b.Queue
+	assert.Equal(t, Recovered, first.Reason)
+	assert.Equal(t, false, first.Spilled)
+
+	second := <-b.Queue
+	assert.Equal(t, Recovered, second.Reason)
+	assert.Equal(t, true, second.Spilled)
+	assert.Equal(t, "/tmp/buffer-spill/spill", filepath.Dir(second.Path))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
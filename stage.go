@@ -0,0 +1,222 @@
+package buffer
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"os"
+	"time"
+)
+
+// Grouping of queued files into merged files by a consumer stage.
+type grouping struct {
+	name  string
+	skip  func(f *Flush) bool                   // Deliver f as is, nil for none
+	split func(pending []*Flush, f *Flush) bool // Deliver the pending files before f, nil for never
+	full  func(pending []*Flush) bool           // Deliver the pending files
+	merge func(files []*Flush) (*Flush, error)  // Merge the pending files
+}
+
+// Deliver files from Config.Queue grouped by `g`, delivering partial
+// groups `window` after their first file when non-zero, and when the
+// queue is closed.
+func (b *Buffer) group(ctx context.Context, window time.Duration, g *grouping) <-chan *Flush {
+	out := make(chan *Flush)
+
+	go func() {
+		b.label(g.name)
+		defer close(out)
+
+		var pending []*Flush
+		var timer *time.Timer
+		var timeout <-chan time.Time
+
+		send := func(f *Flush) bool {
+			select {
+			case <-ctx.Done():
+				return false
+			case out <- f:
+				return true
+			}
+		}
+
+		emit := func() bool {
+			if timer != nil {
+				timer.Stop()
+				timer, timeout = nil, nil
+			}
+
+			if len(pending) == 0 {
+				return true
+			}
+
+			files := pending
+			pending = nil
+
+			f, err := g.merge(files)
+			if err == nil {
+				return send(f)
+			}
+
+			b.error(err)
+			for _, f := range files {
+				if !send(f) {
+					return false
+				}
+			}
+
+			return true
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timeout:
+				if !emit() {
+					return
+				}
+			case f, ok := <-b.Queue:
+				if !ok {
+					emit()
+					return
+				}
+
+				if g.skip != nil && g.skip(f) {
+					if !send(f) {
+						return
+					}
+					continue
+				}
+
+				if len(pending) != 0 && g.split != nil && g.split(pending, f) {
+					if !emit() {
+						return
+					}
+				}
+
+				pending = append(pending, f)
+
+				if g.full(pending) {
+					if !emit() {
+						return
+					}
+					continue
+				}
+
+				if window != 0 && timer == nil {
+					timer = time.NewTimer(window)
+					timeout = timer.C
+				}
+			}
+		}
+	}()
+
+	return out
+}
+
+// Record file `f` as flushed in place of `files`, which are removed.
+func (b *Buffer) supersede(f *Flush, files []*Flush) error {
+	var err error
+	if b.Sidecar {
+		err = b.sidecar(f)
+	}
+
+	if b.MetaFile && err == nil {
+		err = b.metafile(f)
+	}
+
+	if err == nil {
+		err = b.journal(flushed, f, nil)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	b.staleness.track(f)
+	b.charge(f)
+	b.states.set(f.Path, Queued, nil)
+
+	for _, in := range files {
+		b.error(b.absorb(in))
+	}
+
+	return nil
+}
+
+// Write the file of `f` with `write`, renaming it into place once
+// complete and setting its checksum.
+func (b *Buffer) writeAtomic(f *Flush, write func(w io.Writer) error) error {
+	tmp := f.Path + ".tmp"
+	file, err := b.createFile(tmp)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = file
+	if b.Checksum != "" {
+		h := checksums[b.Checksum]()
+		w = io.MultiWriter(file, h)
+		defer func() {
+			f.Hash, f.Checksum = b.Checksum, hex.EncodeToString(h.Sum(nil))
+		}()
+	}
+
+	err = write(w)
+	if err == nil && b.Durability >= FileSync {
+		err = file.Sync()
+	}
+
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+
+	if err == nil {
+		err = os.Rename(tmp, f.Path)
+	}
+
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if b.Durability >= PowerSafe {
+		return syncDir(f.Path)
+	}
+
+	return nil
+}
+
+// Release file `f` superseded by a merged file, removing it and its
+// sidecars.
+func (b *Buffer) absorb(f *Flush) error {
+	err := b.journal(merged, f, nil)
+	if err != nil {
+		return err
+	}
+
+	b.states.forget(f.Path)
+	b.staleness.untrack(f)
+
+	if b.Quota != nil {
+		b.Quota.remove(f)
+	}
+
+	paths := []string{f.Path}
+	if b.Sidecar {
+		paths = append(paths, f.Path+"."+f.Hash)
+	}
+	if b.MetaFile {
+		paths = append(paths, f.Path+MetaFileSuffix)
+	}
+
+	for _, path := range paths {
+		err := os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package buffer
+
+import (
+	"sync"
+	"time"
+)
+
+// Staleness tracking shared by a buffer and its partitions.
+type staleness struct {
+	sync.Mutex
+	bound    time.Duration
+	ticker   *time.Ticker
+	done     chan struct{}
+	pending  map[string]time.Time
+	reported map[string]bool
+}
+
+// New staleness tracker.
+func newStaleness() *staleness {
+	return &staleness{
+		pending:  make(map[string]time.Time),
+		reported: make(map[string]bool),
+	}
+}
+
+// MaxStaleness guarantees, while the pipeline is healthy, that no record
+// older than `d` remains unshipped. Files are rotated with reason Stale
+// once their first write is half of `d` old, leaving the other half for
+// delivery, and a StaleError is reported for each file which is not acked
+// in time. Delivery is prioritized by the Flush.Deadline of each file:
+// requeued files are retried before their deadline regardless of backoff,
+// and consumers should deliver the earliest deadlines first. Zero
+// disables the guarantee.
+func (b *Buffer) MaxStaleness(d time.Duration) {
+	s := b.staleness
+	s.Lock()
+	defer s.Unlock()
+
+	s.bound = d
+
+	switch {
+	case d == 0 && s.ticker != nil:
+		s.halt()
+	case d != 0 && s.ticker == nil:
+		s.ticker = time.NewTicker(d / 4)
+		s.done = make(chan struct{})
+		go b.enforce(s.ticker, s.done)
+	case d != 0:
+		s.ticker.Reset(d / 4)
+	}
+}
+
+// Stop enforcing the bound, which is kept for Reopen.
+func (s *staleness) stop() {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.ticker != nil {
+		s.halt()
+	}
+}
+
+// Stop the ticker and its enforcing goroutine, with the lock held.
+func (s *staleness) halt() {
+	s.ticker.Stop()
+	s.ticker = nil
+	close(s.done)
+	s.done = nil
+}
+
+// Bound being enforced.
+func (s *staleness) current() time.Duration {
+	s.Lock()
+	defer s.Unlock()
+	return s.bound
+}
+
+// Track `f` until it is acked, setting its deadline.
+func (s *staleness) track(f *Flush) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.bound == 0 {
+		return
+	}
+
+	first := f.First
+	if first.IsZero() {
+		first = f.Opened
+	}
+
+	s.pending[f.Path] = first
+	f.Deadline = first.Add(s.bound)
+}
+
+// Stop tracking `f`.
+func (s *staleness) untrack(f *Flush) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.pending, f.Path)
+	delete(s.reported, f.Path)
+}
+
+// Report `path` once when its first write is older than the bound.
+func (b *Buffer) overdue(path string, first time.Time) {
+	s := b.staleness
+	s.Lock()
+	age := time.Since(first)
+	late := s.bound != 0 && age > s.bound && !s.reported[path]
+	if late {
+		s.reported[path] = true
+	}
+	bound := s.bound
+	s.Unlock()
+
+	if late {
+		b.error(&StaleError{Path: path, First: first, Age: age, Bound: bound})
+	}
+}
+
+// Rotate stale files and report violations on each tick until `done`.
+func (b *Buffer) enforce(t *time.Ticker, done chan struct{}) {
+	b.label("staleness")
+
+	for {
+		select {
+		case <-b.quit:
+			return
+		case <-done:
+			return
+		case <-t.C:
+		}
+
+		s := b.staleness
+		s.Lock()
+		bound := s.bound
+		pending := make(map[string]time.Time, len(s.pending))
+		for path, first := range s.pending {
+			pending[path] = first
+		}
+		s.Unlock()
+
+		b.RLock()
+		buffers := []*Buffer{b}
+		for _, k := range b.keys {
+			buffers = append(buffers, k)
+		}
+		b.RUnlock()
+
+		for _, x := range buffers {
+			x.Lock()
+			if x.writes != 0 && time.Since(x.first) >= bound/2 {
+				x.error(x.attempt(func() error { return x.flush(Stale) }))
+			}
+
+			if x.writes != 0 {
+				x.overdue(x.staged, x.first)
+			}
+			x.Unlock()
+		}
+
+		for path, first := range pending {
+			b.overdue(path, first)
+		}
+	}
+}
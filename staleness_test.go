@@ -0,0 +1,111 @@
+package buffer
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test rotating stale files and reporting violations.
+func TestBuffer_MaxStaleness(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		Errors:      make(chan error, 100),
+		FlushWrites: 100,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.MaxStaleness(100 * time.Millisecond)
+
+	b.Write([]byte("hello"))
+	b.WriteKeyed("tobi", []byte("hello"))
+
+	f := <-b.Queue
+	assert.Equal(t, Stale, f.Reason)
+	assert.Equal(t, true, time.Since(f.First) < 100*time.Millisecond)
+	b.Ack(f)
+
+	f = <-b.Queue
+	assert.Equal(t, Stale, f.Reason)
+
+	e := (<-b.Errors).(*StaleError)
+	assert.Equal(t, f.Path, e.Path)
+	assert.Equal(t, 100*time.Millisecond, e.Bound)
+
+	select {
+	case err := <-b.Errors:
+		t.Fatalf("unexpected error: %s", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test prioritizing delivery of files by their deadline.
+func TestBuffer_MaxStaleness_Deadline(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:         make(chan *Flush, 100),
+		FlushWrites:   1,
+		RenameBackoff: time.Hour,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.MaxStaleness(200 * time.Millisecond)
+
+	b.Write([]byte("hello"))
+
+	f := <-b.Queue
+	assert.Equal(t, f.First.Add(200*time.Millisecond), f.Deadline)
+
+	b.Requeue(f, errors.New("boom"))
+
+	select {
+	case f = <-b.Queue:
+	case <-time.After(150 * time.Millisecond):
+		t.Fatal("requeued file not prioritized")
+	}
+
+	f.Deadline = time.Now().Add(-time.Second)
+	b.Requeue(f, errors.New("boom"))
+
+	select {
+	case <-b.Queue:
+		t.Fatal("file past its deadline requeued without backoff")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	b.Ack(f)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test disabling the bound stops enforcing it.
+func TestBuffer_MaxStaleness_disable(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 100,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.MaxStaleness(100 * time.Millisecond)
+	done := b.staleness.done
+
+	b.MaxStaleness(0)
+
+	select {
+	case <-done:
+	default:
+		t.Fatal("enforcing not stopped")
+	}
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
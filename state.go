@@ -0,0 +1,174 @@
+package buffer
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// State of a file in the delivery pipeline.
+type State string
+
+// File states.
+const (
+	Writing      State = "open"          // Open and being written
+	Sealed       State = "sealed"        // Flushed and not yet queued
+	Queued       State = "queued"        // Published to the queue
+	Delivering   State = "delivering"    // Taken by a consumer, see Delivering
+	Delivered    State = "delivered"     // Acked
+	Failed       State = "failed"        // Nacked, pending another attempt
+	DeadLettered State = "dead_lettered" // Given up on, see DeadLetter
+	Evicted      State = "evicted"       // Evicted under the quota or as stale
+	Dropped      State = "dropped"       // Dropped under the QueueFull policy, or discarded
+)
+
+// Terminal returns true for states files do not leave.
+func (s State) Terminal() bool {
+	switch s {
+	case Delivered, DeadLettered, Evicted, Dropped:
+		return true
+	default:
+		return false
+	}
+}
+
+// Whether files in the state may be evicted, not being written or taken
+// by a consumer.
+func (s State) evictable() bool {
+	switch s {
+	case Sealed, Queued, Failed:
+		return true
+	default:
+		return false
+	}
+}
+
+// FileState is the state of a file and when it was entered.
+type FileState struct {
+	Path  string    `json:"path"`
+	State State     `json:"state"`
+	Since time.Time `json:"since"`
+	Error string    `json:"error,omitempty"`
+}
+
+// StateChange notifies a file entering a state.
+type StateChange struct {
+	FileState
+	From State `json:"from,omitempty"`
+}
+
+// File states shared by a buffer and its partitions.
+type states struct {
+	sync.Mutex
+	files    map[string]FileState
+	watchers map[chan StateChange]bool
+}
+
+// New state registry.
+func newStates() *states {
+	return &states{
+		files:    make(map[string]FileState),
+		watchers: make(map[chan StateChange]bool),
+	}
+}
+
+// FileStates returns the states of files which have not reached a
+// terminal state, ordered by path.
+func (b *Buffer) FileStates() []FileState {
+	s := b.states
+	s.Lock()
+	defer s.Unlock()
+
+	list := make([]FileState, 0, len(s.files))
+	for _, f := range s.files {
+		list = append(list, f)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Path < list[j].Path })
+	return list
+}
+
+// FileState returns the state of the file at `path`, if it has not
+// reached a terminal state.
+func (b *Buffer) FileState(path string) (FileState, bool) {
+	b.states.Lock()
+	defer b.states.Unlock()
+	f, ok := b.states.files[path]
+	return f, ok
+}
+
+// Watch returns a channel of state changes with capacity `size` and a
+// function to stop watching. Changes are dropped when the channel is full.
+func (b *Buffer) Watch(size int) (<-chan StateChange, func()) {
+	c := make(chan StateChange, size)
+
+	b.states.Lock()
+	b.states.watchers[c] = true
+	b.states.Unlock()
+
+	var once sync.Once
+	return c, func() {
+		once.Do(func() {
+			b.states.Lock()
+			delete(b.states.watchers, c)
+			b.states.Unlock()
+			close(c)
+		})
+	}
+}
+
+// Delivering records that a consumer has taken `f` for delivery.
+func (b *Buffer) Delivering(f *Flush) {
+	b.states.set(f.Path, Delivering, nil)
+}
+
+// DeadLetter records that delivery of `f` was given up after `err`.
+func (b *Buffer) DeadLetter(f *Flush, err error) {
+	b.log(1, "dead-lettered %q: %s", f.Path, err)
+	f.attempted(err)
+	b.error(b.journal(dead, f, err))
+	b.states.set(f.Path, DeadLettered, err)
+	b.staleness.untrack(f)
+	b.batches.settle(f.Batches, DeadLettered)
+
+	if b.Quota != nil {
+		b.Quota.remove(f)
+	}
+}
+
+// Set the state of `path`.
+func (s *states) set(path string, state State, err error) {
+	s.move(path, path, state, err)
+}
+
+// Move the state of `from` to `path`, renamed on entering `state`.
+func (s *states) move(from, path string, state State, err error) {
+	s.Lock()
+	defer s.Unlock()
+
+	prev := s.files[from].State
+	delete(s.files, from)
+
+	f := FileState{Path: path, State: state, Since: time.Now()}
+	if err != nil {
+		f.Error = err.Error()
+	}
+
+	if !state.Terminal() {
+		s.files[path] = f
+	}
+
+	for c := range s.watchers {
+		select {
+		case c <- StateChange{FileState: f, From: prev}:
+		default:
+		}
+	}
+}
+
+// Forget `path`, such as an empty file which was removed.
+func (s *states) forget(path string) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.files, path)
+}
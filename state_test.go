@@ -0,0 +1,58 @@
+package buffer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test tracking file states.
+func TestBuffer_FileStates(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1,
+	})
+
+	assert.Equal(t, nil, err)
+
+	changes, stop := b.Watch(100)
+
+	b.Write([]byte("hello\n"))
+	f := <-b.Queue
+
+	s, ok := b.FileState(f.Path)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, Queued, s.State)
+
+	b.Delivering(f)
+	b.Nack(f, errors.New("boom"))
+
+	s, _ = b.FileState(f.Path)
+	assert.Equal(t, Failed, s.State)
+	assert.Equal(t, "boom", s.Error)
+
+	b.Delivering(f)
+	b.Ack(f)
+
+	_, ok = b.FileState(f.Path)
+	assert.Equal(t, false, ok)
+
+	states := b.FileStates()
+	assert.Equal(t, 1, len(states))
+	assert.Equal(t, Writing, states[0].State)
+
+	stop()
+
+	var got []State
+	for c := range changes {
+		if c.Path == f.Path {
+			got = append(got, c.State)
+		}
+	}
+
+	assert.Equal(t, []State{Sealed, Queued, Delivering, Failed, Delivering, Delivered}, got)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
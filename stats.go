@@ -0,0 +1,107 @@
+package buffer
+
+import (
+	"encoding/json"
+	"sync/atomic"
+	"time"
+)
+
+// Lifetime totals.
+type totals struct {
+	writes  int64
+	bytes   int64
+	reasons map[Reason]int64
+}
+
+// Stats are cumulative totals since the buffer was created,
+// including keyed partitions.
+type Stats struct {
+	Writes    int64            `json:"writes"`     // Writes made
+	Bytes     int64            `json:"bytes"`      // Bytes written
+	Flushes   int64            `json:"flushes"`    // Files flushed
+	Reasons   map[Reason]int64 `json:"reasons"`    // Files flushed by reason
+	Errors    int64            `json:"errors"`     // Failed writes and background errors
+	Age       time.Duration    `json:"age"`        // Age of the current file
+	LastFlush time.Time        `json:"last_flush"` // Time of the last flush
+	Queue     int              `json:"queue"`      // Flushes waiting in the queue
+	Recovered int64            `json:"recovered"`  // Recovered files published
+	Backlog   int64            `json:"backlog"`    // Recovered files remaining
+	Tripped   bool             `json:"tripped"`    // Circuit breaker open
+	Trips     int64            `json:"trips"`      // Circuit breaker trips
+}
+
+// Stats returns lifetime totals.
+func (b *Buffer) Stats() Stats {
+	b.RLock()
+	s := Stats{
+		Age:     time.Since(b.opened),
+		Queue:   len(b.Queue),
+		Reasons: make(map[Reason]int64),
+	}
+
+	keys := make([]*Buffer, 0, len(b.keys))
+	for _, k := range b.keys {
+		keys = append(keys, k)
+	}
+	b.RUnlock()
+
+	b.add(&s)
+	for _, k := range keys {
+		k.add(&s)
+	}
+
+	delivered, total := b.Backlog()
+	s.Recovered = delivered
+	s.Backlog = total - delivered
+	s.Tripped = b.breaker.open()
+	s.Trips = b.breaker.tripped()
+
+	return s
+}
+
+// Add totals to `s`.
+func (b *Buffer) add(s *Stats) {
+	b.RLock()
+	defer b.RUnlock()
+
+	s.Writes += b.total.writes
+	s.Bytes += b.total.bytes
+	s.Flushes += b.flushes
+	s.Errors += atomic.LoadInt64(&b.errors)
+
+	for reason, n := range b.total.reasons {
+		s.Reasons[reason] += n
+	}
+
+	if b.flushed.After(s.LastFlush) {
+		s.LastFlush = b.flushed
+	}
+}
+
+// Write Stats records, tagged with the "type" annotation "stats", to
+// Config.StatsBuffer so buffer health flows through the same pipeline.
+func (b *Buffer) snapshot() {
+	b.label("stats")
+
+	for {
+		select {
+		case <-b.quit:
+			return
+		case <-b.snapshots.C:
+		}
+
+		record, err := json.Marshal(b.Stats())
+		if err != nil {
+			b.error(err)
+			continue
+		}
+
+		dst := b.StatsBuffer
+		if dst == nil {
+			dst = b
+		}
+
+		_, err = dst.WriteWithMeta(append(record, '\n'), map[string]string{"type": "stats"})
+		b.error(err)
+	}
+}
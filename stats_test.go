@@ -0,0 +1,86 @@
+package buffer
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test lifetime stats.
+func TestBuffer_Stats(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 2,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+	b.Write([]byte("hello"))
+	b.Write([]byte("world"))
+	b.WriteKeyed("tobi", []byte("hi"))
+	b.Flush()
+
+	s := b.Stats()
+	assert.Equal(t, int64(4), s.Writes)
+	assert.Equal(t, int64(17), s.Bytes)
+	assert.Equal(t, int64(3), s.Flushes)
+	assert.Equal(t, int64(1), s.Reasons[Writes])
+	assert.Equal(t, int64(2), s.Reasons[Forced])
+	assert.Equal(t, int64(0), s.Errors)
+	assert.Equal(t, 3, s.Queue)
+	assert.Equal(t, false, s.LastFlush.IsZero())
+
+	assert.Equal(t, int64(0), b.Writes())
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test writing stats records to a meta-buffer.
+func TestBuffer_StatsInterval(t *testing.T) {
+	meta, err := New("/tmp/buffer-meta", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1,
+		Meta:        true,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b, err := New("/tmp/buffer", &Config{
+		Queue:         make(chan *Flush, 100),
+		FlushWrites:   100,
+		StatsInterval: 10 * time.Millisecond,
+		StatsBuffer:   meta,
+		Verbosity:     0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+
+	f := <-meta.Queue
+	assert.Equal(t, []string{"stats"}, f.Meta["type"])
+
+	record, err := ioutil.ReadFile(f.Path)
+	assert.Equal(t, nil, err)
+
+	_, data, err := ParseMeta(record)
+	assert.Equal(t, nil, err)
+
+	var s Stats
+	err = json.Unmarshal(data, &s)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(1), s.Writes)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	err = meta.Close()
+	assert.Equal(t, nil, err)
+}
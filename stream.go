@@ -0,0 +1,143 @@
+package buffer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Live state of the open file for streaming.
+type live struct {
+	sync.Mutex
+	cond      *sync.Cond
+	path      string
+	committed int64
+	sealed    bool
+	flushed   string
+	next      *live
+}
+
+// New live state for `path`.
+func newLive(path string) *live {
+	l := &live{path: path}
+	l.cond = sync.NewCond(&l.Mutex)
+	return l
+}
+
+// New live state for `path`, following this file when non-nil.
+func (l *live) succeed(path string) *live {
+	next := newLive(path)
+	if l != nil {
+		l.Lock()
+		l.next = next
+		l.Unlock()
+	}
+
+	return next
+}
+
+// Commit data up to offset `n`.
+func (l *live) commit(n int64) {
+	l.Lock()
+	l.committed = n
+	l.Unlock()
+	l.cond.Broadcast()
+}
+
+// Seal the file at offset `n`, flushed to `path` or removed when empty.
+func (l *live) seal(n int64, path string) {
+	l.Lock()
+	l.committed = n
+	l.sealed = true
+	l.flushed = path
+	l.Unlock()
+	l.cond.Broadcast()
+}
+
+// Stream reads the open file of a buffer as it is written.
+type Stream struct {
+	live   *live
+	file   *os.File
+	off    int64
+	closed bool
+}
+
+// Stream returns a reader of the currently open file, which follows
+// writes up to the offset committed to disk and returns io.EOF once the
+// file is flushed. Config.Streaming must be enabled. Data still held in
+// the write buffer is not visible until the buffer writes it out.
+func (b *Buffer) Stream() (*Stream, error) {
+	if !b.Streaming {
+		return nil, fmt.Errorf("streaming is not enabled")
+	}
+
+	b.RLock()
+	l := b.live
+	b.RUnlock()
+
+	f, err := os.Open(l.path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Stream{live: l, file: f}, nil
+}
+
+// Path returns the path the file was opened with.
+func (s *Stream) Path() string {
+	return s.live.path
+}
+
+// Read implements io.Reader, blocking until data is committed.
+func (s *Stream) Read(p []byte) (int, error) {
+	l := s.live
+	l.Lock()
+
+	for s.off >= l.committed && !l.sealed && !s.closed {
+		l.cond.Wait()
+	}
+
+	if s.closed {
+		l.Unlock()
+		return 0, os.ErrClosed
+	}
+
+	if s.off >= l.committed {
+		l.Unlock()
+		return 0, io.EOF
+	}
+
+	if n := l.committed - s.off; int64(len(p)) > n {
+		p = p[:n]
+	}
+
+	l.Unlock()
+
+	n, err := s.file.ReadAt(p, s.off)
+	s.off += int64(n)
+
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+
+	return n, err
+}
+
+// Close the stream, unblocking pending reads.
+func (s *Stream) Close() error {
+	s.live.Lock()
+	s.closed = true
+	s.live.Unlock()
+	s.live.cond.Broadcast()
+	return s.file.Close()
+}
+
+// Offset committed to the file.
+func (b *Buffer) committed() int64 {
+	if b.buf != nil {
+		return b.bytes - int64(b.buf.Buffered())
+	}
+
+	return b.bytes
+}
@@ -0,0 +1,58 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test streaming the open file.
+func TestBuffer_Stream(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 3,
+		Streaming:   true,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	s, err := b.Stream()
+	assert.Equal(t, nil, err)
+	defer s.Close()
+
+	b.Write([]byte("hello "))
+
+	p := make([]byte, 100)
+	n, err := s.Read(p)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "hello ", string(p[:n]))
+
+	go func() {
+		b.Write([]byte("world"))
+		b.Write([]byte("!"))
+	}()
+
+	rest, err := ioutil.ReadAll(s)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "world!", string(rest))
+
+	flush := <-b.Queue
+	assert.Equal(t, int64(12), flush.Bytes)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test streaming requires the option.
+func TestBuffer_Stream_Disabled(t *testing.T) {
+	b, err := New("/tmp/buffer", config)
+	assert.Equal(t, nil, err)
+
+	_, err = b.Stream()
+	assert.Equal(t, "streaming is not enabled", err.Error())
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
@@ -0,0 +1,37 @@
+package buffer
+
+import "fmt"
+
+// Make room for a write of `n` bytes with StrictBytes, flushing first
+// when it would take the file over FlushBytes, less the trailer. The
+// write is rejected when the file could not be flushed, as while paused
+// or when its rotation was dropped.
+func (b *Buffer) fit(n int) error {
+	if !b.StrictBytes || b.FlushBytes == 0 {
+		return nil
+	}
+
+	limit := b.FlushBytes
+	if b.Trailer {
+		limit -= TrailerSize
+	}
+
+	if int64(n) > limit {
+		return fmt.Errorf("%w: %d bytes exceeds the %d byte file limit", ErrTooLarge, n, limit)
+	}
+
+	if b.bytes+int64(n) <= limit {
+		return nil
+	}
+
+	err := b.attempt(func() error { return b.flush(Bytes) })
+	if err != nil {
+		return err
+	}
+
+	if b.bytes+int64(n) > limit {
+		return fmt.Errorf("%w: %d bytes does not fit the %d byte file limit with %d written", ErrTooLarge, n, limit, b.bytes)
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package buffer
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test capping file sizes at FlushBytes.
+func TestBuffer_StrictBytes(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushBytes:  10,
+		StrictBytes: true,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello\n"))
+	b.Write([]byte("world\n"))
+
+	flush := <-b.Queue
+	assert.Equal(t, Bytes, flush.Reason)
+	assert.Equal(t, int64(6), flush.Bytes)
+
+	b.Write([]byte("hi\n"))
+	b.Write([]byte("x\n"))
+
+	_, err = b.Write([]byte("this is too long\n"))
+	assert.Equal(t, "write too large: 17 bytes exceeds the 10 byte file limit", err.Error())
+
+	_, err = b.WriteBatch([][]byte{[]byte("ab\n"), []byte("cd\n")})
+	assert.Equal(t, nil, err)
+
+	flush = <-b.Queue
+	assert.Equal(t, int64(9), flush.Bytes)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test capping file sizes with trailers.
+func TestBuffer_StrictBytes_Trailer(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushBytes:  TrailerSize + 10,
+		StrictBytes: true,
+		Trailer:     true,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello\n"))
+	b.Write([]byte("world\n"))
+
+	flush := <-b.Queue
+	info, err := os.Stat(flush.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(TrailerSize+6), info.Size())
+
+	_, err = b.Write([]byte("0123456789\n"))
+	assert.Equal(t, "write too large: 11 bytes exceeds the 10 byte file limit", err.Error())
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test capping file sizes while paused.
+func TestBuffer_StrictBytes_paused(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushBytes:  10,
+		StrictBytes: true,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Pause()
+	b.Write([]byte("hello\n"))
+
+	_, err = b.Write([]byte("world\n"))
+	assert.Equal(t, true, errors.Is(err, ErrTooLarge))
+	assert.Equal(t, int64(6), b.Bytes())
+
+	err = b.Resume()
+	assert.Equal(t, nil, err)
+
+	_, err = b.Write([]byte("world\n"))
+	assert.Equal(t, nil, err)
+
+	flush := <-b.Queue
+	assert.Equal(t, int64(6), flush.Bytes)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test capping file sizes when rotations are dropped.
+func TestBuffer_StrictBytes_RotateFailed(t *testing.T) {
+	os.RemoveAll("/tmp/buffer-strict")
+	os.MkdirAll("/tmp/buffer-strict/buffer.1.closed/taken", 0755)
+	defer os.RemoveAll("/tmp/buffer-strict")
+
+	b, err := New("/tmp/buffer-strict/buffer", &Config{
+		Queue:         make(chan *Flush, 100),
+		FlushBytes:    10,
+		StrictBytes:   true,
+		Filename:      "{{.Path}}.{{.Seq}}",
+		RenameBackoff: time.Millisecond,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello\n"))
+
+	_, err = b.Write([]byte("world\n"))
+	assert.Equal(t, true, errors.Is(err, ErrTooLarge))
+	assert.Equal(t, int64(6), b.Bytes())
+
+	os.RemoveAll("/tmp/buffer-strict/buffer.1.closed")
+
+	_, err = b.Write([]byte("world\n"))
+	assert.Equal(t, nil, err)
+
+	flush := <-b.Queue
+	assert.Equal(t, int64(6), flush.Bytes)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test strict byte limits are rejected for compressed files.
+func TestBuffer_StrictBytes_Codec(t *testing.T) {
+	_, err := New("/tmp/buffer", &Config{
+		FlushBytes:  10,
+		StrictBytes: true,
+		Codec:       "gzip",
+	})
+
+	assert.Equal(t, "strict byte limits cannot be applied to compressed or encrypted files", err.Error())
+}
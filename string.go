@@ -0,0 +1,9 @@
+package buffer
+
+import "unsafe"
+
+// WriteString implements io.StringWriter, writing `s` without copying
+// it to a byte slice. FlushFunc must not retain the last record.
+func (b *Buffer) WriteString(s string) (int, error) {
+	return b.Write(unsafe.Slice(unsafe.StringData(s), len(s)))
+}
@@ -0,0 +1,61 @@
+package buffer
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test writing strings.
+func TestBuffer_WriteString(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 2,
+	})
+
+	assert.Equal(t, nil, err)
+
+	var w io.StringWriter = b
+	n, err := w.WriteString("hello\n")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 6, n)
+
+	fmt.Fprintf(b, "%s\n", "world")
+
+	flush := <-b.Queue
+	data, err := ioutil.ReadFile(flush.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "hello\nworld\n", string(data))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Benchmark writing strings.
+func BenchmarkBuffer_WriteString(t *testing.B) {
+	b, err := New("/tmp/buffer", &Config{
+		FlushWrites:   30000,
+		FlushBytes:    1 << 30,
+		FlushInterval: time.Minute,
+		BufferSize:    1 << 10,
+		Verbosity:     0,
+	})
+
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+
+	discard(b)
+
+	s := "hello world"
+	t.ReportAllocs()
+	t.ResetTimer()
+
+	for i := 0; i < t.N; i++ {
+		b.WriteString(s)
+	}
+}
@@ -0,0 +1,114 @@
+package buffer
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// Tail follows writes to the open file of a buffer across flushes, see
+// Buffer.Tail.
+type Tail struct {
+	b      *Buffer
+	mu     sync.Mutex
+	stream *Stream
+	closed bool
+}
+
+// Tail returns a reader of the currently open file from its start, which
+// follows writes as Stream does and moves on to the next file when it is
+// flushed, returning io.EOF once the buffer is closed. Config.Streaming
+// must be enabled. Files of keyed partitions are not followed.
+func (b *Buffer) Tail() (*Tail, error) {
+	s, err := b.Stream()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tail{b: b, stream: s}, nil
+}
+
+// Read implements io.Reader, blocking until data is committed.
+func (t *Tail) Read(p []byte) (int, error) {
+	for {
+		t.mu.Lock()
+		s, closed := t.stream, t.closed
+		t.mu.Unlock()
+
+		if closed {
+			return 0, os.ErrClosed
+		}
+
+		n, err := s.Read(p)
+		if err != io.EOF {
+			return n, err
+		}
+
+		next, err := t.next(s.live)
+		if err != nil {
+			return 0, err
+		}
+
+		t.mu.Lock()
+		if t.closed {
+			t.mu.Unlock()
+			next.Close()
+			return 0, os.ErrClosed
+		}
+		t.stream = next
+		t.mu.Unlock()
+
+		s.file.Close()
+	}
+}
+
+// Stream of the file opened after the flushed file of `prev`, skipping
+// files removed since.
+func (t *Tail) next(prev *live) (*Stream, error) {
+	t.b.RLock()
+	defer t.b.RUnlock()
+
+	for {
+		prev.Lock()
+		l := prev.next
+		prev.Unlock()
+
+		if l == nil {
+			return nil, io.EOF
+		}
+
+		l.Lock()
+		path := l.path
+		if l.sealed {
+			path = l.flushed
+		}
+		l.Unlock()
+
+		f, err := os.Open(path)
+		if os.IsNotExist(err) {
+			prev = l
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &Stream{live: l, file: f}, nil
+	}
+}
+
+// Path returns the path of the file being followed.
+func (t *Tail) Path() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stream.Path()
+}
+
+// Close the reader, unblocking pending reads.
+func (t *Tail) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	return t.stream.Close()
+}
@@ -0,0 +1,37 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test following writes across flushes.
+func TestBuffer_Tail(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 2,
+		Streaming:   true,
+	})
+
+	assert.Equal(t, nil, err)
+
+	tail, err := b.Tail()
+	assert.Equal(t, nil, err)
+	defer tail.Close()
+
+	go func() {
+		b.Write([]byte("hello "))
+		b.Write([]byte("world"))
+		b.Write([]byte("!"))
+		b.Close()
+	}()
+
+	data, err := ioutil.ReadAll(tail)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "hello world!", string(data))
+
+	flush := <-b.Queue
+	assert.Equal(t, int64(11), flush.Bytes)
+}
@@ -0,0 +1,133 @@
+package buffer
+
+import (
+	"io"
+	"sync"
+)
+
+// Bytes held by a tee reader before dropping writes.
+const teeBuffer = 1 << 20
+
+// Live readers of writes, shared by a buffer and its partitions.
+type tees struct {
+	sync.Mutex
+	readers map[*Tee]bool
+}
+
+// Tee is a best-effort live copy of writes, see TeeReader.
+type Tee struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	tees    *tees
+	buf     []byte
+	closed  bool
+	dropped int64
+}
+
+// TeeReader returns a reader of a live copy of writes to the buffer and
+// its partitions, made after the call. Up to 1 MiB is held for a slow
+// reader, after which writes are dropped whole. Reads return io.EOF once
+// the reader or buffer is closed.
+func (b *Buffer) TeeReader() io.ReadCloser {
+	t := &Tee{tees: b.tees}
+	t.cond = sync.NewCond(&t.mu)
+
+	b.RLock()
+	defer b.RUnlock()
+
+	if b.stopped {
+		t.closed = true
+		return t
+	}
+
+	b.tees.Lock()
+	b.tees.readers[t] = true
+	b.tees.Unlock()
+
+	return t
+}
+
+// Read implements io.Reader, blocking until data is written.
+func (t *Tee) Read(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for len(t.buf) == 0 && !t.closed {
+		t.cond.Wait()
+	}
+
+	if len(t.buf) == 0 {
+		return 0, io.EOF
+	}
+
+	n := copy(p, t.buf)
+	t.buf = t.buf[n:]
+	return n, nil
+}
+
+// Close implements io.Closer.
+func (t *Tee) Close() error {
+	t.tees.Lock()
+	delete(t.tees.readers, t)
+	t.tees.Unlock()
+
+	t.close()
+	return nil
+}
+
+// Dropped returns the number of writes dropped while the reader was full.
+func (t *Tee) Dropped() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.dropped
+}
+
+// Append a copy of `data`, dropping it when full.
+func (t *Tee) push(data []byte) {
+	t.mu.Lock()
+	if len(t.buf)+len(data) > teeBuffer {
+		t.dropped++
+	} else {
+		t.buf = append(t.buf, data...)
+	}
+	t.mu.Unlock()
+	t.cond.Broadcast()
+}
+
+// Mark the reader closed.
+func (t *Tee) close() {
+	t.mu.Lock()
+	t.closed = true
+	t.mu.Unlock()
+	t.cond.Broadcast()
+}
+
+// New tee registry.
+func newTees() *tees {
+	return &tees{readers: make(map[*Tee]bool)}
+}
+
+// Copy `data` to the readers.
+func (t *tees) push(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	for r := range t.readers {
+		r.push(data)
+	}
+}
+
+// Close the readers.
+func (t *tees) close() {
+	t.Lock()
+	defer t.Unlock()
+
+	for r := range t.readers {
+		r.close()
+		delete(t.readers, r)
+	}
+}
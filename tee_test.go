@@ -0,0 +1,46 @@
+package buffer
+
+import (
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test live copies of writes.
+func TestBuffer_TeeReader(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1000,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("before\n"))
+
+	r := b.TeeReader()
+	b.Write([]byte("hello\n"))
+	b.WriteKeyed("tenant", []byte("world\n"))
+
+	p := make([]byte, 6)
+	n, err := r.Read(p)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "hello\n", string(p[:n]))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	rest, err := ioutil.ReadAll(r)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "world\n", string(rest))
+
+	_, err = b.TeeReader().Read(p)
+	assert.Equal(t, io.EOF, err)
+
+	full := b.TeeReader().(*Tee)
+	full.push(make([]byte, teeBuffer))
+	full.push([]byte("dropped\n"))
+	assert.Equal(t, int64(1), full.Dropped())
+	full.Close()
+}
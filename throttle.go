@@ -0,0 +1,114 @@
+package buffer
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Throttle caps the bandwidth and concurrency of consumers shipping
+// flushed files, so draining a backlog does not starve other traffic.
+// It is safe for concurrent use by multiple consumers.
+type Throttle struct {
+	mu    sync.Mutex
+	bytes *tokenBucket
+	slots chan struct{}
+}
+
+// NewThrottle returns a throttle of `rate` bytes per second shared by up
+// to `concurrency` files shipped at once, either zero for no limit.
+func NewThrottle(rate int64, concurrency int) *Throttle {
+	t := &Throttle{bytes: newTokenBucket(rate)}
+	if concurrency != 0 {
+		t.slots = make(chan struct{}, concurrency)
+	}
+
+	return t
+}
+
+// Open returns a reader of flushed file `f` for shipping, or of its data
+// when held in memory, waiting for a free slot first. Reads wait to stay within the bandwidth, and fail
+// with the context error once `ctx` is done. Closing the reader frees
+// its slot.
+func (t *Throttle) Open(ctx context.Context, f *Flush) (io.ReadCloser, error) {
+	if t.slots != nil {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case t.slots <- struct{}{}:
+		}
+	}
+
+	file, _, err := openData(f)
+	if err != nil {
+		t.release()
+		return nil, err
+	}
+
+	return &throttled{t: t, ctx: ctx, file: file}, nil
+}
+
+// Free a slot.
+func (t *Throttle) release() {
+	if t.slots != nil {
+		<-t.slots
+	}
+}
+
+// Wait until `n` bytes may be shipped.
+func (t *Throttle) reserve(ctx context.Context, n int) error {
+	t.mu.Lock()
+	t.bytes.refill(time.Now())
+	wait := t.bytes.wait(float64(n))
+	t.bytes.take(float64(n))
+	t.mu.Unlock()
+
+	if wait == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Throttled reader of a file being shipped.
+type throttled struct {
+	t    *Throttle
+	ctx  context.Context
+	file io.ReadCloser
+	once sync.Once
+}
+
+// Read implements io.Reader.
+func (r *throttled) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	if b := r.t.bytes; b != nil && float64(len(p)) > b.rate {
+		p = p[:int(b.rate)]
+	}
+
+	n, err := r.file.Read(p)
+	if n > 0 && r.t.bytes != nil {
+		if werr := r.t.reserve(r.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+
+	return n, err
+}
+
+// Close implements io.Closer, freeing the slot.
+func (r *throttled) Close() error {
+	r.once.Do(r.t.release)
+	return r.file.Close()
+}
@@ -0,0 +1,40 @@
+package buffer
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test capping shipping bandwidth and concurrency.
+func TestThrottle(t *testing.T) {
+	err := ioutil.WriteFile("/tmp/buffer.throttle", make([]byte, 150), 0666)
+	assert.Equal(t, nil, err)
+
+	f := &Flush{Path: "/tmp/buffer.throttle"}
+	throttle := NewThrottle(100, 1)
+
+	r, err := throttle.Open(context.Background(), f)
+	assert.Equal(t, nil, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	_, err = throttle.Open(ctx, f)
+	cancel()
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	start := time.Now()
+	data, err := ioutil.ReadAll(r)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 150, len(data))
+	assert.Equal(t, true, time.Since(start) >= 400*time.Millisecond)
+
+	err = r.Close()
+	assert.Equal(t, nil, err)
+
+	r, err = throttle.Open(context.Background(), f)
+	assert.Equal(t, nil, err)
+	r.Close()
+}
@@ -0,0 +1,82 @@
+package buffer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// Trailer magic bytes.
+var trailerMagic = []byte("DBTR")
+
+// TrailerSize is the size of the trailer appended to flushed files when
+// Config.Trailer is set: magic bytes, the big-endian CRC32C of the data
+// and the big-endian record count, including delimited header and footer
+// records. Readers should strip it.
+const TrailerSize = 16
+
+// CRC32C table.
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Verification errors.
+var (
+	ErrNoTrailer = errors.New("missing trailer")
+	ErrChecksum  = errors.New("checksum mismatch")
+)
+
+// Append the trailer to the open file.
+func (b *Buffer) trailer() error {
+	buf := make([]byte, 0, TrailerSize)
+	buf = append(buf, trailerMagic...)
+	buf = binary.BigEndian.AppendUint32(buf, b.crc.Sum32())
+	buf = binary.BigEndian.AppendUint64(buf, uint64(b.writes+b.framed))
+
+	_, err := b.sink.Write(buf)
+	return err
+}
+
+// Verify the trailer of the flushed file at `path`, returning the record
+// count. ErrNoTrailer is returned for files which are truncated or were
+// written without a trailer, and ErrChecksum when the data is corrupt.
+func Verify(path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	size := info.Size() - TrailerSize
+	if size < 0 {
+		return 0, ErrNoTrailer
+	}
+
+	h := crc32.New(castagnoli)
+	_, err = io.CopyN(h, f, size)
+	if err != nil {
+		return 0, err
+	}
+
+	trailer := make([]byte, TrailerSize)
+	_, err = io.ReadFull(f, trailer)
+	if err != nil {
+		return 0, err
+	}
+
+	if !bytes.Equal(trailer[:4], trailerMagic) {
+		return 0, ErrNoTrailer
+	}
+
+	if binary.BigEndian.Uint32(trailer[4:8]) != h.Sum32() {
+		return 0, ErrChecksum
+	}
+
+	return int64(binary.BigEndian.Uint64(trailer[8:])), nil
+}
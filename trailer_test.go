@@ -0,0 +1,102 @@
+package buffer
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test appending and verifying trailers.
+func TestBuffer_Trailer(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 3,
+		Trailer:     true,
+		Checksum:    "crc32",
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello\n"))
+	b.Write([]byte("world\n"))
+	b.Write([]byte("tobi\n"))
+
+	f := <-b.Queue
+
+	n, err := Verify(f.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(3), n)
+
+	data, err := ioutil.ReadFile(f.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "hello\nworld\ntobi\n", string(data[:len(data)-TrailerSize]))
+
+	h := checksums["crc32"]()
+	h.Write(data)
+	assert.Equal(t, hex.EncodeToString(h.Sum(nil)), f.Checksum)
+
+	data[0] = 'j'
+	err = ioutil.WriteFile(f.Path, data, 0644)
+	assert.Equal(t, nil, err)
+
+	_, err = Verify(f.Path)
+	assert.Equal(t, ErrChecksum, err)
+
+	err = os.Truncate(f.Path, int64(len(data)-4))
+	assert.Equal(t, nil, err)
+
+	_, err = Verify(f.Path)
+	assert.Equal(t, ErrNoTrailer, err)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test header and footer records are counted in the trailer.
+func TestBuffer_Trailer_framed(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 10),
+		FlushWrites: 2,
+		Delimiter:   []byte("\n"),
+		Trailer:     true,
+		CSVHeader:   []string{"name", "species"},
+		Hooks: Hooks{
+			Footer: func(w io.Writer, f *Flush) error {
+				_, err := fmt.Fprintf(w, "# %d records\n", f.Writes)
+				return err
+			},
+		},
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.WriteCSV([]string{"tobi", "ferret"})
+	b.WriteCSV([]string{"loki", "ferret"})
+
+	f := <-b.Queue
+
+	n, err := Verify(f.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(4), n)
+
+	s, err := Records(f.Path, &Config{Delimiter: []byte("\n")})
+	assert.Equal(t, nil, err)
+
+	var records []string
+	for s.Scan() {
+		records = append(records, string(s.Record()))
+	}
+
+	assert.Equal(t, nil, s.Err())
+	assert.Equal(t, []string{"name,species", "tobi,ferret", "loki,ferret", "# 2 records"}, records)
+	s.Close()
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
@@ -0,0 +1,139 @@
+package buffer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Evict the file at `path` with its sidecars, moving them to the trash
+// directory when set so they can be restored within Config.TrashRetention.
+func (b *Buffer) evict(path string) error {
+	b.states.set(path, Evicted, nil)
+
+	if b.TrashDir == "" {
+		for _, s := range sidecars(path) {
+			err := os.Remove(s)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+
+		return os.Remove(path)
+	}
+
+	target := filepath.Join(b.TrashDir, filepath.Base(path))
+	b.log(1, "trashing %q", path)
+
+	err := b.mkdir(target)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(path, target)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	err = os.Chtimes(target, now, now)
+	if err != nil {
+		return err
+	}
+
+	err = moveSidecars(path, target, now)
+	if err != nil {
+		return err
+	}
+
+	return b.journal(evicted, &Flush{Version: FlushVersion, Path: path}, nil)
+}
+
+// Restore the evicted file `name` from the trash directory, moving it
+// next to flushed files, charging it to the quota and publishing it with
+// reason Restored.
+func (b *Buffer) Restore(name string) (*Flush, error) {
+	dir := filepath.Dir(b.path)
+	if b.OutDir != "" {
+		dir = b.OutDir
+	}
+
+	b.log(1, "restoring %q", filepath.Base(name))
+	f, err := RestoreFile(filepath.Join(b.TrashDir, filepath.Base(name)), dir)
+	if err != nil {
+		return nil, err
+	}
+
+	err = b.journal(flushed, f, nil)
+	if err != nil {
+		return f, err
+	}
+
+	b.staleness.track(f)
+	b.charge(f)
+
+	err = b.publish(f)
+	b.reclaim()
+	return f, err
+}
+
+// RestoreFile moves the evicted file at `path` in a trash directory into
+// `dir` with its sidecars, returning its flush with reason Restored.
+func RestoreFile(path, dir string) (*Flush, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Flush{
+		Version: FlushVersion,
+		Reason:  Restored,
+		Path:    filepath.Join(dir, info.Name()),
+		Bytes:   info.Size(),
+		Closed:  time.Now(),
+	}
+
+	err = os.Rename(path, f.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	return f, moveSidecars(path, f.Path, time.Time{})
+}
+
+// Sidecar files written alongside the flushed file at `path`.
+func sidecars(path string) []string {
+	paths := []string{path + MetaFileSuffix}
+	for name := range checksums {
+		paths = append(paths, path+"."+name)
+	}
+
+	return paths
+}
+
+// Move the sidecars of the file at `path` to accompany `target`, touching
+// them with `now` unless zero.
+func moveSidecars(path, target string, now time.Time) error {
+	for _, s := range sidecars(path) {
+		moved := target + strings.TrimPrefix(s, path)
+
+		err := os.Rename(s, moved)
+		if os.IsNotExist(err) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if !now.IsZero() {
+			err = os.Chtimes(moved, now, now)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
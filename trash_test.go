@@ -0,0 +1,112 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test trashing evicted files and restoring them.
+func TestBuffer_Restore(t *testing.T) {
+	os.RemoveAll("/tmp/buffer-trash")
+	os.RemoveAll("/tmp/buffer-spool")
+	os.MkdirAll("/tmp/buffer-spool", 0755)
+
+	path := "/tmp/buffer-spool/buffer.stale"
+	err := ioutil.WriteFile(path, nil, 0644)
+	assert.Equal(t, nil, err)
+
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(path, old, old)
+
+	b, err := New("/tmp/buffer-spool/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 100,
+		RemoveStale: time.Minute,
+		TrashDir:    "/tmp/buffer-trash",
+		Manifest:    "/tmp/buffer-spool/manifest",
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	_, err = os.Stat(path)
+	assert.Equal(t, true, os.IsNotExist(err))
+
+	files, _ := filepath.Glob("/tmp/buffer-trash/*")
+	assert.Equal(t, []string{"/tmp/buffer-trash/buffer.stale"}, files)
+
+	f, err := b.Restore("buffer.stale")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, Restored, f.Reason)
+	assert.Equal(t, path, f.Path)
+	assert.Equal(t, f, <-b.Queue)
+
+	_, err = os.Stat(path)
+	assert.Equal(t, nil, err)
+	os.Remove(path)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test evicted sidecars are trashed and restored files charged to the quota.
+func TestBuffer_Restore_Quota(t *testing.T) {
+	os.RemoveAll("/tmp/buffer-trash")
+	os.RemoveAll("/tmp/buffer-quota")
+	os.MkdirAll("/tmp/buffer-quota", 0755)
+
+	q := NewQuotaManager(8)
+	b, err := New("/tmp/buffer-quota/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1,
+		Quota:       q,
+		TrashDir:    "/tmp/buffer-trash",
+		Checksum:    "sha256",
+		Sidecar:     true,
+		MetaFile:    true,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello"))
+	b.Write([]byte("world"))
+
+	evicted := <-b.Queue
+	acked := <-b.Queue
+	assert.Equal(t, acked.Bytes, q.Usage())
+
+	name := filepath.Base(evicted.Path)
+	files, _ := filepath.Glob("/tmp/buffer-trash/*")
+	assert.Equal(t, []string{
+		"/tmp/buffer-trash/" + name,
+		"/tmp/buffer-trash/" + name + MetaFileSuffix,
+		"/tmp/buffer-trash/" + name + ".sha256",
+	}, files)
+
+	b.Ack(acked)
+	assert.Equal(t, int64(0), q.Usage())
+
+	f, err := b.Restore(name)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, evicted.Path, f.Path)
+	assert.Equal(t, f.Bytes, q.Usage())
+	assert.Equal(t, f, <-b.Queue)
+
+	_, err = VerifySidecar(f.Path)
+	assert.Equal(t, nil, err)
+
+	_, err = os.Stat(f.Path + MetaFileSuffix)
+	assert.Equal(t, nil, err)
+
+	files, _ = filepath.Glob("/tmp/buffer-trash/*")
+	assert.Equal(t, 0, len(files))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
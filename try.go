@@ -0,0 +1,109 @@
+package buffer
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrWouldBlock is returned by TryWrite when the write would wait.
+var ErrWouldBlock = errors.New("write would block")
+
+// TryWrite writes like Write, failing with ErrWouldBlock instead of
+// waiting for a flush in progress, for a full Async ring, or for room
+// in a full queue under the Block policy.
+func (b *Buffer) TryWrite(data []byte) (n int, err error) {
+	b.log(3, "try write %s", data)
+
+	if b.Instrument != nil {
+		start := time.Now()
+		defer func() {
+			b.Instrument.Wrote(n, time.Since(start), err)
+		}()
+	}
+
+	err = b.checkSize(len(data))
+	if err != nil {
+		return 0, err
+	}
+
+	drop, err := b.throttle(1, len(data), true)
+	if err == nil && !drop {
+		drop, err = b.guard()
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	if drop {
+		return len(data), nil
+	}
+
+	if b.Async != 0 {
+		return b.tryEnqueue(data)
+	}
+
+	if !b.TryLock() {
+		return 0, ErrWouldBlock
+	}
+	defer b.Unlock()
+
+	if b.blocks(len(data)) {
+		return 0, ErrWouldBlock
+	}
+
+	n, err = b.store(data, nil)
+	if err == nil && b.Shadow != nil {
+		b.mirror(data, nil)
+	}
+
+	return n, err
+}
+
+// Enqueue a copy of `data` unless the ring is full.
+func (b *Buffer) tryEnqueue(data []byte) (int, error) {
+	b.RLock()
+	draining := b.draining
+	b.RUnlock()
+
+	if draining {
+		return 0, ErrDraining
+	}
+
+	buf := make([]byte, len(data))
+	copy(buf, data)
+
+	if !b.ringMu.TryRLock() {
+		return 0, ErrWouldBlock
+	}
+	defer b.ringMu.RUnlock()
+
+	if b.ringClosed {
+		return 0, ErrClosed
+	}
+
+	select {
+	case b.ring <- op{data: buf}:
+		return len(data), nil
+	default:
+		return 0, ErrWouldBlock
+	}
+}
+
+// Whether writing `n` bytes would flush into a full queue which blocks.
+func (b *Buffer) blocks(n int) bool {
+	if b.Queue == nil || len(b.Queue) < cap(b.Queue) || b.Policy(QueueFull) != Block {
+		return false
+	}
+
+	switch {
+	case b.FlushWrites != 0 && b.writes+1 >= b.FlushWrites:
+		return true
+	case b.FlushBytes != 0 && b.bytes+int64(n) >= b.FlushBytes:
+		return true
+	case b.FlushBucket != 0 && !time.Now().Before(b.bucket.Add(b.FlushBucket)):
+		return b.writes != 0
+	default:
+		return false
+	}
+}
@@ -0,0 +1,47 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test non-blocking writes.
+func TestBuffer_TryWrite(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 1),
+		FlushWrites: 2,
+	})
+
+	assert.Equal(t, nil, err)
+
+	n, err := b.TryWrite([]byte("hello\n"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 6, n)
+
+	_, err = b.TryWrite([]byte("world\n"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, len(b.Queue))
+
+	_, err = b.TryWrite([]byte("hello\n"))
+	assert.Equal(t, nil, err)
+
+	_, err = b.TryWrite([]byte("world\n"))
+	assert.Equal(t, ErrWouldBlock, err)
+
+	b.Lock()
+	_, err = b.TryWrite([]byte("hello\n"))
+	assert.Equal(t, ErrWouldBlock, err)
+	b.Unlock()
+
+	<-b.Queue
+
+	_, err = b.TryWrite([]byte("world\n"))
+	assert.Equal(t, nil, err)
+
+	flush := <-b.Queue
+	assert.Equal(t, int64(2), flush.Writes)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
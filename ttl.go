@@ -0,0 +1,156 @@
+package buffer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ExpiresKey is the annotation holding the expiry of a record written
+// with WriteWithTTL, in Unix nanoseconds.
+const ExpiresKey = "expires"
+
+// Splitter is implemented by envelopes whose records span lines, to
+// split files into records, see bufio.SplitFunc. Records are otherwise
+// split on newlines.
+type Splitter interface {
+	Split(data []byte, atEOF bool) (advance int, token []byte, err error)
+}
+
+// WriteWithTTL writes `data` annotated to expire after `ttl`, so that
+// Expire drops it from files which sat in a backlog for longer.
+func (b *Buffer) WriteWithTTL(data []byte, ttl time.Duration) (int, error) {
+	expires := time.Now().Add(ttl).UnixNano()
+	return b.WriteWithMeta(data, map[string]string{ExpiresKey: strconv.FormatInt(expires, 10)})
+}
+
+// Expire drops expired records from the file of `f` before delivery,
+// rewriting it in place and counting them in Flush.Expired. Records are
+// decoded with Config.Envelope. Compressed and encrypted files are not
+// supported.
+func (b *Buffer) Expire(f *Flush) error {
+	if f.Codec != "" || f.KeyID != "" {
+		return fmt.Errorf("expiring records of compressed or encrypted files is not supported")
+	}
+
+	src, err := os.Open(f.Path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp := f.Path + ".tmp"
+	dst, err := b.createFile(tmp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	env := b.envelope()
+	s := bufio.NewScanner(src)
+	s.Buffer(nil, 1<<26)
+	s.Split(scanLines)
+	if sp, ok := env.(Splitter); ok {
+		s.Split(sp.Split)
+	}
+
+	w := bufio.NewWriter(dst)
+	now := time.Now().UnixNano()
+
+	var expired, size int64
+	for s.Scan() {
+		record := s.Bytes()
+
+		meta, _, err := env.Decode(record)
+		if err == nil && meta[ExpiresKey] != "" {
+			expires, err := strconv.ParseInt(meta[ExpiresKey], 10, 64)
+			if err == nil && expires < now {
+				expired++
+				continue
+			}
+		}
+
+		n, err := w.Write(record)
+		size += int64(n)
+		if err != nil {
+			dst.Close()
+			return err
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		dst.Close()
+		return err
+	}
+
+	err = w.Flush()
+	if err != nil {
+		dst.Close()
+		return err
+	}
+
+	err = dst.Close()
+	if err != nil {
+		return err
+	}
+
+	if expired == 0 {
+		return nil
+	}
+
+	b.log(1, "dropped %d expired records from %q", expired, f.Path)
+	err = os.Rename(tmp, f.Path)
+	if err != nil {
+		return err
+	}
+
+	f.Expired += expired
+	f.Bytes = size
+	if f.Writes >= expired {
+		f.Writes -= expired
+	}
+
+	return nil
+}
+
+// Split records on newlines, keeping them.
+func scanLines(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i+1], nil
+	}
+
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+
+	return 0, nil, nil
+}
+
+// Split implements Splitter, keeping the annotations line with the data
+// line which follows it.
+func (QueryEnvelope) Split(data []byte, atEOF bool) (int, []byte, error) {
+	if len(data) == 0 || data[0] != MetaPrefix {
+		return scanLines(data, atEOF)
+	}
+
+	i := bytes.IndexByte(data, '\n')
+	if i == -1 {
+		if atEOF {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+
+	n, _, err := scanLines(data[i+1:], atEOF)
+	if n == 0 {
+		if atEOF {
+			return len(data), data, nil
+		}
+		return 0, nil, err
+	}
+
+	return i + 1 + n, data[:i+1+n], err
+}
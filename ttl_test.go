@@ -0,0 +1,51 @@
+package buffer
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test dropping expired records.
+func TestBuffer_Expire(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 4,
+		Verbosity:   0,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.WriteWithTTL([]byte("stale\n"), -time.Second)
+	b.WriteWithTTL([]byte("fresh\n"), time.Hour)
+	b.Write([]byte("plain\n"))
+	b.WriteWithMeta([]byte("tagged\n"), map[string]string{"level": "info"})
+
+	f := <-b.Queue
+	err = b.Expire(f)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(1), f.Expired)
+	assert.Equal(t, int64(3), f.Writes)
+
+	data, err := ioutil.ReadFile(f.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, f.Bytes, int64(len(data)))
+
+	var records []string
+	s := bufio.NewScanner(bytes.NewReader(data))
+	s.Split(QueryEnvelope{}.Split)
+	for s.Scan() {
+		_, record, err := ParseMeta(s.Bytes())
+		assert.Equal(t, nil, err)
+		records = append(records, string(record))
+	}
+
+	assert.Equal(t, []string{"fresh\n", "plain\n", "tagged\n"}, records)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
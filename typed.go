@@ -0,0 +1,51 @@
+package buffer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Encoder encodes values of T as records, such as with json.Marshal or
+// proto.Marshal.
+type Encoder[T any] func(v T) ([]byte, error)
+
+// Typed buffers values of T, encoding each as one record and framing it
+// with Config.Delimiter when set, or a varint length prefix as with
+// WriteDelimited otherwise.
+type Typed[T any] struct {
+	*Buffer
+	encode Encoder[T]
+}
+
+// NewTyped returns a buffer of values of T written to `b`, encoded with
+// `encode`.
+func NewTyped[T any](b *Buffer, encode Encoder[T]) *Typed[T] {
+	return &Typed[T]{Buffer: b, encode: encode}
+}
+
+// JSON encodes `v` with json.Marshal.
+func JSON[T any](v T) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Append encodes and writes `v` as one record. Records containing the
+// delimiter are rejected, as they could not be split back out.
+func (t *Typed[T]) Append(v T) error {
+	data, err := t.encode(v)
+	if err != nil {
+		return err
+	}
+
+	if len(t.Delimiter) == 0 {
+		_, err = t.WriteDelimited(data)
+		return err
+	}
+
+	if bytes.Contains(data, t.Delimiter) {
+		return fmt.Errorf("encoded record contains the delimiter %q", t.Delimiter)
+	}
+
+	_, err = t.Write(data)
+	return err
+}
@@ -0,0 +1,84 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Typed test value.
+type pet struct {
+	Name string `json:"name"`
+}
+
+// Test appending values framed by the delimiter.
+func TestTyped_Append(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 2,
+		Delimiter:   []byte("\n"),
+	})
+
+	assert.Equal(t, nil, err)
+
+	pets := NewTyped(b, JSON[pet])
+
+	err = pets.Append(pet{"tobi"})
+	assert.Equal(t, nil, err)
+
+	err = pets.Append(pet{"loki"})
+	assert.Equal(t, nil, err)
+
+	flush := <-b.Queue
+	assert.Equal(t, int64(2), flush.Writes)
+	assert.Equal(t, int64(32), flush.Bytes)
+
+	data, err := ioutil.ReadFile(flush.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "{\"name\":\"tobi\"}\n{\"name\":\"loki\"}\n", string(data))
+
+	lines := NewTyped(b, func(s string) ([]byte, error) { return []byte(s), nil })
+	err = lines.Append("two\nlines")
+	assert.NotEqual(t, nil, err)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test appending values framed by length prefixes.
+func TestTyped_AppendDelimited(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 2,
+	})
+
+	assert.Equal(t, nil, err)
+
+	pets := NewTyped(b, JSON[pet])
+
+	err = pets.Append(pet{"tobi"})
+	assert.Equal(t, nil, err)
+
+	err = pets.Append(pet{"loki"})
+	assert.Equal(t, nil, err)
+
+	flush := <-b.Queue
+
+	f, err := os.Open(flush.Path)
+	assert.Equal(t, nil, err)
+	defer f.Close()
+
+	r := NewDelimitedReader(f, 0)
+	record, err := r.Next()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "{\"name\":\"tobi\"}", string(record))
+
+	record, err = r.Next()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "{\"name\":\"loki\"}", string(record))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
@@ -0,0 +1,112 @@
+package buffer
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// FlushVersion is the version of the Flush schema written to manifests
+// and metadata files. Flushes serialized without a version are version 1.
+const FlushVersion = 2
+
+// Decoders of serialized flushes by schema version, upgrading them to
+// the current version.
+var flushDecoders = map[int]func(data []byte, f *Flush) error{
+	1: decodeFlushV1,
+	2: decodeFlush,
+}
+
+// Schema of the current version.
+type flushSchema Flush
+
+// Decode a flush with the current schema.
+func decodeFlush(data []byte, f *Flush) error {
+	return json.Unmarshal(data, (*flushSchema)(f))
+}
+
+// Schema of version 1, flushes serialized before versioning.
+type flushV1 struct {
+	Reason   Reason              `json:"reason"`
+	Path     string              `json:"path"`
+	Key      string              `json:"key,omitempty"`
+	KeyID    string              `json:"key_id,omitempty"`
+	Batches  []string            `json:"batches,omitempty"`
+	Meta     map[string][]string `json:"meta,omitempty"`
+	Codec    string              `json:"codec,omitempty"`
+	Hash     string              `json:"hash,omitempty"`
+	Checksum string              `json:"checksum,omitempty"`
+	Bucket   time.Time           `json:"bucket"`
+	Seq      int64               `json:"seq"`
+	Writes   int64               `json:"writes"`
+	Bytes    int64               `json:"bytes"`
+	Opened   time.Time           `json:"opened"`
+	First    time.Time           `json:"first"`
+	Closed   time.Time           `json:"closed"`
+	Age      time.Duration       `json:"age"`
+	Attempts []Attempt           `json:"attempts,omitempty"`
+	Expired  int64               `json:"expired,omitempty"`
+}
+
+// Decode a version 1 flush, ignoring fields it did not have.
+func decodeFlushV1(data []byte, f *Flush) error {
+	var v flushV1
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return err
+	}
+
+	*f = Flush{
+		Reason:   v.Reason,
+		Path:     v.Path,
+		Key:      v.Key,
+		KeyID:    v.KeyID,
+		Batches:  v.Batches,
+		Meta:     v.Meta,
+		Codec:    v.Codec,
+		Hash:     v.Hash,
+		Checksum: v.Checksum,
+		Bucket:   v.Bucket,
+		Seq:      v.Seq,
+		Writes:   v.Writes,
+		Bytes:    v.Bytes,
+		Opened:   v.Opened,
+		First:    v.First,
+		Closed:   v.Closed,
+		Age:      v.Age,
+		Attempts: v.Attempts,
+		Expired:  v.Expired,
+	}
+
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding flushes of any
+// supported schema version as the current version.
+func (f *Flush) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Version int `json:"version"`
+	}
+
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return err
+	}
+
+	if v.Version == 0 {
+		v.Version = 1
+	}
+
+	decode, ok := flushDecoders[v.Version]
+	if !ok {
+		return fmt.Errorf("unsupported flush version %d", v.Version)
+	}
+
+	err = decode(data, f)
+	if err != nil {
+		return err
+	}
+
+	f.Version = FlushVersion
+	return nil
+}
@@ -0,0 +1,51 @@
+package buffer
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test decoding versioned flushes.
+func TestBuffer_FlushVersion(t *testing.T) {
+	var f Flush
+	err := json.Unmarshal([]byte(`{"reason":"writes","path":"/tmp/buffer.1","writes":5,"dict":"0000abcd"}`), &f)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, FlushVersion, f.Version)
+	assert.Equal(t, Writes, f.Reason)
+	assert.Equal(t, int64(5), f.Writes)
+	assert.Equal(t, "", f.Dict)
+
+	err = json.Unmarshal([]byte(`{"version":2,"reason":"writes","path":"/tmp/buffer.1","dict":"0000abcd"}`), &f)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "0000abcd", f.Dict)
+
+	err = json.Unmarshal([]byte(`{"version":99,"path":"/tmp/buffer.1"}`), &f)
+	assert.Equal(t, "unsupported flush version 99", err.Error())
+
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 1,
+		MetaFile:    true,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("hello\n"))
+	flush := <-b.Queue
+
+	data, err := ioutil.ReadFile(flush.Path + MetaFileSuffix)
+	assert.Equal(t, nil, err)
+
+	var decoded Flush
+	err = json.Unmarshal(data, &decoded)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, FlushVersion, decoded.Version)
+	assert.Equal(t, flush.Path, decoded.Path)
+	assert.Equal(t, flush.Seq, decoded.Seq)
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
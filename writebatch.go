@@ -0,0 +1,109 @@
+package buffer
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// WriteBatch writes `records` under one lock acquisition, checking flush
+// thresholds once after the last record, so the batch lands in a single
+// file. Without a BufferSize they are written with one vectored write
+// where supported. With Async or SingleWriter the records are submitted
+// one by one.
+// It returns the number of bytes written.
+func (b *Buffer) WriteBatch(records [][]byte) (n int, err error) {
+	for _, r := range records {
+		err := b.checkSize(len(r))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if b.ringed() {
+		for _, r := range records {
+			m, err := b.submit(r, nil)
+			n += m
+			if err != nil {
+				return n, err
+			}
+		}
+		return n, nil
+	}
+
+	if b.Instrument != nil {
+		start := time.Now()
+		defer func() {
+			b.Instrument.Wrote(n, time.Since(start), err)
+		}()
+	}
+
+	if len(records) == 0 {
+		return 0, nil
+	}
+
+	size := 0
+	for _, r := range records {
+		size += len(r)
+	}
+
+	drop, err := b.throttle(len(records), size, false)
+	if err == nil && !drop {
+		drop, err = b.guard()
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	if drop {
+		return size, nil
+	}
+
+	if b.Shadow != nil {
+		defer func() {
+			if err == nil {
+				for _, r := range records {
+					b.mirror(r, nil)
+				}
+			}
+		}()
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	defer func() {
+		if err != nil {
+			atomic.AddInt64(&b.errors, 1)
+		}
+	}()
+
+	err = b.admit()
+	if err != nil {
+		return 0, err
+	}
+
+	err = b.fit(size + len(records)*len(b.Delimiter))
+	if err != nil {
+		return 0, err
+	}
+
+	if b.vectored() {
+		n, err = b.writev(records)
+		if err != nil {
+			return n, err
+		}
+
+		return n, b.thresholds(records[len(records)-1])
+	}
+
+	for _, r := range records {
+		m, err := b.write(r)
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, b.thresholds(records[len(records)-1])
+}
@@ -0,0 +1,184 @@
+package buffer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// Test writing batches of records.
+func TestBuffer_WriteBatch(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 2,
+	})
+
+	assert.Equal(t, nil, err)
+
+	b.Write([]byte("first\n"))
+
+	n, err := b.WriteBatch([][]byte{
+		[]byte("hello\n"),
+		[]byte("world\n"),
+		[]byte("again\n"),
+	})
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 18, n)
+
+	flush := <-b.Queue
+	assert.Equal(t, int64(4), flush.Writes)
+
+	data, err := ioutil.ReadFile(flush.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "first\nhello\nworld\nagain\n", string(data))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	_, err = b.WriteBatch([][]byte{[]byte("hello\n")})
+	assert.Equal(t, ErrClosed, err)
+}
+
+// Benchmark writing batches of records.
+func BenchmarkBuffer_WriteBatch(t *testing.B) {
+	b, err := New("/tmp/buffer", &Config{
+		FlushWrites:   30000,
+		FlushBytes:    1 << 30,
+		FlushInterval: time.Minute,
+		BufferSize:    1 << 10,
+		Verbosity:     0,
+	})
+
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+
+	discard(b)
+
+	batch := make([][]byte, 100)
+	for i := range batch {
+		batch[i] = []byte("hello world")
+	}
+
+	t.ResetTimer()
+
+	t.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			b.WriteBatch(batch)
+		}
+	})
+}
+
+// Test writing batches with vectored writes.
+func TestBuffer_WriteBatch_Vectored(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 2500,
+	})
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, b.vectored())
+
+	var records [][]byte
+	var expected []byte
+	for i := 0; i < 2500; i++ {
+		r := []byte(fmt.Sprintf("record %d\n", i))
+		if i%100 == 0 {
+			r = nil
+		}
+		records = append(records, r)
+		expected = append(expected, r...)
+	}
+
+	n, err := b.WriteBatch(records)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, len(expected), n)
+
+	flush := <-b.Queue
+	assert.Equal(t, int64(2500), flush.Writes)
+	assert.Equal(t, int64(len(expected)), flush.Bytes)
+
+	data, err := ioutil.ReadFile(flush.Path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, string(expected), string(data))
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test counters are unwound when vectored writes fail.
+func TestBuffer_WriteBatch_Vectored_error(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 100,
+	})
+
+	assert.Equal(t, nil, err)
+
+	_, err = b.WriteBatch([][]byte{[]byte("hello")})
+	assert.Equal(t, nil, err)
+
+	b.Lock()
+	file := b.file
+	ro, err := os.Open(file.Name())
+	assert.Equal(t, nil, err)
+	b.file, b.w = ro, ro
+	b.Unlock()
+
+	_, err = b.WriteBatch([][]byte{[]byte("hello"), []byte("world")})
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, int64(1), b.Writes())
+	assert.Equal(t, int64(5), b.Bytes())
+	assert.Equal(t, int64(1), b.Stats().Errors)
+
+	b.Lock()
+	b.file, b.w = file, file
+	b.Unlock()
+	ro.Close()
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+}
+
+// Test records written whole before a failed vectored write are teed.
+func TestBuffer_WriteBatch_Vectored_partial(t *testing.T) {
+	b, err := New("/tmp/buffer", &Config{
+		Queue:       make(chan *Flush, 100),
+		FlushWrites: 100,
+	})
+
+	assert.Equal(t, nil, err)
+
+	r, w, err := os.Pipe()
+	assert.Equal(t, nil, err)
+	defer r.Close()
+	w.SetWriteDeadline(time.Now().Add(50 * time.Millisecond))
+
+	tee := b.TeeReader()
+
+	b.Lock()
+	file := b.file
+	b.file, b.w = w, w
+	b.Unlock()
+
+	_, err = b.WriteBatch([][]byte{[]byte("hello"), make([]byte, 256<<10)})
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, int64(1), b.Writes())
+
+	b.Lock()
+	b.file, b.w = file, file
+	b.Unlock()
+	w.Close()
+
+	err = b.Close()
+	assert.Equal(t, nil, err)
+
+	data, err := ioutil.ReadAll(tee)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "hello", string(data))
+}
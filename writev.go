@@ -0,0 +1,115 @@
+package buffer
+
+import (
+	"os"
+	"time"
+)
+
+// Whether writes go straight to the file, so batches can be written
+// with one vectored write.
+func (b *Buffer) vectored() bool {
+	return b.buf == nil && !b.Paranoid && b.w == b.file
+}
+
+// Write `records` to the file with vectored writes.
+func (b *Buffer) writev(records [][]byte) (int, error) {
+	if b.writes == 0 {
+		b.first = time.Now()
+	}
+
+	b.record()
+
+	if b.FlushIdle != 0 {
+		b.touch()
+	}
+
+	bufs := records
+	if len(b.Delimiter) != 0 {
+		bufs = make([][]byte, 0, 2*len(records))
+		for _, r := range records {
+			bufs = append(bufs, r, b.Delimiter)
+		}
+	}
+
+	var n int
+	for _, r := range bufs {
+		n += len(r)
+	}
+
+	b.writes += int64(len(records))
+	b.bytes += int64(n)
+	b.total.writes += int64(len(records))
+	b.total.bytes += int64(n)
+
+	m, err := writev(b.file, bufs)
+	done := len(records)
+	if err != nil {
+		done = complete(records, b.Delimiter, m)
+		failed := int64(len(records) - done)
+		b.writes -= failed
+		b.bytes -= int64(n - m)
+		b.total.writes -= failed
+		b.total.bytes -= int64(n - m)
+	}
+
+	per := 1
+	if len(b.Delimiter) != 0 {
+		per = 2
+	}
+
+	for _, r := range bufs[:per*done] {
+		b.tees.push(r)
+	}
+
+	if b.live != nil {
+		b.live.commit(b.committed())
+	}
+
+	return written(records, b.Delimiter, m), err
+}
+
+// Bytes of `records` among the first `n` bytes written of records
+// followed by `delim`.
+func written(records [][]byte, delim []byte, n int) int {
+	var w int
+	for _, r := range records {
+		if n <= len(r) {
+			return w + n
+		}
+
+		w += len(r)
+		n -= len(r) + len(delim)
+		if n <= 0 {
+			return w
+		}
+	}
+
+	return w
+}
+
+// Number of `records` written whole, with `delim`, within the first `n`
+// bytes written.
+func complete(records [][]byte, delim []byte, n int) int {
+	for i, r := range records {
+		n -= len(r) + len(delim)
+		if n < 0 {
+			return i
+		}
+	}
+
+	return len(records)
+}
+
+// Write `bufs` to `f` in order, one write at a time.
+func writeAll(f *os.File, bufs [][]byte) (int, error) {
+	var n int
+	for _, buf := range bufs {
+		m, err := f.Write(buf)
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
@@ -0,0 +1,81 @@
+package buffer
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// Maximum buffers per writev(2).
+const iovMax = 1024
+
+// Write `bufs` to `f` in order with writev(2), resuming partial writes.
+func writev(f *os.File, bufs [][]byte) (int, error) {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return writeAll(f, bufs)
+	}
+
+	bufs = append([][]byte(nil), bufs...)
+	iov := make([]syscall.Iovec, 0, iovMax)
+
+	var total int
+	for len(bufs) > 0 {
+		iov = iov[:0]
+		for _, buf := range bufs {
+			if len(iov) == iovMax {
+				break
+			}
+
+			if len(buf) == 0 {
+				continue
+			}
+
+			v := syscall.Iovec{Base: &buf[0]}
+			v.SetLen(len(buf))
+			iov = append(iov, v)
+		}
+
+		if len(iov) == 0 {
+			return total, nil
+		}
+
+		var n uintptr
+		var errno syscall.Errno
+		err := rc.Write(func(fd uintptr) bool {
+			n, _, errno = syscall.Syscall(syscall.SYS_WRITEV, fd, uintptr(unsafe.Pointer(&iov[0])), uintptr(len(iov)))
+			return errno != syscall.EAGAIN
+		})
+
+		if err != nil {
+			return total, err
+		}
+
+		if errno == syscall.EINTR {
+			continue
+		}
+
+		if errno != 0 {
+			return total, &os.PathError{Op: "writev", Path: f.Name(), Err: errno}
+		}
+
+		total += int(n)
+		bufs = consume(bufs, int(n))
+	}
+
+	return total, nil
+}
+
+// Consume `n` written bytes from the front of `bufs`.
+func consume(bufs [][]byte, n int) [][]byte {
+	for len(bufs) > 0 && n >= len(bufs[0]) {
+		n -= len(bufs[0])
+		bufs = bufs[1:]
+	}
+
+	if len(bufs) > 0 {
+		bufs[0] = bufs[0][n:]
+	}
+
+	return bufs
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package buffer
+
+import "os"
+
+// Write `bufs` to `f` in order.
+func writev(f *os.File, bufs [][]byte) (int, error) {
+	return writeAll(f, bufs)
+}
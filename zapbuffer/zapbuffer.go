@@ -0,0 +1,33 @@
+// Package zapbuffer adapts zap loggers for disk buffers.
+package zapbuffer
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/tj/go-disk-buffer"
+)
+
+// Logger implements buffer.Logger.
+type Logger struct {
+	sugar *zap.SugaredLogger
+}
+
+// New logger writing to `l`.
+func New(l *zap.Logger) buffer.Logger {
+	return &Logger{sugar: l.Sugar()}
+}
+
+// Debug implements buffer.Logger.
+func (l *Logger) Debug(msg string, keyvals ...interface{}) {
+	l.sugar.Debugw(msg, keyvals...)
+}
+
+// Info implements buffer.Logger.
+func (l *Logger) Info(msg string, keyvals ...interface{}) {
+	l.sugar.Infow(msg, keyvals...)
+}
+
+// Error implements buffer.Logger.
+func (l *Logger) Error(msg string, keyvals ...interface{}) {
+	l.sugar.Errorw(msg, keyvals...)
+}
@@ -0,0 +1,564 @@
+package buffer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+)
+
+// Zstandard frame and dictionary magic numbers.
+const (
+	zstdMagic     = 0xFD2FB528
+	zstdDictMagic = 0xEC30A437
+)
+
+// Maximum size of a zstd block.
+const zstdBlockSize = 128 << 10
+
+// Sequence codes of the zstd format.
+const (
+	maxLL = 35
+	maxML = 52
+	maxOF = 31
+)
+
+// Literal length baselines and extra bits by code.
+var llBase = [maxLL + 1]uint32{
+	0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15,
+	16, 18, 20, 22, 24, 28, 32, 40, 48, 64, 128, 256, 512, 1024, 2048, 4096,
+	8192, 16384, 32768, 65536,
+}
+
+var llBits = [maxLL + 1]uint8{
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	1, 1, 1, 1, 2, 2, 3, 3, 4, 6, 7, 8, 9, 10, 11, 12,
+	13, 14, 15, 16,
+}
+
+// Match length baselines and extra bits by code.
+var mlBase = [maxML + 1]uint32{
+	3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18,
+	19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31, 32, 33, 34,
+	35, 37, 39, 41, 43, 47, 51, 59, 67, 83, 99, 131, 259, 515, 1027, 2051,
+	4099, 8195, 16387, 32771, 65539,
+}
+
+var mlBits = [maxML + 1]uint8{
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	1, 1, 1, 1, 2, 2, 3, 3, 4, 4, 5, 7, 8, 9, 10, 11,
+	12, 13, 14, 15, 16,
+}
+
+// Predefined distributions of literal lengths, match lengths and offsets.
+var (
+	llDefault = []int16{
+		4, 3, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 1, 1, 1,
+		2, 2, 2, 2, 2, 2, 2, 2, 2, 3, 2, 1, 1, 1, 1, 1,
+		-1, -1, -1, -1,
+	}
+
+	mlDefault = []int16{
+		1, 4, 3, 2, 2, 2, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, -1, -1,
+		-1, -1, -1, -1, -1,
+	}
+
+	ofDefault = []int16{
+		1, 1, 1, 1, 1, 1, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, -1, -1, -1, -1, -1,
+	}
+)
+
+// Predefined decoding tables.
+var (
+	llTable = newFSETable(llDefault, 6)
+	mlTable = newFSETable(mlDefault, 6)
+	ofTable = newFSETable(ofDefault, 5)
+)
+
+// Code of `v` in `base`, the last baseline not above it.
+func zstdCode(base []uint32, v uint32) uint8 {
+	i := len(base) - 1
+	for base[i] > v {
+		i--
+	}
+	return uint8(i)
+}
+
+// Index of the highest set bit of `v`.
+func highbit(v uint32) uint {
+	return uint(bits.Len32(v)) - 1
+}
+
+// Entry of an FSE decoding table.
+type fseState struct {
+	symbol uint8
+	bits   uint8
+	base   uint16
+}
+
+// FSE decoding table.
+type fseTable struct {
+	log    uint
+	states []fseState
+}
+
+// Spread the symbols of normalized distribution `norm` over a table of
+// 1<<`log` cells, returning the symbol of each cell.
+func fseSpread(norm []int16, log uint) []uint8 {
+	size := 1 << log
+	cells := make([]uint8, size)
+	high := size - 1
+
+	for s, n := range norm {
+		if n == -1 {
+			cells[high] = uint8(s)
+			high--
+		}
+	}
+
+	step := size>>1 + size>>3 + 3
+	pos := 0
+	for s, n := range norm {
+		for i := 0; i < int(n); i++ {
+			cells[pos] = uint8(s)
+			pos = (pos + step) & (size - 1)
+			for pos > high {
+				pos = (pos + step) & (size - 1)
+			}
+		}
+	}
+
+	return cells
+}
+
+// Build the decoding table of normalized distribution `norm`.
+func newFSETable(norm []int16, log uint) *fseTable {
+	size := 1 << log
+	cells := fseSpread(norm, log)
+
+	next := make([]uint16, len(norm))
+	for s, n := range norm {
+		if n == -1 {
+			next[s] = 1
+		} else {
+			next[s] = uint16(n)
+		}
+	}
+
+	t := &fseTable{log: log, states: make([]fseState, size)}
+	for u, s := range cells {
+		n := next[s]
+		next[s]++
+		nbits := log - highbit(uint32(n))
+		t.states[u] = fseState{
+			symbol: s,
+			bits:   uint8(nbits),
+			base:   uint16(int(n)<<nbits - size),
+		}
+	}
+
+	return t
+}
+
+// Decoding table of a single symbol repeated.
+func rleTable(symbol uint8) *fseTable {
+	return &fseTable{states: []fseState{{symbol: symbol}}}
+}
+
+// FSE encoding table.
+type fseEncoder struct {
+	log    uint
+	states []uint16
+	delta  []int32
+	find   []int32
+}
+
+// Build the encoding table of normalized distribution `norm`.
+func newFSEEncoder(norm []int16, log uint) *fseEncoder {
+	size := 1 << log
+	cells := fseSpread(norm, log)
+
+	cumul := make([]int, len(norm)+1)
+	for s, n := range norm {
+		if n == -1 {
+			n = 1
+		}
+		cumul[s+1] = cumul[s] + int(n)
+	}
+
+	e := &fseEncoder{
+		log:    log,
+		states: make([]uint16, size),
+		delta:  make([]int32, len(norm)),
+		find:   make([]int32, len(norm)),
+	}
+
+	for u, s := range cells {
+		e.states[cumul[s]] = uint16(size + u)
+		cumul[s]++
+	}
+
+	total := 0
+	for s, n := range norm {
+		switch n {
+		case 0:
+		case -1, 1:
+			e.delta[s] = int32(log<<16) - int32(size)
+			e.find[s] = int32(total - 1)
+			total++
+		default:
+			out := log - highbit(uint32(n-1))
+			e.delta[s] = int32(out<<16) - int32(int(n)<<out)
+			e.find[s] = int32(total - int(n))
+			total += int(n)
+		}
+	}
+
+	return e
+}
+
+// Initial state encoding `symbol`.
+func (e *fseEncoder) init(symbol uint8) uint32 {
+	out := uint32(e.delta[symbol]+1<<15) >> 16
+	v := out<<16 - uint32(e.delta[symbol])
+	return uint32(e.states[int32(v>>out)+e.find[symbol]])
+}
+
+// Encode `symbol` from `state` into `w`, returning the next state.
+func (e *fseEncoder) encode(w *bitWriter, state uint32, symbol uint8) uint32 {
+	out := (state + uint32(e.delta[symbol])) >> 16
+	w.add(uint64(state), uint(out))
+	return uint32(e.states[int32(state>>out)+e.find[symbol]])
+}
+
+// Predefined encoding tables.
+var (
+	llEncoder = newFSEEncoder(llDefault, 6)
+	mlEncoder = newFSEEncoder(mlDefault, 6)
+	ofEncoder = newFSEEncoder(ofDefault, 5)
+)
+
+// Bit stream written forward from the low bits, and read backward.
+type bitWriter struct {
+	out   []byte
+	bits  uint64
+	count uint
+}
+
+// Add the low `n` bits of `v`.
+func (w *bitWriter) add(v uint64, n uint) {
+	w.bits |= (v & (1<<n - 1)) << w.count
+	w.count += n
+	for w.count >= 8 {
+		w.out = append(w.out, byte(w.bits))
+		w.bits >>= 8
+		w.count -= 8
+	}
+}
+
+// Close the stream with its end mark.
+func (w *bitWriter) close() []byte {
+	w.add(1, 1)
+	if w.count > 0 {
+		w.out = append(w.out, byte(w.bits))
+	}
+	return w.out
+}
+
+// Bit stream read backward from its end mark.
+type bitReader struct {
+	data []byte
+	pos  int
+}
+
+// Start reading stream `data`.
+func newBitReader(data []byte) (*bitReader, error) {
+	if len(data) == 0 || data[len(data)-1] == 0 {
+		return nil, fmt.Errorf("zstd: missing bit stream end mark")
+	}
+
+	pos := (len(data)-1)*8 + int(highbit(uint32(data[len(data)-1])))
+	return &bitReader{data: data, pos: pos}, nil
+}
+
+// Next `n` bits without consuming them, zero past the start.
+func (r *bitReader) peek(n uint) uint64 {
+	if n == 0 || r.pos <= 0 {
+		return 0
+	}
+
+	lo := r.pos - int(n)
+	shift := uint(0)
+	if lo < 0 {
+		shift = uint(-lo)
+		lo = 0
+	}
+
+	var v uint64
+	i := lo >> 3
+	if i+8 <= len(r.data) {
+		v = binary.LittleEndian.Uint64(r.data[i:])
+	} else {
+		for j := len(r.data) - 1; j >= i; j-- {
+			v = v<<8 | uint64(r.data[j])
+		}
+	}
+
+	count := uint(r.pos - lo)
+	v = v >> uint(lo&7) & (1<<count - 1)
+	return v << shift
+}
+
+// Consume the next `n` bits.
+func (r *bitReader) read(n uint) uint64 {
+	v := r.peek(n)
+	r.pos -= int(n)
+	return v
+}
+
+// Whether more bits were read than the stream holds.
+func (r *bitReader) overflow() bool {
+	return r.pos < 0
+}
+
+// Read normalized distribution of at most `max`+1 symbols from `data`,
+// returning it with its accuracy log and the bytes read.
+func readNCount(data []byte, maxSymbol int, maxLog uint) ([]int16, uint, int, error) {
+	pos := 0
+	read := func(n uint) int {
+		var v int
+		for i := uint(0); i < n; i++ {
+			if b := pos + int(i); b>>3 < len(data) && data[b>>3]>>uint(b&7)&1 == 1 {
+				v |= 1 << i
+			}
+		}
+		return v
+	}
+
+	log := uint(read(4)) + 5
+	pos += 4
+	if log > maxLog {
+		return nil, 0, 0, fmt.Errorf("zstd: accuracy log %d too large", log)
+	}
+
+	remaining := 1<<log + 1
+	threshold := 1 << log
+	nbits := log + 1
+
+	var norm []int16
+	for remaining > 1 {
+		if len(norm) > maxSymbol {
+			return nil, 0, 0, fmt.Errorf("zstd: too many symbols")
+		}
+
+		limit := 2*threshold - 1 - remaining
+		var count int
+		if v := read(nbits - 1); v < limit {
+			count = v
+			pos += int(nbits) - 1
+		} else {
+			count = read(nbits)
+			if count >= threshold {
+				count -= limit
+			}
+			pos += int(nbits)
+		}
+
+		count--
+		if count < 0 {
+			remaining--
+		} else {
+			remaining -= count
+		}
+		norm = append(norm, int16(count))
+
+		if count == 0 {
+			for {
+				repeat := read(2)
+				pos += 2
+				for i := 0; i < repeat; i++ {
+					norm = append(norm, 0)
+				}
+				if repeat < 3 {
+					break
+				}
+			}
+		}
+
+		for remaining < threshold {
+			nbits--
+			threshold >>= 1
+		}
+	}
+
+	n := (pos + 7) >> 3
+	if remaining != 1 || len(norm) > maxSymbol+1 || n > len(data) {
+		return nil, 0, 0, fmt.Errorf("zstd: invalid distribution")
+	}
+
+	return norm, log, n, nil
+}
+
+// Append the description of normalized distribution `norm` to `out`.
+func writeNCount(out []byte, norm []int16, log uint) []byte {
+	w := &bitWriter{out: out}
+	w.add(uint64(log-5), 4)
+
+	remaining := 1<<log + 1
+	threshold := 1 << log
+	nbits := log + 1
+	zero := false
+
+	for s := 0; s < len(norm) && remaining > 1; s++ {
+		if zero {
+			start := s
+			for s < len(norm) && norm[s] == 0 {
+				s++
+			}
+			if s == len(norm) {
+				break
+			}
+			for s >= start+3 {
+				start += 3
+				w.add(3, 2)
+			}
+			w.add(uint64(s-start), 2)
+		}
+
+		count := int(norm[s])
+		limit := 2*threshold - 1 - remaining
+		if count < 0 {
+			remaining += count
+		} else {
+			remaining -= count
+		}
+
+		count++
+		if count >= threshold {
+			count += limit
+		}
+
+		if count < limit {
+			w.add(uint64(count), nbits-1)
+		} else {
+			w.add(uint64(count), nbits)
+		}
+
+		zero = count == 1
+		for remaining < threshold {
+			nbits--
+			threshold >>= 1
+		}
+	}
+
+	if w.count > 0 {
+		w.out = append(w.out, byte(w.bits))
+	}
+	return w.out
+}
+
+// XXH64 primes.
+const (
+	xxPrime1 uint64 = 11400714785074694791
+	xxPrime2 uint64 = 14029467366897019727
+	xxPrime3 uint64 = 1609587929392839161
+	xxPrime4 uint64 = 9650029242287828579
+	xxPrime5 uint64 = 2870177450012600261
+)
+
+// XXH64 digest with a zero seed, the zstd content checksum.
+type xxh64 struct {
+	v     [4]uint64
+	total uint64
+	mem   [32]byte
+	n     int
+}
+
+// New XXH64 digest.
+func newXXH64() *xxh64 {
+	p1, p2 := xxPrime1, xxPrime2
+	return &xxh64{v: [4]uint64{p1 + p2, p2, 0, -p1}}
+}
+
+// XXH64 round.
+func xxRound(acc, v uint64) uint64 {
+	acc += v * xxPrime2
+	return bits.RotateLeft64(acc, 31) * xxPrime1
+}
+
+// XXH64 merge round.
+func xxMerge(acc, v uint64) uint64 {
+	acc ^= xxRound(0, v)
+	return acc*xxPrime1 + xxPrime4
+}
+
+// Write implements io.Writer.
+func (x *xxh64) Write(p []byte) (int, error) {
+	n := len(p)
+	x.total += uint64(n)
+
+	if x.n > 0 {
+		c := copy(x.mem[x.n:], p)
+		x.n += c
+		p = p[c:]
+		if x.n < 32 {
+			return n, nil
+		}
+		x.stripe(x.mem[:])
+		x.n = 0
+	}
+
+	for len(p) >= 32 {
+		x.stripe(p)
+		p = p[32:]
+	}
+
+	x.n = copy(x.mem[:], p)
+	return n, nil
+}
+
+// Consume a 32 byte stripe.
+func (x *xxh64) stripe(p []byte) {
+	for i := range x.v {
+		x.v[i] = xxRound(x.v[i], binary.LittleEndian.Uint64(p[i*8:]))
+	}
+}
+
+// Sum64 of the data written.
+func (x *xxh64) Sum64() uint64 {
+	var h uint64
+	if x.total >= 32 {
+		h = bits.RotateLeft64(x.v[0], 1) + bits.RotateLeft64(x.v[1], 7) + bits.RotateLeft64(x.v[2], 12) + bits.RotateLeft64(x.v[3], 18)
+		for _, v := range x.v {
+			h = xxMerge(h, v)
+		}
+	} else {
+		h = x.v[2] + xxPrime5
+	}
+
+	h += x.total
+	p := x.mem[:x.n]
+	for ; len(p) >= 8; p = p[8:] {
+		h ^= xxRound(0, binary.LittleEndian.Uint64(p))
+		h = bits.RotateLeft64(h, 27)*xxPrime1 + xxPrime4
+	}
+	if len(p) >= 4 {
+		h ^= uint64(binary.LittleEndian.Uint32(p)) * xxPrime1
+		h = bits.RotateLeft64(h, 23)*xxPrime2 + xxPrime3
+		p = p[4:]
+	}
+	for _, b := range p {
+		h ^= uint64(b) * xxPrime5
+		h = bits.RotateLeft64(h, 11) * xxPrime1
+	}
+
+	h ^= h >> 33
+	h *= xxPrime2
+	h ^= h >> 29
+	h *= xxPrime3
+	h ^= h >> 32
+	return h
+}
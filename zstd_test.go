@@ -0,0 +1,95 @@
+package buffer
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// Compress `data` with dictionary `dict`.
+func zstdCompress(t *testing.T, data, dict []byte) []byte {
+	var buf bytes.Buffer
+	z, err := newZstdWriter(&buf, dict)
+	assert.Equal(t, nil, err)
+
+	_, err = z.Write(data)
+	assert.Equal(t, nil, err)
+
+	err = z.Close()
+	assert.Equal(t, nil, err)
+	return buf.Bytes()
+}
+
+// Test zstd round trips, across blocks and concatenated frames.
+func TestZstd(t *testing.T) {
+	random := make([]byte, 300<<10)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	var records bytes.Buffer
+	for i := 0; records.Len() < 2<<20; i++ {
+		fmt.Fprintf(&records, `{"level":"info","order":%d}`+"\n", i*7919%100000)
+	}
+
+	inputs := [][]byte{
+		{},
+		[]byte("hello"),
+		bytes.Repeat([]byte("hello world\n"), 1000),
+		random,
+		records.Bytes(),
+	}
+
+	var frames, all []byte
+	for _, in := range inputs {
+		z := zstdCompress(t, in, nil)
+		assert.Equal(t, zstdHead, z[:4])
+
+		out, err := ioutil.ReadAll(newZstdReader(bytes.NewReader(z), nil))
+		assert.Equal(t, nil, err)
+		assert.Equal(t, true, bytes.Equal(in, out))
+
+		frames = append(frames, z...)
+		all = append(all, in...)
+	}
+
+	assert.Equal(t, true, len(frames) < len(all)/2)
+
+	out, err := ioutil.ReadAll(newZstdReader(bytes.NewReader(frames), nil))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, bytes.Equal(all, out))
+
+	z := zstdCompress(t, []byte("hello world\n"), nil)
+	z[len(z)-1]++
+	_, err = ioutil.ReadAll(newZstdReader(bytes.NewReader(z), nil))
+	assert.Equal(t, errZstdChecksum, err)
+}
+
+// Test zstd dictionaries are referenced by id and improve compression.
+func TestZstd_dictionary(t *testing.T) {
+	content := []byte(`{"level":"info","service":"checkout","message":"order placed"}`)
+	dict := zstdDictionary(40000, content)
+
+	d, err := parseZstdDict(dict)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, uint32(40000), d.id)
+	assert.Equal(t, content, d.content)
+
+	record := []byte(`{"level":"info","service":"checkout","message":"order placed","order":1}`)
+	plain := zstdCompress(t, record, nil)
+	z := zstdCompress(t, record, dict)
+	assert.Equal(t, true, len(z) < len(plain))
+
+	var ids []uint32
+	r := newZstdReader(bytes.NewReader(z), func(id uint32) (*zstdDict, error) {
+		ids = append(ids, id)
+		return d, nil
+	})
+
+	out, err := ioutil.ReadAll(r)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, string(record), string(out))
+	assert.Equal(t, []uint32{40000}, ids)
+}
@@ -0,0 +1,736 @@
+package buffer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Largest window of zstd frames read.
+const zstdMaxWindow = 1 << 27
+
+// Errors reading zstd data.
+var (
+	errZstdCorrupt  = errors.New("zstd: corrupt data")
+	errZstdChecksum = errors.New("zstd: checksum mismatch")
+)
+
+// Zstandard dictionary, its content and the entropy tables and repeat
+// offsets frames compressed with it start from.
+type zstdDict struct {
+	id         uint32
+	content    []byte
+	reps       [3]uint32
+	huff       *huffTable
+	ll, of, ml *fseTable
+}
+
+// Parse zstd dictionary `data`, taking data without the dictionary magic
+// as raw content.
+func parseZstdDict(data []byte) (*zstdDict, error) {
+	if len(data) < 8 || binary.LittleEndian.Uint32(data) != zstdDictMagic {
+		return &zstdDict{content: data, reps: [3]uint32{1, 4, 8}}, nil
+	}
+
+	d := &zstdDict{id: binary.LittleEndian.Uint32(data[4:])}
+	p := data[8:]
+
+	h, n, err := readHuffman(p)
+	if err != nil {
+		return nil, err
+	}
+	d.huff = h
+	p = p[n:]
+
+	tables := []struct {
+		table *(*fseTable)
+		max   int
+		log   uint
+	}{
+		{&d.of, maxOF, 8},
+		{&d.ml, maxML, 9},
+		{&d.ll, maxLL, 9},
+	}
+
+	for _, t := range tables {
+		norm, log, n, err := readNCount(p, t.max, t.log)
+		if err != nil {
+			return nil, err
+		}
+		*t.table = newFSETable(norm, log)
+		p = p[n:]
+	}
+
+	if len(p) < 12 {
+		return nil, fmt.Errorf("zstd: truncated dictionary")
+	}
+
+	d.content = p[12:]
+	for i := range d.reps {
+		d.reps[i] = binary.LittleEndian.Uint32(p[i*4:])
+		if d.reps[i] == 0 || int(d.reps[i]) > len(d.content) {
+			return nil, fmt.Errorf("zstd: invalid dictionary repeat offset")
+		}
+	}
+
+	return d, nil
+}
+
+// Entry of a Huffman decoding table.
+type huffEntry struct {
+	symbol uint8
+	bits   uint8
+}
+
+// Huffman decoding table, indexed by the next `log` bits.
+type huffTable struct {
+	log     uint
+	entries []huffEntry
+}
+
+// Read a Huffman tree description from `data`, returning its table and
+// the bytes read.
+func readHuffman(data []byte) (*huffTable, int, error) {
+	if len(data) == 0 {
+		return nil, 0, errZstdCorrupt
+	}
+
+	var weights []uint8
+	h := int(data[0])
+	n := 1
+
+	if h >= 128 {
+		count := h - 127
+		n += (count + 1) / 2
+		if n > len(data) {
+			return nil, 0, errZstdCorrupt
+		}
+
+		for i := 0; i < count; i++ {
+			b := data[1+i/2]
+			if i%2 == 0 {
+				weights = append(weights, b>>4)
+			} else {
+				weights = append(weights, b&15)
+			}
+		}
+	} else {
+		n += h
+		if n > len(data) {
+			return nil, 0, errZstdCorrupt
+		}
+
+		norm, log, c, err := readNCount(data[1:n], 15, 6)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		t := newFSETable(norm, log)
+		r, err := newBitReader(data[1+c : n])
+		if err != nil {
+			return nil, 0, err
+		}
+
+		// two interleaved states, until the stream is exhausted
+		s := [2]uint64{r.read(log), r.read(log)}
+		for i := 0; ; i ^= 1 {
+			if len(weights) > 255 {
+				return nil, 0, errZstdCorrupt
+			}
+
+			e := t.states[s[i]]
+			weights = append(weights, e.symbol)
+			s[i] = uint64(e.base) + r.read(uint(e.bits))
+
+			if r.overflow() {
+				weights = append(weights, t.states[s[i^1]].symbol)
+				break
+			}
+		}
+	}
+
+	t, err := newHuffTable(weights)
+	return t, n, err
+}
+
+// Build the Huffman table of symbol `weights`, deducing the weight of
+// the last symbol.
+func newHuffTable(weights []uint8) (*huffTable, error) {
+	var total uint32
+	for _, w := range weights {
+		if w > 11 {
+			return nil, errZstdCorrupt
+		}
+		if w > 0 {
+			total += 1 << (w - 1)
+		}
+	}
+
+	if total == 0 || len(weights) > 255 {
+		return nil, errZstdCorrupt
+	}
+
+	log := highbit(total) + 1
+	rest := uint32(1)<<log - total
+	if log > 11 || rest&(rest-1) != 0 {
+		return nil, errZstdCorrupt
+	}
+	weights = append(weights, uint8(highbit(rest)+1))
+
+	t := &huffTable{log: log, entries: make([]huffEntry, 1<<log)}
+	pos := 0
+	for w := uint8(1); uint(w) <= log; w++ {
+		for s, sw := range weights {
+			if sw != w {
+				continue
+			}
+
+			e := huffEntry{symbol: uint8(s), bits: uint8(log + 1 - uint(w))}
+			for i := 0; i < 1<<(w-1); i++ {
+				t.entries[pos+i] = e
+			}
+			pos += 1 << (w - 1)
+		}
+	}
+
+	return t, nil
+}
+
+// Append `n` symbols decoded from Huffman stream `data` to `out`.
+func (t *huffTable) decode(out, data []byte, n int) ([]byte, error) {
+	r, err := newBitReader(data)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < n; i++ {
+		e := t.entries[r.peek(t.log)]
+		r.pos -= int(e.bits)
+		out = append(out, e.symbol)
+	}
+
+	if r.pos != 0 {
+		return nil, errZstdCorrupt
+	}
+
+	return out, nil
+}
+
+// Zstandard decompressor of concatenated frames, loading the dictionaries
+// frames reference with `dicts`.
+type zstdReader struct {
+	r        *bufio.Reader
+	dicts    func(id uint32) (*zstdDict, error)
+	hist     []byte
+	out      []byte
+	block    []byte
+	window   int
+	frame    bool
+	last     bool
+	checksum bool
+	sum      *xxh64
+	reps     [3]uint32
+	huff     *huffTable
+	ll       *fseTable
+	of       *fseTable
+	ml       *fseTable
+	err      error
+}
+
+// New decompressor of `r`.
+func newZstdReader(r io.Reader, dicts func(id uint32) (*zstdDict, error)) *zstdReader {
+	return &zstdReader{r: bufio.NewReader(r), dicts: dicts}
+}
+
+// Read implements io.Reader.
+func (z *zstdReader) Read(p []byte) (int, error) {
+	for len(z.out) == 0 {
+		if z.err != nil {
+			return 0, z.err
+		}
+		z.err = z.next()
+	}
+
+	n := copy(p, z.out)
+	z.out = z.out[n:]
+	return n, nil
+}
+
+// Close implements io.Closer, without closing the underlying reader.
+func (z *zstdReader) Close() error {
+	return nil
+}
+
+// Read exactly len(`p`) bytes of the current frame.
+func (z *zstdReader) fill(p []byte) error {
+	_, err := io.ReadFull(z.r, p)
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Decode the next frame header, block or checksum.
+func (z *zstdReader) next() error {
+	if !z.frame {
+		return z.start()
+	}
+
+	if !z.last {
+		return z.decodeBlock()
+	}
+
+	z.frame = false
+	if !z.checksum {
+		return nil
+	}
+
+	var sum [4]byte
+	err := z.fill(sum[:])
+	if err != nil {
+		return err
+	}
+
+	if binary.LittleEndian.Uint32(sum[:]) != uint32(z.sum.Sum64()) {
+		return errZstdChecksum
+	}
+
+	return nil
+}
+
+// Read the next frame header, skipping skippable frames.
+func (z *zstdReader) start() error {
+	var magic [4]byte
+	_, err := io.ReadFull(z.r, magic[:])
+	if err != nil {
+		return err
+	}
+
+	m := binary.LittleEndian.Uint32(magic[:])
+	if m&0xFFFFFFF0 == 0x184D2A50 {
+		err = z.fill(magic[:])
+		if err != nil {
+			return err
+		}
+		_, err = z.r.Discard(int(binary.LittleEndian.Uint32(magic[:])))
+		return err
+	}
+
+	if m != zstdMagic {
+		return fmt.Errorf("zstd: invalid magic %08x", m)
+	}
+
+	fhd, err := z.r.ReadByte()
+	if err != nil {
+		return io.ErrUnexpectedEOF
+	}
+
+	if fhd&0x08 != 0 {
+		return errZstdCorrupt
+	}
+
+	single := fhd&0x20 != 0
+	window := 0
+	if !single {
+		wd, err := z.r.ReadByte()
+		if err != nil {
+			return io.ErrUnexpectedEOF
+		}
+
+		base := 1 << (10 + wd>>3)
+		window = base + base/8*int(wd&7)
+	}
+
+	var field [8]byte
+	id := field[:[]int{0, 1, 2, 4}[fhd&3]]
+	err = z.fill(id)
+	if err != nil {
+		return err
+	}
+
+	fcsSize := []int{0, 2, 4, 8}[fhd>>6]
+	if single && fhd>>6 == 0 {
+		fcsSize = 1
+	}
+
+	fcs := make([]byte, 8)
+	err = z.fill(fcs[:fcsSize])
+	if err != nil {
+		return err
+	}
+
+	if single {
+		window = int(binary.LittleEndian.Uint64(fcs))
+		if fcsSize == 2 {
+			window += 256
+		}
+	}
+
+	if window > zstdMaxWindow {
+		return fmt.Errorf("zstd: window of %d bytes too large", window)
+	}
+
+	z.window = window
+	z.hist = z.hist[:0]
+	z.reps = [3]uint32{1, 4, 8}
+	z.huff, z.ll, z.of, z.ml = nil, nil, nil, nil
+
+	if did := binary.LittleEndian.Uint64(field[:]); did != 0 {
+		d, err := z.dicts(uint32(did))
+		if err != nil {
+			return err
+		}
+
+		z.hist = append(z.hist, d.content...)
+		z.reps = d.reps
+		z.huff, z.ll, z.of, z.ml = d.huff, d.ll, d.of, d.ml
+	}
+
+	z.sum = newXXH64()
+	z.frame = true
+	z.last = false
+	z.checksum = fhd&0x04 != 0
+	return nil
+}
+
+// Decode the next block of the frame.
+func (z *zstdReader) decodeBlock() error {
+	var h [3]byte
+	err := z.fill(h[:])
+	if err != nil {
+		return err
+	}
+
+	v := int(h[0]) | int(h[1])<<8 | int(h[2])<<16
+	size := v >> 3
+	if size > zstdBlockSize {
+		return errZstdCorrupt
+	}
+
+	// keep the window of history for matches
+	if d := len(z.hist) - z.window; d > 4*zstdBlockSize {
+		z.hist = z.hist[:copy(z.hist, z.hist[d:])]
+	}
+
+	start := len(z.hist)
+	switch v >> 1 & 3 {
+	case 0:
+		z.hist = append(z.hist, make([]byte, size)...)
+		err = z.fill(z.hist[start:])
+	case 1:
+		var b byte
+		b, err = z.r.ReadByte()
+		if err != nil {
+			return io.ErrUnexpectedEOF
+		}
+		z.hist = append(z.hist, bytes.Repeat([]byte{b}, size)...)
+	case 2:
+		if cap(z.block) < size {
+			z.block = make([]byte, size)
+		}
+		z.block = z.block[:size]
+		err = z.fill(z.block)
+		if err == nil {
+			err = z.compressed(z.block)
+		}
+	default:
+		err = errZstdCorrupt
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if len(z.hist)-start > zstdBlockSize {
+		return errZstdCorrupt
+	}
+
+	z.out = z.hist[start:]
+	z.sum.Write(z.out)
+	z.last = v&1 == 1
+	return nil
+}
+
+// Decode compressed block `data` into the history.
+func (z *zstdReader) compressed(data []byte) error {
+	lits, n, err := z.literals(data)
+	if err != nil {
+		return err
+	}
+
+	return z.sequences(data[n:], lits)
+}
+
+// Decode the literals section of `data`, returning the literals and the
+// bytes read.
+func (z *zstdReader) literals(data []byte) ([]byte, int, error) {
+	if len(data) == 0 {
+		return nil, 0, errZstdCorrupt
+	}
+
+	kind := data[0] & 3
+	format := data[0] >> 2 & 3
+
+	if kind < 2 {
+		n := []int{1, 2, 1, 3}[format]
+		if n+1 > len(data) {
+			return nil, 0, errZstdCorrupt
+		}
+
+		var size int
+		switch format {
+		case 0, 2:
+			size = int(data[0] >> 3)
+		case 1:
+			size = int(data[0]>>4) | int(data[1])<<4
+		case 3:
+			size = int(data[0]>>4) | int(data[1])<<4 | int(data[2])<<12
+		}
+
+		if kind == 1 {
+			return bytes.Repeat(data[n:n+1], size), n + 1, nil
+		}
+
+		if n+size > len(data) {
+			return nil, 0, errZstdCorrupt
+		}
+		return data[n : n+size], n + size, nil
+	}
+
+	n, width, streams := 3, uint(10), 4
+	switch format {
+	case 0:
+		streams = 1
+	case 2:
+		n, width = 4, 14
+	case 3:
+		n, width = 5, 18
+	}
+
+	if n > len(data) {
+		return nil, 0, errZstdCorrupt
+	}
+
+	var h uint64
+	for i := n - 1; i >= 0; i-- {
+		h = h<<8 | uint64(data[i])
+	}
+
+	mask := uint64(1)<<width - 1
+	regen := int(h >> 4 & mask)
+	size := int(h >> (4 + width) & mask)
+	if regen > zstdBlockSize || n+size > len(data) {
+		return nil, 0, errZstdCorrupt
+	}
+
+	src := data[n : n+size]
+	if kind == 2 {
+		t, c, err := readHuffman(src)
+		if err != nil {
+			return nil, 0, err
+		}
+		z.huff = t
+		src = src[c:]
+	} else if z.huff == nil {
+		return nil, 0, errZstdCorrupt
+	}
+
+	var out []byte
+	var err error
+	if streams == 1 {
+		out, err = z.huff.decode(out, src, regen)
+		return out, n + size, err
+	}
+
+	if len(src) < 6 {
+		return nil, 0, errZstdCorrupt
+	}
+
+	var sizes [4]int
+	rest := len(src) - 6
+	for i := 0; i < 3; i++ {
+		sizes[i] = int(binary.LittleEndian.Uint16(src[i*2:]))
+		rest -= sizes[i]
+	}
+	sizes[3] = rest
+
+	each := (regen + 3) / 4
+	if rest < 0 || regen < 3*each {
+		return nil, 0, errZstdCorrupt
+	}
+
+	src = src[6:]
+	for i, s := range sizes {
+		count := each
+		if i == 3 {
+			count = regen - 3*each
+		}
+
+		out, err = z.huff.decode(out, src[:s], count)
+		if err != nil {
+			return nil, 0, err
+		}
+		src = src[s:]
+	}
+
+	return out, n + size, nil
+}
+
+// Decode the sequences section `data`, executing its sequences with
+// literals `lits` into the history.
+func (z *zstdReader) sequences(data, lits []byte) error {
+	if len(data) == 0 {
+		return errZstdCorrupt
+	}
+
+	count, n := int(data[0]), 1
+	switch {
+	case count == 0:
+		z.hist = append(z.hist, lits...)
+		return nil
+	case count == 255:
+		if len(data) < 3 {
+			return errZstdCorrupt
+		}
+		count, n = int(data[1])|int(data[2])<<8+0x7F00, 3
+	case count >= 128:
+		if len(data) < 2 {
+			return errZstdCorrupt
+		}
+		count, n = (count-128)<<8|int(data[1]), 2
+	}
+
+	if n >= len(data) {
+		return errZstdCorrupt
+	}
+
+	modes := data[n]
+	n++
+	if modes&3 != 0 {
+		return errZstdCorrupt
+	}
+
+	tables := []struct {
+		table *(*fseTable)
+		def   *fseTable
+		max   int
+		log   uint
+		mode  byte
+	}{
+		{&z.ll, llTable, maxLL, 9, modes >> 6},
+		{&z.of, ofTable, maxOF, 8, modes >> 4 & 3},
+		{&z.ml, mlTable, maxML, 9, modes >> 2 & 3},
+	}
+
+	for _, t := range tables {
+		switch t.mode {
+		case 0:
+			*t.table = t.def
+		case 1:
+			if n >= len(data) || int(data[n]) > t.max {
+				return errZstdCorrupt
+			}
+			*t.table = rleTable(data[n])
+			n++
+		case 2:
+			norm, log, c, err := readNCount(data[n:], t.max, t.log)
+			if err != nil {
+				return err
+			}
+			*t.table = newFSETable(norm, log)
+			n += c
+		case 3:
+			if *t.table == nil {
+				return errZstdCorrupt
+			}
+		}
+	}
+
+	r, err := newBitReader(data[n:])
+	if err != nil {
+		return err
+	}
+
+	ll := r.read(z.ll.log)
+	of := r.read(z.of.log)
+	ml := r.read(z.ml.log)
+
+	for i := 0; i < count; i++ {
+		ofc := z.of.states[of].symbol
+		mlc := z.ml.states[ml].symbol
+		llc := z.ll.states[ll].symbol
+		if ofc > maxOF || mlc > maxML || llc > maxLL {
+			return errZstdCorrupt
+		}
+
+		offset := uint32(1)<<ofc + uint32(r.read(uint(ofc)))
+		match := int(mlBase[mlc]) + int(r.read(uint(mlBits[mlc])))
+		length := int(llBase[llc]) + int(r.read(uint(llBits[llc])))
+
+		if i < count-1 {
+			e := z.ll.states[ll]
+			ll = uint64(e.base) + r.read(uint(e.bits))
+			e = z.ml.states[ml]
+			ml = uint64(e.base) + r.read(uint(e.bits))
+			e = z.of.states[of]
+			of = uint64(e.base) + r.read(uint(e.bits))
+		}
+
+		off := int(z.offset(offset, length))
+		if length > len(lits) || r.overflow() {
+			return errZstdCorrupt
+		}
+
+		z.hist = append(z.hist, lits[:length]...)
+		lits = lits[length:]
+
+		src := len(z.hist) - off
+		if off == 0 || src < 0 || match > zstdBlockSize {
+			return errZstdCorrupt
+		}
+
+		if off >= match {
+			z.hist = append(z.hist, z.hist[src:src+match]...)
+		} else {
+			for j := 0; j < match; j++ {
+				z.hist = append(z.hist, z.hist[src+j])
+			}
+		}
+	}
+
+	if r.pos != 0 {
+		return errZstdCorrupt
+	}
+
+	z.hist = append(z.hist, lits...)
+	return nil
+}
+
+// Offset of sequence offset value `v` after `lits` literals, updating
+// the repeat offsets.
+func (z *zstdReader) offset(v uint32, lits int) uint32 {
+	if v > 3 {
+		z.reps = [3]uint32{v - 3, z.reps[0], z.reps[1]}
+		return v - 3
+	}
+
+	if lits == 0 {
+		v++
+	}
+
+	switch v {
+	case 1:
+		return z.reps[0]
+	case 2:
+		z.reps = [3]uint32{z.reps[1], z.reps[0], z.reps[2]}
+	case 3:
+		z.reps = [3]uint32{z.reps[2], z.reps[0], z.reps[1]}
+	default:
+		z.reps = [3]uint32{z.reps[0] - 1, z.reps[0], z.reps[1]}
+	}
+
+	return z.reps[0]
+}
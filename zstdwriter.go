@@ -0,0 +1,324 @@
+package buffer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// Window of zstd frames written, the longest match offset.
+const zstdWindow = 1 << 20
+
+// Size of the match finder's hash table in bits.
+const zstdHashLog = 15
+
+// Sequence of literals followed by a match.
+type zstdSeq struct {
+	lits   uint32
+	offset uint32
+	match  uint32
+}
+
+// Zstandard compressor writing a single frame with a greedy match finder,
+// raw literals and the predefined sequence tables, referencing the
+// content of dictionary `dict` when given.
+type zstdWriter struct {
+	w       io.Writer
+	id      uint32
+	hist    []byte
+	done    int
+	table   []int32
+	sum     *xxh64
+	started bool
+	err     error
+}
+
+// New compressor writing to `w` with the zstd dictionary `dict`, if any.
+func newZstdWriter(w io.Writer, dict []byte) (*zstdWriter, error) {
+	z := &zstdWriter{
+		w:     w,
+		table: make([]int32, 1<<zstdHashLog),
+		sum:   newXXH64(),
+	}
+
+	if dict != nil {
+		d, err := parseZstdDict(dict)
+		if err != nil {
+			return nil, err
+		}
+
+		content := d.content
+		if len(content) > zstdWindow {
+			content = content[len(content)-zstdWindow:]
+		}
+
+		z.id = d.id
+		z.hist = append(z.hist, content...)
+		z.done = len(z.hist)
+		for i := 0; i+4 <= z.done; i++ {
+			z.table[z.hash(i)] = int32(i + 1)
+		}
+	}
+
+	return z, nil
+}
+
+// Write implements io.Writer.
+func (z *zstdWriter) Write(p []byte) (int, error) {
+	if z.err != nil {
+		return 0, z.err
+	}
+
+	n := len(p)
+	z.sum.Write(p)
+
+	for len(p) > 0 {
+		if len(z.hist)-z.done == zstdBlockSize {
+			z.err = z.flush(false)
+			if z.err != nil {
+				return n - len(p), z.err
+			}
+		}
+
+		c := zstdBlockSize - (len(z.hist) - z.done)
+		if c > len(p) {
+			c = len(p)
+		}
+
+		z.hist = append(z.hist, p[:c]...)
+		p = p[c:]
+	}
+
+	return n, nil
+}
+
+// Close writes the last block and the content checksum, without closing
+// the underlying writer.
+func (z *zstdWriter) Close() error {
+	if z.err != nil {
+		return z.err
+	}
+
+	z.err = z.flush(true)
+	if z.err != nil {
+		return z.err
+	}
+
+	var sum [4]byte
+	binary.LittleEndian.PutUint32(sum[:], uint32(z.sum.Sum64()))
+	_, z.err = z.w.Write(sum[:])
+	return z.err
+}
+
+// Write the frame header.
+func (z *zstdWriter) header() error {
+	h := make([]byte, 4, 10)
+	binary.LittleEndian.PutUint32(h, zstdMagic)
+
+	// content checksum, 4 byte dictionary id when set, and the window
+	if z.id != 0 {
+		h = append(h, 0x07, 0x50)
+		h = append(h, byte(z.id), byte(z.id>>8), byte(z.id>>16), byte(z.id>>24))
+	} else {
+		h = append(h, 0x04, 0x50)
+	}
+
+	_, err := z.w.Write(h)
+	return err
+}
+
+// Write the pending data as a block, the frame's last when `last`.
+func (z *zstdWriter) flush(last bool) error {
+	if !z.started {
+		z.started = true
+		err := z.header()
+		if err != nil {
+			return err
+		}
+	}
+
+	start, end := z.done, len(z.hist)
+	data := z.compress(start, end)
+	kind := uint32(2)
+	if len(data) >= end-start {
+		data = z.hist[start:end]
+		kind = 0
+	}
+
+	h := kind<<1 | uint32(len(data))<<3
+	if last {
+		h |= 1
+	}
+
+	_, err := z.w.Write([]byte{byte(h), byte(h >> 8), byte(h >> 16)})
+	if err != nil {
+		return err
+	}
+
+	_, err = z.w.Write(data)
+	if err != nil {
+		return err
+	}
+
+	z.done = end
+	z.slide()
+	return nil
+}
+
+// Drop history beyond the window.
+func (z *zstdWriter) slide() {
+	d := z.done - zstdWindow
+	if d < zstdBlockSize {
+		return
+	}
+
+	z.hist = z.hist[:copy(z.hist, z.hist[d:])]
+	z.done -= d
+	for i, v := range z.table {
+		if v -= int32(d); v > 0 {
+			z.table[i] = v
+		} else {
+			z.table[i] = 0
+		}
+	}
+}
+
+// Hash of the 4 bytes of history at `i`.
+func (z *zstdWriter) hash(i int) uint32 {
+	return binary.LittleEndian.Uint32(z.hist[i:]) * 2654435761 >> (32 - zstdHashLog)
+}
+
+// Compressed block of the history from `start` to `end`.
+func (z *zstdWriter) compress(start, end int) []byte {
+	var lits []byte
+	var seqs []zstdSeq
+
+	anchor := start
+	for i := start; i+4 <= end; {
+		h := z.hash(i)
+		c := int(z.table[h]) - 1
+		z.table[h] = int32(i + 1)
+
+		if c < 0 || i-c > zstdWindow || binary.LittleEndian.Uint32(z.hist[c:]) != binary.LittleEndian.Uint32(z.hist[i:]) {
+			i++
+			continue
+		}
+
+		n := 4
+		for i+n < end && z.hist[c+n] == z.hist[i+n] {
+			n++
+		}
+
+		for i > anchor && c > 0 && z.hist[i-1] == z.hist[c-1] {
+			i--
+			c--
+			n++
+		}
+
+		lits = append(lits, z.hist[anchor:i]...)
+		seqs = append(seqs, zstdSeq{lits: uint32(i - anchor), offset: uint32(i - c), match: uint32(n)})
+
+		for j := i + 1; j < i+n && j+4 <= end; j++ {
+			z.table[z.hash(j)] = int32(j + 1)
+		}
+
+		i += n
+		anchor = i
+	}
+	lits = append(lits, z.hist[anchor:end]...)
+
+	// raw literals
+	out := make([]byte, 0, end-start)
+	switch n := len(lits); {
+	case n < 32:
+		out = append(out, byte(n<<3))
+	case n < 4096:
+		out = append(out, byte(n<<4|1<<2), byte(n>>4))
+	default:
+		out = append(out, byte(n<<4|3<<2), byte(n>>4), byte(n>>12))
+	}
+	out = append(out, lits...)
+
+	switch n := len(seqs); {
+	case n < 128:
+		out = append(out, byte(n))
+	case n < 0x7F00:
+		out = append(out, byte(n>>8+0x80), byte(n))
+	default:
+		out = append(out, 0xFF, byte(n-0x7F00), byte((n-0x7F00)>>8))
+	}
+
+	if len(seqs) == 0 {
+		return out
+	}
+
+	// predefined tables
+	out = append(out, 0)
+	return encodeSequences(out, seqs)
+}
+
+// Append the bit stream of `seqs` to `out` with the predefined tables,
+// encoding the last sequence first so they decode in order.
+func encodeSequences(out []byte, seqs []zstdSeq) []byte {
+	type codes struct {
+		ll, ml, of uint8
+	}
+
+	c := make([]codes, len(seqs))
+	for i, s := range seqs {
+		c[i] = codes{
+			ll: zstdCode(llBase[:], s.lits),
+			ml: zstdCode(mlBase[:], s.match),
+			of: uint8(highbit(s.offset + 3)),
+		}
+	}
+
+	w := &bitWriter{out: out}
+	extra := func(i int) {
+		s := seqs[i]
+		w.add(uint64(s.lits-llBase[c[i].ll]), uint(llBits[c[i].ll]))
+		w.add(uint64(s.match-mlBase[c[i].ml]), uint(mlBits[c[i].ml]))
+		w.add(uint64(s.offset+3), uint(c[i].of))
+	}
+
+	last := len(seqs) - 1
+	ml := mlEncoder.init(c[last].ml)
+	of := ofEncoder.init(c[last].of)
+	ll := llEncoder.init(c[last].ll)
+	extra(last)
+
+	for i := last - 1; i >= 0; i-- {
+		of = ofEncoder.encode(w, of, c[i].of)
+		ml = mlEncoder.encode(w, ml, c[i].ml)
+		ll = llEncoder.encode(w, ll, c[i].ll)
+		extra(i)
+	}
+
+	w.add(uint64(ml), mlEncoder.log)
+	w.add(uint64(of), ofEncoder.log)
+	w.add(uint64(ll), llEncoder.log)
+	return w.close()
+}
+
+// Formatted zstd dictionary `id` of `content`, weighting literals 0-127
+// evenly and describing the predefined sequence tables.
+func zstdDictionary(id uint32, content []byte) []byte {
+	out := make([]byte, 8, 160+len(content))
+	binary.LittleEndian.PutUint32(out, zstdDictMagic)
+	binary.LittleEndian.PutUint32(out[4:], id)
+
+	// 127 direct weights of one, the last deduced
+	out = append(out, 254)
+	out = append(out, bytes.Repeat([]byte{0x11}, 63)...)
+	out = append(out, 0x10)
+
+	out = writeNCount(out, ofDefault, 5)
+	out = writeNCount(out, mlDefault, 6)
+	out = writeNCount(out, llDefault, 6)
+
+	for _, rep := range []uint32{1, 4, 8} {
+		out = append(out, byte(rep), byte(rep>>8), byte(rep>>16), byte(rep>>24))
+	}
+
+	return append(out, content...)
+}